@@ -0,0 +1,452 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/selectors"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
+)
+
+var (
+	errDiffUsage   = errors.New("invalid usage: expected 'diff [options] [task...]'")
+	errDiffChanged = errors.New("assembled contexts differ")
+	errUnsafeTar   = errors.New("unsafe path in git archive")
+)
+
+// diffSide is one of the two configurations compared by diff.
+type diffSide struct {
+	name     string // "old" or "new", or the git ref
+	ref      string // Git ref to read the -C directory at; empty for the worktree
+	agent    string
+	params   taskparser.Params
+	includes selectors.Selectors
+	cc       *codingcontext.Context
+}
+
+// runDiff assembles each task under two configurations and prints how they differ: the
+// rules added, removed, and changed, the token delta, and a unified diff of the prompt.
+// Without task arguments, every task found in either configuration is compared.
+func runDiff(ctx context.Context, args []string, stdout io.Writer, logger *slog.Logger) error {
+	oldSide := &diffSide{name: "old", params: make(taskparser.Params), includes: make(selectors.Selectors)}
+	newSide := &diffSide{name: "new", params: make(taskparser.Params), includes: make(selectors.Selectors)}
+
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	workDir := flags.String("C", ".", "Change to directory before doing anything.")
+	exitCode := flags.Bool("exit-code", false, "Exit with an error if any task's assembled context differs.")
+
+	var searchPaths []string
+
+	flags.Func("d", "Directory containing rules and tasks, for both sides. Can be specified multiple times.",
+		func(s string) error {
+			searchPaths = append(searchPaths, s)
+
+			return nil
+		})
+
+	for _, side := range []*diffSide{oldSide, newSide} {
+		flags.StringVar(&side.ref, side.name+"-ref", "",
+			"Read the "+side.name+" side's rules and tasks from the -C directory at this git ref instead of the worktree.")
+		flags.StringVar(&side.agent, side.name+"-a", "", "Target agent for the "+side.name+" side.")
+		flags.Var(&side.params, side.name+"-p", "Parameter for the "+side.name+" side, as key=value.")
+		flags.Var(&side.includes, side.name+"-s", "Selector for the "+side.name+" side, as key=value.")
+	}
+
+	flags.Func("a", "Target agent for both sides.", func(s string) error {
+		oldSide.agent, newSide.agent = s, s
+
+		return nil
+	})
+	flags.Func("p", "Parameter for both sides, as key=value.", func(s string) error {
+		return errors.Join(oldSide.params.Set(s), newSide.params.Set(s))
+	})
+	flags.Func("s", "Selector for both sides, as key=value.", func(s string) error {
+		return errors.Join(oldSide.includes.Set(s), newSide.includes.Set(s))
+	})
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errDiffUsage, err)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	if err := loadAgentConfigs(homeDir, *workDir); err != nil {
+		return err
+	}
+
+	for _, side := range []*diffSide{oldSide, newSide} {
+		cleanup, err := side.newContext(ctx, *workDir, homeDir, searchPaths, logger)
+		if err != nil {
+			return fmt.Errorf("%s side: %w", side.name, err)
+		}
+
+		defer cleanup()
+	}
+
+	tasks := flags.Args()
+	if len(tasks) == 0 {
+		if tasks, err = diffTasks(ctx, oldSide, newSide); err != nil {
+			return err
+		}
+	}
+
+	changed := 0
+
+	for _, task := range tasks {
+		diff, err := diffTask(ctx, task, oldSide, newSide)
+		if err != nil {
+			return err
+		}
+
+		if !diff.Changed() {
+			continue
+		}
+
+		changed++
+
+		if err := writeTaskDiff(stdout, task, diff); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("Compared tasks", "tasks", len(tasks), "changed", changed)
+
+	if *exitCode && changed > 0 {
+		return fmt.Errorf("%w: %d of %d task(s)", errDiffChanged, changed, len(tasks))
+	}
+
+	return nil
+}
+
+// newContext creates the side's Context, reading the -C directory at the side's git ref if
+// it has one. The returned function removes the checked out ref and downloaded directories.
+func (side *diffSide) newContext(
+	ctx context.Context, workDir, homeDir string, searchPaths []string, logger *slog.Logger,
+) (func(), error) {
+	root := workDir
+	cleanup := func() {}
+
+	if side.ref != "" {
+		dir, err := checkoutGitRef(ctx, workDir, side.ref)
+		if err != nil {
+			return nil, err
+		}
+
+		root, side.name = dir, side.ref
+		cleanup = func() { _ = os.RemoveAll(dir) }
+	}
+
+	var agent codingcontext.Agent
+	if side.agent != "" {
+		if err := agent.Set(side.agent); err != nil {
+			cleanup()
+
+			return nil, fmt.Errorf("invalid agent: %w", err)
+		}
+	}
+
+	side.cc = codingcontext.New(
+		codingcontext.WithSearchPaths(append(slices.Clone(searchPaths), "file://"+root, "file://"+homeDir)...),
+		codingcontext.WithParams(side.params),
+		codingcontext.WithSelectors(side.includes),
+		codingcontext.WithAgent(agent),
+		codingcontext.WithLogger(logger),
+		codingcontext.WithSession(true),
+	)
+
+	return func() {
+		closeContext(side.cc, logger)
+		cleanup()
+	}, nil
+}
+
+// diffTasks returns the tasks of the old side followed by those only in the new side.
+func diffTasks(ctx context.Context, oldSide, newSide *diffSide) ([]string, error) {
+	var tasks []string
+
+	for _, side := range []*diffSide{oldSide, newSide} {
+		discovered, err := side.cc.ListTasks(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s side: failed to list tasks: %w", side.name, err)
+		}
+
+		for _, task := range discovered {
+			if !slices.Contains(tasks, task.Name) {
+				tasks = append(tasks, task.Name)
+			}
+		}
+	}
+
+	return tasks, nil
+}
+
+// diffTask assembles task on each side and compares them. The contexts are assembled like
+// linting, so no bootstrap scripts or shell commands are run. A task missing from one side
+// is compared with an empty context.
+func diffTask(ctx context.Context, task string, oldSide, newSide *diffSide) (codingcontext.ResultDiff, error) {
+	results := make([]*codingcontext.Result, 0, 2)
+
+	for _, side := range []*diffSide{oldSide, newSide} {
+		result, err := side.cc.Lint(ctx, task)
+
+		switch {
+		case errors.Is(err, codingcontext.ErrTaskNotFound):
+			results = append(results, nil)
+		case err != nil:
+			return codingcontext.ResultDiff{}, fmt.Errorf("%s side: task %s: %w", side.name, task, err)
+		default:
+			results = append(results, result.Result)
+		}
+	}
+
+	diff, err := codingcontext.DiffResults(results[0], results[1],
+		task+" ("+oldSide.name+")", task+" ("+newSide.name+")")
+	if err != nil {
+		return codingcontext.ResultDiff{}, fmt.Errorf("task %s: %w", task, err)
+	}
+
+	return diff, nil
+}
+
+// writeTaskDiff writes the summary and prompt diff of a task.
+func writeTaskDiff(w io.Writer, task string, diff codingcontext.ResultDiff) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "=== %s\n", task)
+
+	for _, rules := range []struct {
+		label string
+		names []string
+	}{
+		{"Rules added", diff.RulesAdded},
+		{"Rules removed", diff.RulesRemoved},
+		{"Rules changed", diff.RulesChanged},
+	} {
+		if len(rules.names) > 0 {
+			fmt.Fprintf(&b, "%s: %s\n", rules.label, strings.Join(rules.names, ", "))
+		}
+	}
+
+	fmt.Fprintf(&b, "Tokens: %d -> %d (%+d)\n", diff.OldTokens, diff.NewTokens, diff.TokenDelta())
+	b.WriteString(diff.PromptDiff)
+	b.WriteString("\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+
+	return nil
+}
+
+// checkoutGitRef writes the files of the directory dir at the git ref to a new temporary
+// directory and returns it. dir may be a subdirectory of the repository.
+func checkoutGitRef(ctx context.Context, dir, ref string) (string, error) {
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--show-toplevel", "--show-prefix").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to find git repository of %s: %w", dir, err)
+	}
+
+	// The prefix is empty, leaving a single line, when dir is the top level.
+	topLevel, prefix, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+
+	tmp, err := os.MkdirTemp("", "coding-context-diff-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory for git ref %s: %w", ref, err)
+	}
+
+	// Cancelling kills git archive if extracting fails part way through.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stderr bytes.Buffer
+
+	// git archive is run from the top level, because from a subdirectory it only archives
+	// that subdirectory of the tree. The archive is streamed to disk as it is written, so
+	// that a large repository is never held in memory.
+	// #nosec G204 -- ref is passed as a single argument to git, not to a shell
+	cmd := exec.CommandContext(ctx, "git", "-C", topLevel, "archive", "--format=tar", ref+":"+prefix)
+	cmd.Stderr = &stderr
+
+	archive, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+
+		return "", fmt.Errorf("failed to read git ref %s: %w", ref, err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		_ = os.RemoveAll(tmp)
+
+		return "", fmt.Errorf("failed to read git ref %s: %w", ref, err)
+	}
+
+	extractErr := extractTar(archive, tmp)
+	if extractErr != nil {
+		cancel()
+	}
+
+	// Read the padding after the end of the archive, so that git archive can exit.
+	_, _ = io.Copy(io.Discard, archive)
+
+	if err := cmd.Wait(); err != nil && extractErr == nil {
+		_ = os.RemoveAll(tmp)
+
+		return "", fmt.Errorf("failed to read git ref %s: %w: %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+
+	if extractErr != nil {
+		_ = os.RemoveAll(tmp)
+
+		return "", fmt.Errorf("failed to extract git ref %s: %w", ref, extractErr)
+	}
+
+	return tmp, nil
+}
+
+// extractTar extracts the directories, files, and relative symlinks of a tar archive into dst.
+// Directories and files are created through an os.Root, so that they cannot be written through
+// a symlink to outside dst; symlinks are checked by safeSymlink.
+func extractTar(r io.Reader, dst string) error {
+	const dirMode, fileMode = 0o750, 0o600
+
+	root, err := os.OpenRoot(dst)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", dst, err)
+	}
+	defer root.Close()
+
+	tr := tar.NewReader(r)
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := filepath.Clean(filepath.FromSlash(header.Name))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("%w: %s", errUnsafeTar, header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := mkdirAllInRoot(root, name, dirMode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := mkdirAllInRoot(root, filepath.Dir(name), dirMode); err != nil {
+				return err
+			}
+
+			// Keep the executable bit of bootstrap scripts.
+			mode := os.FileMode(fileMode) | os.FileMode(header.Mode)&0o100
+
+			// #nosec G110 -- the archive is produced by git from the user's own repository
+			file, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", name, err)
+			}
+
+			_, err = io.Copy(file, tr)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", name, err)
+			}
+		case tar.TypeSymlink:
+			if err := mkdirAllInRoot(root, filepath.Dir(name), dirMode); err != nil {
+				return err
+			}
+
+			target := filepath.FromSlash(header.Linkname)
+
+			safe, err := safeSymlink(root, name, target)
+			if err != nil {
+				return err
+			}
+
+			if safe {
+				if err := os.Symlink(target, filepath.Join(dst, name)); err != nil {
+					return fmt.Errorf("failed to create %s: %w", name, err)
+				}
+			}
+		}
+	}
+}
+
+// mkdirAllInRoot creates the directory name in root, along with any missing parents.
+func mkdirAllInRoot(root *os.Root, name string, mode os.FileMode) error {
+	var path string
+
+	for part := range strings.SplitSeq(name, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+
+		if err := root.Mkdir(path, mode); err != nil && !errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("failed to create %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// safeSymlink returns whether the symlink name -> target stays in root, so that it can be
+// created; a symlink that does not, e.g. an absolute one, is skipped. Git never archives a
+// path below a symlink, so a symlink whose parent is one is an error: a chain such as
+// a -> . and a/b -> .. would escape even though each target looks local.
+//
+// The target is only resolved lexically, which is right as long as its ".." elements come
+// first: they then climb from the symlink's parent, which is a real directory. Every later
+// element descends, possibly through another symlink that, checked the same way, stays in root.
+func safeSymlink(root *os.Root, name, target string) (bool, error) {
+	parent := filepath.Dir(name)
+
+	var path string
+
+	for part := range strings.SplitSeq(parent, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+
+		if info, err := root.Lstat(path); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return false, fmt.Errorf("%w: %s is below the symlink %s", errUnsafeTar, name, path)
+		}
+	}
+
+	if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(parent, target)) {
+		return false, nil
+	}
+
+	descending := false
+
+	for part := range strings.SplitSeq(target, string(filepath.Separator)) {
+		switch part {
+		case "..":
+			if descending {
+				return false, nil
+			}
+		case "", ".":
+		default:
+			descending = true
+		}
+	}
+
+	return true, nil
+}
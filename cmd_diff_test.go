@@ -0,0 +1,116 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tarEntry is a file, or with a link, a symlink, of a test archive.
+type tarEntry struct {
+	name, content, link string
+}
+
+func writeTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if entry.link != "" {
+			header = &tar.Header{Name: entry.name, Linkname: entry.link, Typeflag: tar.TypeSymlink}
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write header: %v", err)
+		}
+
+		if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatalf("failed to write content: %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	return &buf
+}
+
+func TestExtractTar(t *testing.T) {
+	t.Parallel()
+
+	dst := filepath.Join(t.TempDir(), "tree")
+	if err := os.Mkdir(dst, 0o750); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+
+	archive := writeTar(t, []tarEntry{
+		{name: "AGENTS.md", content: "# Rules\n"},
+		{name: ".agents/rules/go.md", content: "# Go\n"},
+		{name: "CLAUDE.md", link: "AGENTS.md"},
+		{name: ".agents/rules/shared.md", link: "../../AGENTS.md"},
+		{name: "absolute", link: "/etc/hostname"},
+		{name: "outside", link: "../outside"},
+		{name: "climbs-after-descending", link: ".agents/../../outside"},
+	})
+
+	if err := extractTar(archive, dst); err != nil {
+		t.Fatalf("extractTar() error: %v", err)
+	}
+
+	for _, name := range []string{".agents/rules/go.md", "CLAUDE.md", ".agents/rules/shared.md"} {
+		if _, err := os.ReadFile(filepath.Join(dst, name)); err != nil {
+			t.Errorf("%s was not extracted: %v", name, err)
+		}
+	}
+
+	for _, name := range []string{"absolute", "outside", "climbs-after-descending"} {
+		if _, err := os.Lstat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+			t.Errorf("unsafe symlink %s was extracted, stat error: %v", name, err)
+		}
+	}
+}
+
+func TestExtractTar_Unsafe(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		entries []tarEntry
+	}{
+		{name: "path outside", entries: []tarEntry{{name: "../outside.md", content: "x"}}},
+		{name: "symlink chain", entries: []tarEntry{{name: "a", link: "."}, {name: "a/b", link: ".."}}},
+		{name: "file below a symlink to outside", entries: []tarEntry{
+			{name: "a", link: "."}, {name: "a/b", link: "../.."}, {name: "a/b/escaped.md", content: "x"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// a/b -> ../.. would point to parent.
+			parent := t.TempDir()
+			dst := filepath.Join(parent, "x", "tree")
+
+			if err := os.MkdirAll(dst, 0o750); err != nil {
+				t.Fatalf("failed to create dir: %v", err)
+			}
+
+			if err := extractTar(writeTar(t, tt.entries), dst); !errors.Is(err, errUnsafeTar) {
+				t.Errorf("extractTar() error = %v, want %v", err, errUnsafeTar)
+			}
+
+			if _, err := os.Lstat(filepath.Join(parent, "escaped.md")); !os.IsNotExist(err) {
+				t.Errorf("extractTar() wrote outside dst, stat error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/selectors"
+)
+
+var (
+	errNewUsage     = errors.New("invalid usage: expected 'new [options] rule|task|command|skill <name>'")
+	errNewName      = errors.New("name must be a file name without a directory or extension")
+	errNewFlag      = errors.New("option does not apply")
+	errNewExists    = errors.New("file already exists")
+	errNewLintError = errors.New("created file has problems")
+)
+
+// newKinds are the kinds of file that new creates, with the directory under .agents (or a
+// namespace) they are created in.
+var newKinds = map[string]string{
+	"rule":    "rules",
+	"task":    "tasks",
+	"command": "commands",
+	"skill":   "skills",
+}
+
+// newOptions are the frontmatter options of the new subcommand.
+type newOptions struct {
+	namespace   string
+	description string
+	agent       string
+	languages   []string
+	includes    selectors.Selectors
+	expand      bool
+}
+
+// runNew creates a rule, task, command, or skill file with frontmatter from the options,
+// in .agents or the namespace's directory, and lints it. It refuses to overwrite a file.
+func runNew(ctx context.Context, args []string, stdout io.Writer, logger *slog.Logger) error {
+	opts := newOptions{includes: make(selectors.Selectors)}
+
+	flags := flag.NewFlagSet("new", flag.ContinueOnError)
+	workDir := flags.String("C", ".", "Create the file in this directory.")
+	flags.StringVar(&opts.namespace, "namespace", "", "Create the file in this namespace (.agents/namespaces/<name>).")
+	flags.StringVar(&opts.description, "description", "",
+		"Description of the file. Skills require one; a placeholder is written if it is not given.")
+	flags.StringVar(&opts.agent, "agent", "", "Agent the rule is for, or the task's default agent.")
+	flags.Func("languages", "Language of a rule, or of the rules a task selects. Can be specified multiple times.",
+		func(s string) error {
+			opts.languages = append(opts.languages, s)
+
+			return nil
+		})
+	flags.Var(&opts.includes, "s", "Selector, as key=value: frontmatter of a rule or skill, or the selectors "+
+		"of a task or command. Can be specified multiple times.")
+	flags.BoolVar(&opts.expand, "expand", true, "Expand parameters in the file; use -expand=false to write expand: false.")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errNewUsage, err)
+	}
+
+	if flags.NArg() != 2 {
+		return errNewUsage
+	}
+
+	kind, name := flags.Arg(0), flags.Arg(1)
+
+	dir, ok := newKinds[kind]
+	if !ok {
+		return fmt.Errorf("%w: unknown kind %q", errNewUsage, kind)
+	}
+
+	if err := validateNewName(kind, name); err != nil {
+		return err
+	}
+
+	if err := opts.check(kind); err != nil {
+		return err
+	}
+
+	agentsDir := filepath.Join(*workDir, ".agents")
+	if opts.namespace != "" {
+		if err := validateNewName("namespace", opts.namespace); err != nil {
+			return err
+		}
+
+		agentsDir = filepath.Join(agentsDir, "namespaces", opts.namespace)
+	}
+
+	path := filepath.Join(agentsDir, dir, name+".md")
+	if kind == "skill" {
+		path = filepath.Join(agentsDir, dir, name, "SKILL.md")
+	}
+
+	content, err := opts.render(kind, name)
+	if err != nil {
+		return err
+	}
+
+	if err := createNewFile(path, content); err != nil {
+		return err
+	}
+
+	logger.Info("Created "+kind, "path", path)
+
+	problems, err := lintNewFile(ctx, kind, name, path, *workDir, opts.namespace, logger)
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range problems {
+		if _, err := fmt.Fprintln(stdout, problem); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %d problem(s) in %s", errNewLintError, len(problems), path)
+	}
+
+	return nil
+}
+
+// validateNewName checks that name can be used as the file or directory name of kind.
+func validateNewName(kind, name string) error {
+	if kind == "skill" {
+		if err := codingcontext.ValidateSkillName(name); err != nil {
+			return fmt.Errorf("invalid skill name: %w", err)
+		}
+
+		return nil
+	}
+
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") ||
+		strings.ContainsAny(name, `/\`) || filepath.Ext(name) == ".md" {
+		return fmt.Errorf("invalid %s name %q: %w", kind, name, errNewName)
+	}
+
+	return nil
+}
+
+// check returns an error for an option that does not apply to kind.
+func (o newOptions) check(kind string) error {
+	for _, opt := range []struct {
+		flag  string
+		set   bool
+		kinds []string
+	}{
+		{"-agent", o.agent != "", []string{"rule", "task"}},
+		{"-languages", len(o.languages) > 0, []string{"rule", "task"}},
+		{"-expand", !o.expand, []string{"rule", "task", "command"}},
+	} {
+		if opt.set && !slices.Contains(opt.kinds, kind) {
+			return fmt.Errorf("%w: %s cannot be used for a %s", errNewFlag, opt.flag, kind)
+		}
+	}
+
+	if o.agent != "" {
+		if _, err := codingcontext.ParseAgent(o.agent); err != nil {
+			return fmt.Errorf("invalid -agent: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// render returns the content of a new file of kind: frontmatter from the options, and a
+// body to replace.
+func (o newOptions) render(kind, name string) (string, error) {
+	var frontMatter yaml.MapSlice
+
+	add := func(key string, value any) {
+		frontMatter = append(frontMatter, yaml.MapItem{Key: key, Value: value})
+	}
+
+	description := o.description
+	if kind == "skill" {
+		add("name", name)
+
+		if description == "" {
+			description = "TODO: Describe what this skill does and when to use it."
+		}
+	}
+
+	if description != "" {
+		add("description", description)
+	}
+
+	if o.agent != "" {
+		add("agent", o.agent)
+	}
+
+	if len(o.languages) > 0 {
+		add("languages", o.languages)
+	}
+
+	if len(o.includes) > 0 {
+		selectorMap := make(yaml.MapSlice, 0, len(o.includes))
+
+		for _, key := range sortedKeys(o.includes) {
+			values := sortedKeys(o.includes[key])
+
+			var value any = values
+			if len(values) == 1 {
+				value = values[0]
+			}
+
+			selectorMap = append(selectorMap, yaml.MapItem{Key: key, Value: value})
+		}
+
+		// Rules and skills are selected by their own frontmatter; tasks and commands
+		// select rules with their selectors field.
+		if kind == "rule" || kind == "skill" {
+			frontMatter = append(frontMatter, selectorMap...)
+		} else {
+			add("selectors", selectorMap)
+		}
+	}
+
+	if !o.expand {
+		add("expand", false)
+	}
+
+	var b strings.Builder
+
+	if len(frontMatter) > 0 {
+		out, err := yaml.Marshal(frontMatter)
+		if err != nil {
+			return "", fmt.Errorf("failed to write frontmatter: %w", err)
+		}
+
+		b.WriteString("---\n")
+		b.Write(out)
+		b.WriteString("---\n\n")
+	}
+
+	switch kind {
+	case "rule":
+		fmt.Fprintf(&b, "# %s\n\nTODO: Write the rule.\n", name)
+	case "task":
+		fmt.Fprintf(&b, "# %s\n\nTODO: Write the task.\n", name)
+	case "command":
+		b.WriteString("TODO: Write the text that /" + name + " adds to a task.\n")
+	case "skill":
+		fmt.Fprintf(&b, "# %s\n\nTODO: Write the instructions for using the skill.\n", name)
+	}
+
+	return b.String(), nil
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
+
+// createNewFile writes content to a new file at path, creating its directory. It fails if
+// the file exists.
+func createNewFile(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	file, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: %s", errNewExists, path)
+	} else if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	_, err = file.WriteString(content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// lintNewFile returns the problems with the new file: a skill is validated against the
+// Agent Skills specification, a task is linted, and rules and commands are parsed.
+func lintNewFile(
+	ctx context.Context, kind, name, path, workDir, namespace string, logger *slog.Logger,
+) ([]string, error) {
+	var problems []string
+
+	switch kind {
+	case "skill":
+		skillProblems, err := codingcontext.ValidateSkill(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate skill: %w", err)
+		}
+
+		for _, problem := range skillProblems {
+			problems = append(problems, problem.Error())
+		}
+	case "task":
+		taskName := name
+		if namespace != "" {
+			taskName = namespace + "/" + name
+		}
+
+		// Only the problems are of interest, not the files the task would include.
+		quiet := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+		cc := codingcontext.New(codingcontext.WithSearchPaths(workDir), codingcontext.WithLogger(quiet))
+		defer closeContext(cc, logger)
+
+		result, err := cc.Lint(ctx, taskName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lint task: %w", err)
+		}
+
+		for _, lintErr := range result.Errors {
+			problems = append(problems, fmt.Sprintf("%s: %s", lintErr.Path, lintErr.Message))
+		}
+	case "rule":
+		if _, err := markdown.ParseMarkdownFile(path, &markdown.RuleFrontMatter{}); err != nil {
+			problems = append(problems, err.Error())
+		}
+	case "command":
+		if _, err := markdown.ParseMarkdownFile(path, &markdown.CommandFrontMatter{}); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+)
+
+func TestRunNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantPath string
+		want     string
+	}{
+		{
+			name:     "rule with languages and selectors",
+			args:     []string{"-languages", "go", "-s", "team=backend", "-agent", "cursor", "rule", "go-style"},
+			wantPath: ".agents/rules/go-style.md",
+			want: "---\nagent: cursor\nlanguages:\n- go\nteam: backend\n---\n\n" +
+				"# go-style\n\nTODO: Write the rule.\n",
+		},
+		{
+			name:     "task without options",
+			args:     []string{"task", "fix-bug"},
+			wantPath: ".agents/tasks/fix-bug.md",
+			want:     "# fix-bug\n\nTODO: Write the task.\n",
+		},
+		{
+			name:     "command with selectors and expand",
+			args:     []string{"-s", "team=a", "-s", "team=b", "-expand=false", "command", "setup"},
+			wantPath: ".agents/commands/setup.md",
+			want: "---\nselectors:\n  team:\n  - a\n  - b\nexpand: false\n---\n\n" +
+				"TODO: Write the text that /setup adds to a task.\n",
+		},
+		{
+			name:     "skill in a namespace",
+			args:     []string{"-namespace", "myteam", "-description", "Formats Go code.", "skill", "go-fmt"},
+			wantPath: ".agents/namespaces/myteam/skills/go-fmt/SKILL.md",
+			want: "---\nname: go-fmt\ndescription: Formats Go code.\n---\n\n" +
+				"# go-fmt\n\nTODO: Write the instructions for using the skill.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+			var stdout bytes.Buffer
+
+			if err := runNew(t.Context(), append([]string{"-C", dir}, tt.args...), &stdout, logger); err != nil {
+				t.Fatalf("runNew() error = %v, output:\n%s", err, stdout.String())
+			}
+
+			got, err := os.ReadFile(filepath.Join(dir, tt.wantPath))
+			if err != nil {
+				t.Fatalf("failed to read created file: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("created file =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunNew_DefaultSkillDescription(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	if err := runNew(t.Context(), []string{"-C", dir, "skill", "my-skill"}, io.Discard, logger); err != nil {
+		t.Fatalf("runNew() error = %v", err)
+	}
+
+	problems, err := codingcontext.ValidateSkill(filepath.Join(dir, ".agents", "skills", "my-skill", "SKILL.md"))
+	if err != nil {
+		t.Fatalf("ValidateSkill() error = %v", err)
+	}
+
+	if len(problems) != 0 {
+		t.Errorf("ValidateSkill() problems = %v, want none", problems)
+	}
+}
+
+func TestRunNew_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr error
+	}{
+		{name: "missing name", args: []string{"rule"}, wantErr: errNewUsage},
+		{name: "unknown kind", args: []string{"agent", "x"}, wantErr: errNewUsage},
+		{name: "name with directory", args: []string{"rule", "a/b"}, wantErr: errNewName},
+		{name: "name with extension", args: []string{"task", "fix.md"}, wantErr: errNewName},
+		{name: "invalid skill name", args: []string{"skill", "My_Skill"}, wantErr: codingcontext.ErrSkillNameFormat},
+		{name: "agent for a command", args: []string{"-agent", "cursor", "command", "x"}, wantErr: errNewFlag},
+		{name: "expand for a skill", args: []string{"-expand=false", "skill", "x"}, wantErr: errNewFlag},
+		{name: "selector no rule has", args: []string{"-s", "team=none", "task", "x"}, wantErr: errNewLintError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+			err := runNew(t.Context(), append([]string{"-C", dir}, tt.args...), io.Discard, logger)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("runNew() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunNew_RefusesToOverwrite(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".agents", "rules", "existing.md")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatalf("failed to create rules dir: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("# Mine\n"), 0o600); err != nil {
+		t.Fatalf("failed to write rule: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	err := runNew(t.Context(), []string{"-C", dir, "rule", "existing"}, io.Discard, logger)
+	if !errors.Is(err, errNewExists) {
+		t.Errorf("runNew() error = %v, want %v", err, errNewExists)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read rule: %v", err)
+	}
+
+	if string(got) != "# Mine\n" {
+		t.Errorf("rule was overwritten: %q", got)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/selectors"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
+)
+
+var (
+	errServeUsage  = errors.New("invalid usage: expected 'serve [options]'")
+	errLintNoTask  = errors.New("task is required")
+	errInvalidBody = errors.New("invalid request body")
+	errNotJSON     = errors.New("request body must be application/json")
+	errNoToken     = errors.New("missing or invalid bearer token")
+)
+
+// maxRequestBytes limits the size of a request body.
+const maxRequestBytes = 1 << 20
+
+// assembleRequest is the body of POST /tasks/{name}/assemble and POST /lint. Every field is
+// optional, except the task of a lint request.
+type assembleRequest struct {
+	Task       string              `json:"task,omitempty"` // Only for POST /lint
+	Params     map[string]string   `json:"params,omitempty"`
+	Selectors  map[string][]string `json:"selectors,omitempty"`
+	Agent      string              `json:"agent,omitempty"`
+	UserPrompt string              `json:"user_prompt,omitempty"`
+}
+
+// server serves assembled contexts over HTTP. Every request runs on its own copy of cc,
+// made by With, so that requests share the downloaded search paths and parsed files.
+type server struct {
+	cc      *codingcontext.Context
+	timeout time.Duration
+	token   string // If set, every request must send it as a bearer token
+	logger  *slog.Logger
+}
+
+// runServe serves the HTTP API until ctx is done.
+func runServe(ctx context.Context, args []string, logger *slog.Logger) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	workDir := flags.String("C", ".", "Change to directory before doing anything.")
+	addr := flags.String("addr", "localhost:8080", "Address to listen on.")
+	timeout := flags.Duration("timeout", time.Minute,
+		"Maximum time to assemble the context for a request, including bootstrap scripts.")
+	refresh := flags.Duration("refresh", 10*time.Minute,
+		"Download remote search paths again when they are older than this; 0 keeps them until the server stops.")
+	skipBootstrap := flags.Bool("skip-bootstrap", false,
+		"Skip bootstrap: skip discovering rules, skills, and running bootstrap scripts.")
+	manifestURL := flags.String("m", "",
+		"Go Getter URL to a manifest file containing search paths (one per line). Every line is included as-is.")
+	token := flags.String("token", os.Getenv("CODING_CONTEXT_TOKEN"),
+		"Require every request to send this bearer token (default $CODING_CONTEXT_TOKEN).")
+
+	var searchPaths, lenientSearchPaths []string
+
+	flags.Func("d", "Directory containing rules and tasks (strict: errors are fatal). Can be specified multiple times.",
+		func(s string) error {
+			searchPaths = append(searchPaths, s)
+
+			return nil
+		})
+	flags.Func("D", "Directory containing rules and tasks (lenient: errors are warnings). "+
+		"Can be specified multiple times.",
+		func(s string) error {
+			lenientSearchPaths = append(lenientSearchPaths, s)
+
+			return nil
+		})
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errServeUsage, err)
+	}
+
+	if flags.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errServeUsage, flags.Arg(0))
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	if err := loadAgentConfigs(homeDir, *workDir); err != nil {
+		return err
+	}
+
+	cc := codingcontext.New(
+		codingcontext.WithSearchPaths(append(searchPaths, "file://"+*workDir, "file://"+homeDir)...),
+		codingcontext.WithLenientSearchPaths(lenientSearchPaths...),
+		codingcontext.WithManifestURL(*manifestURL),
+		codingcontext.WithBootstrap(!*skipBootstrap),
+		codingcontext.WithLogger(logger),
+		codingcontext.WithSession(true),
+		codingcontext.WithRefresh(*refresh),
+	)
+	defer closeContext(cc, logger)
+
+	srv := &server{cc: cc, timeout: *timeout, token: *token, logger: logger}
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		// Leave time to write the response of a request that takes the whole timeout.
+		WriteTimeout: *timeout + 30*time.Second,
+		BaseContext:  func(net.Listener) context.Context { return ctx },
+	}
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", *addr, err)
+	}
+
+	logger.Info("Serving", "addr", listener.Addr().String())
+
+	errCh := make(chan error, 1)
+
+	go func() { errCh <- httpServer.Serve(listener) }()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), *timeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+
+	return nil
+}
+
+// handler returns the routes of the API.
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /tasks", s.listTasks)
+	// A namespaced task's name is escaped, e.g. /tasks/myteam%2Ffix-bug/assemble.
+	mux.HandleFunc("POST /tasks/{name}/assemble", s.assemble)
+	mux.HandleFunc("POST /lint", s.lint)
+
+	return s.authorize(mux)
+}
+
+// authorize rejects requests that do not send the server's bearer token, if it has one.
+func (s *server) authorize(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.writeError(w, http.StatusUnauthorized, errNoToken)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// listTasks responds with the tasks found in the search paths.
+func (s *server) listTasks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+
+	tasks, err := s.cc.ListTasks(ctx)
+	if err != nil {
+		s.writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list tasks: %w", err))
+
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, tasks)
+}
+
+// assemble responds with the context assembled for the task named in the path.
+func (s *server) assemble(w http.ResponseWriter, r *http.Request) {
+	req, cc, ok := s.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+
+	result, err := cc.Run(ctx, r.PathValue("name"))
+	if err != nil {
+		s.writeError(w, statusOf(err), err)
+
+		return
+	}
+
+	s.logger.Info("Assembled context", "task", result.Name, "agent", req.Agent, "tokens", result.Tokens)
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// lint responds with the lint result of the task named in the body.
+func (s *server) lint(w http.ResponseWriter, r *http.Request) {
+	req, cc, ok := s.parseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Task == "" {
+		s.writeError(w, http.StatusBadRequest, errLintNoTask)
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+	defer cancel()
+
+	result, err := cc.Lint(ctx, req.Task)
+	if err != nil {
+		s.writeError(w, statusOf(err), err)
+
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, result)
+}
+
+// parseRequest decodes the body of r and returns a Context for it. If the body is invalid,
+// it writes the error response and returns false. The body must be sent as JSON, which a
+// browser cannot do for another site without its consent.
+func (s *server) parseRequest(w http.ResponseWriter, r *http.Request) (assembleRequest, *codingcontext.Context, bool) {
+	var req assembleRequest
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil ||
+		mediaType != "application/json" {
+		s.writeError(w, http.StatusUnsupportedMediaType, errNotJSON)
+
+		return req, nil, false
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+
+	// An empty body is an empty request.
+	if err := decoder.Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.writeError(w, http.StatusBadRequest, fmt.Errorf("%w: %w", errInvalidBody, err))
+
+		return req, nil, false
+	}
+
+	params := make(taskparser.Params)
+	for key, value := range req.Params {
+		params[key] = []string{value}
+	}
+
+	includes := make(selectors.Selectors)
+
+	for key, values := range req.Selectors {
+		for _, value := range values {
+			includes.SetValue(key, value)
+		}
+	}
+
+	// The user prompt is taken as plain text, so that a request cannot run commands or
+	// read files through it.
+	opts := []codingcontext.Option{
+		codingcontext.WithParams(params),
+		codingcontext.WithSelectors(includes),
+		codingcontext.WithUserPrompt(req.UserPrompt),
+		codingcontext.WithLiteralUserPrompt(true),
+	}
+
+	if req.Agent != "" {
+		agent, err := codingcontext.ParseAgent(req.Agent)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid agent: %w", err))
+
+			return req, nil, false
+		}
+
+		opts = append(opts, codingcontext.WithAgent(agent))
+	}
+
+	return req, s.cc.With(opts...), true
+}
+
+// statusOf returns the HTTP status for an error from assembling a context.
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, codingcontext.ErrTaskNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as the JSON response body.
+func (s *server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Warn("Failed to write response", "error", err)
+	}
+}
+
+// writeError writes err as a JSON response body of the form {"error": "..."}.
+func (s *server) writeError(w http.ResponseWriter, status int, err error) {
+	s.logger.Warn("Request failed", "status", status, "error", err)
+	s.writeJSON(w, status, map[string]string{"error": err.Error()})
+}
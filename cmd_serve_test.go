@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+)
+
+func TestServe(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+
+	files := map[string]string{
+		filepath.Join(dirs.tasksDir, "deploy.md"): "Deploy to ${env}.\n",
+		filepath.Join(dirs.rulesDir, "go.md"):     "---\nteam: go\n---\n# Go Rule\n",
+		filepath.Join(dirs.rulesDir, "python.md"): "---\nteam: python\n---\n# Python Rule\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := &server{
+		cc: codingcontext.New(
+			codingcontext.WithSearchPaths(dirs.tmpDir),
+			codingcontext.WithLogger(logger),
+			codingcontext.WithSession(true),
+		),
+		timeout: time.Minute,
+		logger:  logger,
+	}
+
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+
+	do := func(method, path, body string, wantStatus int, v any) {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(t.Context(), method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+
+		if method == http.MethodPost {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != wantStatus {
+			t.Errorf("%s %s status = %d, want %d", method, path, resp.StatusCode, wantStatus)
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+
+	var tasks []codingcontext.DiscoveredTask
+
+	do(http.MethodGet, "/tasks", "", http.StatusOK, &tasks)
+
+	if len(tasks) != 1 || tasks[0].Name != "deploy" {
+		t.Errorf("GET /tasks = %+v, want the deploy task", tasks)
+	}
+
+	// The full Result cannot be decoded, because its markdown files hold their parsed AST.
+	var result struct {
+		Agent  codingcontext.Agent
+		Prompt string
+	}
+
+	do(http.MethodPost, "/tasks/deploy/assemble",
+		`{"params": {"env": "production"}, "selectors": {"team": ["python"]}, "agent": "claude", "user_prompt": "Hurry."}`,
+		http.StatusOK, &result)
+
+	for _, want := range []string{"# Python Rule", "Deploy to production.", "Hurry."} {
+		if !strings.Contains(result.Prompt, want) {
+			t.Errorf("assembled prompt does not contain %q:\n%s", want, result.Prompt)
+		}
+	}
+
+	if strings.Contains(result.Prompt, "# Go Rule") || result.Agent != codingcontext.AgentClaude {
+		t.Errorf("assembled context did not use the request's selectors and agent: %+v", result)
+	}
+
+	// Requests do not affect each other.
+	do(http.MethodPost, "/tasks/deploy/assemble", `{"selectors": {"team": ["go"]}}`, http.StatusOK, &result)
+
+	if !strings.Contains(result.Prompt, "# Go Rule") || strings.Contains(result.Prompt, "# Python Rule") {
+		t.Errorf("assembled prompt used the selectors of an earlier request:\n%s", result.Prompt)
+	}
+
+	var lint struct {
+		LoadedFiles []codingcontext.LoadedFile
+	}
+
+	do(http.MethodPost, "/lint", `{"task": "deploy"}`, http.StatusOK, &lint)
+
+	if len(lint.LoadedFiles) == 0 {
+		t.Error("POST /lint did not report the loaded files")
+	}
+
+	var errResp map[string]string
+
+	do(http.MethodPost, "/tasks/missing/assemble", "", http.StatusNotFound, &errResp)
+	do(http.MethodPost, "/lint", `{}`, http.StatusBadRequest, &errResp)
+	do(http.MethodPost, "/tasks/deploy/assemble", `{"agent": "nope"}`, http.StatusBadRequest, &errResp)
+
+	if errResp["error"] == "" {
+		t.Error("error response does not contain the error")
+	}
+}
+
+func TestServeRejectsUnsafeRequests(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+
+	if err := os.WriteFile(filepath.Join(dirs.tasksDir, "deploy.md"), []byte("Deploy.\n"), 0o600); err != nil {
+		t.Fatalf("failed to write task: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := &server{
+		cc: codingcontext.New(
+			codingcontext.WithSearchPaths(dirs.tmpDir),
+			codingcontext.WithLogger(logger),
+			codingcontext.WithSession(true),
+		),
+		timeout: time.Minute,
+		token:   "secret",
+		logger:  logger,
+	}
+
+	ts := httptest.NewServer(srv.handler())
+	t.Cleanup(ts.Close)
+
+	do := func(contentType, token, body string, wantStatus int) string {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, ts.URL+"/tasks/deploy/assemble",
+			strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+
+		req.Header.Set("Content-Type", contentType)
+
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != wantStatus {
+			t.Errorf("Content-Type %q, token %q: status = %d, want %d", contentType, token, resp.StatusCode, wantStatus)
+		}
+
+		var result struct {
+			Prompt string
+		}
+
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+
+		return result.Prompt
+	}
+
+	do("application/json", "", "{}", http.StatusUnauthorized)
+	do("application/json", "wrong", "{}", http.StatusUnauthorized)
+	do("text/plain", "secret", "{}", http.StatusUnsupportedMediaType)
+	do("application/x-www-form-urlencoded", "secret", "{}", http.StatusUnsupportedMediaType)
+
+	// The user prompt is not expanded.
+	userPrompt := "!`echo injected`\n@" + filepath.Join(dirs.tasksDir, "deploy.md") + "\n"
+
+	body, err := json.Marshal(map[string]string{"user_prompt": userPrompt})
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	prompt := do("application/json; charset=utf-8", "secret", string(body), http.StatusOK)
+	if !strings.Contains(prompt, userPrompt) {
+		t.Errorf("assembled prompt does not contain the user prompt as-is:\n%s", prompt)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+)
+
+var (
+	errSkillsUsage = errors.New("invalid usage: expected 'skills validate [path...]', " +
+		"'skills install <url>...', or 'skills pack <skill-dir>'")
+	errSkillsInvalid = errors.New("skill validation failed")
+)
+
+// runSkills runs the skills subcommand with the arguments following "skills".
+func runSkills(ctx context.Context, args []string, stdout io.Writer, logger *slog.Logger) error {
+	if len(args) == 0 {
+		return errSkillsUsage
+	}
+
+	switch args[0] {
+	case "validate":
+		return runSkillsValidate(args[1:], stdout, logger)
+	case "install":
+		return runSkillsInstall(ctx, args[1:], logger)
+	case "pack":
+		return runSkillsPack(args[1:], stdout, logger)
+	default:
+		return fmt.Errorf("%w: unknown skills command %q", errSkillsUsage, args[0])
+	}
+}
+
+// runSkillsValidate validates skills against the Agent Skills specification, printing
+// one line per problem. Each path may be a SKILL.md file, a skill directory, or a
+// directory whose skill search paths are searched; it defaults to the -C directory.
+func runSkillsValidate(args []string, stdout io.Writer, logger *slog.Logger) error {
+	flags := flag.NewFlagSet("skills validate", flag.ContinueOnError)
+	workDir := flags.String("C", ".", "Resolve relative paths against this directory.")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errSkillsUsage, err)
+	}
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	var skillFiles []string
+
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(*workDir, path)
+		}
+
+		files, err := codingcontext.SkillFiles(path)
+		if err != nil {
+			return fmt.Errorf("failed to find skills: %w", err)
+		}
+
+		skillFiles = append(skillFiles, files...)
+	}
+
+	count := 0
+
+	for _, skillFile := range skillFiles {
+		problems, err := codingcontext.ValidateSkill(skillFile)
+		if err != nil {
+			return fmt.Errorf("failed to validate skill: %w", err)
+		}
+
+		for _, problem := range problems {
+			if _, err := fmt.Fprintln(stdout, problem.Error()); err != nil {
+				return fmt.Errorf("writing output: %w", err)
+			}
+		}
+
+		count += len(problems)
+	}
+
+	logger.Info("Validated skills", "skills", len(skillFiles), "problems", count)
+
+	if count > 0 {
+		return fmt.Errorf("%w: %d problem(s) in %d skill(s)", errSkillsInvalid, count, len(skillFiles))
+	}
+
+	return nil
+}
+
+// runSkillsInstall installs the skills found at each go-getter URL into .agents/skills
+// and records them in the skills lock file.
+func runSkillsInstall(ctx context.Context, args []string, logger *slog.Logger) error {
+	flags := flag.NewFlagSet("skills install", flag.ContinueOnError)
+	workDir := flags.String("C", ".", "Install skills into this directory.")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errSkillsUsage, err)
+	}
+
+	if flags.NArg() == 0 {
+		return errSkillsUsage
+	}
+
+	for _, src := range flags.Args() {
+		installed, err := codingcontext.InstallSkills(ctx, src, *workDir)
+		if err != nil {
+			return fmt.Errorf("failed to install skills from %s: %w", src, err)
+		}
+
+		for _, skill := range installed {
+			logger.Info("Installed skill", "name", skill.Name, "path", skill.Path, "source", src, "digest", skill.Digest)
+		}
+	}
+
+	return nil
+}
+
+// runSkillsPack writes the skill in the given directory as a .tar.gz bundle, by default
+// <name>.tar.gz in the -C directory. With "-o -" the bundle is written to stdout.
+func runSkillsPack(args []string, stdout io.Writer, logger *slog.Logger) error {
+	flags := flag.NewFlagSet("skills pack", flag.ContinueOnError)
+	workDir := flags.String("C", ".", "Resolve relative paths against this directory.")
+	output := flags.String("o", "", "Write the bundle to this file, or - for stdout. Defaults to <name>.tar.gz.")
+
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %w", errSkillsUsage, err)
+	}
+
+	if flags.NArg() != 1 {
+		return errSkillsUsage
+	}
+
+	skillDir := flags.Arg(0)
+	if !filepath.IsAbs(skillDir) {
+		skillDir = filepath.Join(*workDir, skillDir)
+	}
+
+	if *output == "-" {
+		if _, err := codingcontext.PackSkill(skillDir, stdout); err != nil {
+			return fmt.Errorf("failed to pack skill: %w", err)
+		}
+
+		return nil
+	}
+
+	var bundle bytes.Buffer
+
+	name, err := codingcontext.PackSkill(skillDir, &bundle)
+	if err != nil {
+		return fmt.Errorf("failed to pack skill: %w", err)
+	}
+
+	path := *output
+	if path == "" {
+		path = name + ".tar.gz"
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(*workDir, path)
+	}
+
+	if err := os.WriteFile(path, bundle.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write skill bundle: %w", err)
+	}
+
+	logger.Info("Packed skill", "name", name, "path", path)
+
+	return nil
+}
@@ -1760,6 +1760,350 @@ func TestWriteRulesOptionWithoutAgent(t *testing.T) {
 }
 
 //nolint:funlen
+
+func TestWriteRulesForMultipleAgents(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+	tmpHome := t.TempDir()
+
+	createStandardTask(t, dirs.tasksDir)
+
+	files := map[string]string{
+		filepath.Join(dirs.rulesDir, "shared.md"):                        "# Shared Rule\n",
+		filepath.Join(dirs.tmpDir, "CLAUDE.md"):                          "# Claude Rule\n",
+		filepath.Join(dirs.tmpDir, ".cursor", "rules", "cursor-rule.md"): "# Cursor Rule\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write rule file: %v", err)
+		}
+	}
+
+	gomodcache := os.Getenv("GOMODCACHE")
+	if gomodcache == "" {
+		gomodcache = filepath.Join(os.Getenv("HOME"), "go", "pkg", "mod")
+	}
+
+	// The context is assembled for each agent, so the bootstrap runs for each.
+	bootstrapLog := filepath.Join(dirs.tmpDir, "bootstrap.log")
+	bootstrap := "#!/bin/sh\necho ran >> " + bootstrapLog + "\n"
+
+	// #nosec G306 -- bootstrap scripts require 0755 for direct execution
+	if err := os.WriteFile(filepath.Join(dirs.rulesDir, "shared-bootstrap"), []byte(bootstrap), 0o755); err != nil {
+		t.Fatalf("failed to write bootstrap script: %v", err)
+	}
+
+	output := runToolWithEnv(t, []string{"HOME=" + tmpHome, "GOMODCACHE=" + gomodcache},
+		"-C", dirs.tmpDir, "-w=cursor,claude", "test-task")
+
+	if strings.Count(output, "# Test Task") != 1 {
+		t.Errorf("task should be printed once:\n%s", output)
+	}
+
+	if ran, err := os.ReadFile(bootstrapLog); err != nil || string(ran) != "ran\nran\n" {
+		t.Errorf("bootstrap should run for each agent, log = %q, error = %v", ran, err)
+	}
+
+	if strings.Count(output, "Rules written") != 2 {
+		t.Errorf("expected a report for each agent:\n%s", output)
+	}
+
+	tests := []struct {
+		path    string
+		want    []string
+		notWant string
+	}{
+		{
+			path:    filepath.Join(tmpHome, ".cursor", "rules", "AGENTS.md"),
+			want:    []string{"# Shared Rule", "# Claude Rule"},
+			notWant: "# Cursor Rule",
+		},
+		{
+			path:    filepath.Join(tmpHome, ".claude", "CLAUDE.md"),
+			want:    []string{"# Shared Rule", "# Cursor Rule"},
+			notWant: "# Claude Rule",
+		},
+	}
+
+	for _, tt := range tests {
+		rules, err := os.ReadFile(tt.path)
+		if err != nil {
+			t.Fatalf("rules were not written to %s: %v", tt.path, err)
+		}
+
+		for _, want := range tt.want {
+			if !strings.Contains(string(rules), want) {
+				t.Errorf("%s does not contain %q:\n%s", tt.path, want, rules)
+			}
+		}
+
+		if strings.Contains(string(rules), tt.notWant) {
+			t.Errorf("%s contains the agent's own rule %q:\n%s", tt.path, tt.notWant, rules)
+		}
+	}
+}
+
+func TestWriteRulesTaskAgent(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+	tmpHome := t.TempDir()
+
+	taskFile := filepath.Join(dirs.tasksDir, "claude-task.md")
+	if err := os.WriteFile(taskFile, []byte("---\nagent: claude\n---\n# Claude Task\n"), 0o600); err != nil {
+		t.Fatalf("failed to write task file: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dirs.rulesDir, "shared.md"), []byte("# Shared Rule\n"), 0o600); err != nil {
+		t.Fatalf("failed to write rule file: %v", err)
+	}
+
+	gomodcache := os.Getenv("GOMODCACHE")
+	if gomodcache == "" {
+		gomodcache = filepath.Join(os.Getenv("HOME"), "go", "pkg", "mod")
+	}
+
+	env := []string{"HOME=" + tmpHome, "GOMODCACHE=" + gomodcache}
+
+	runToolWithEnv(t, env, "-C", dirs.tmpDir, "-w=claude", "claude-task")
+
+	if _, err := os.Stat(filepath.Join(tmpHome, ".claude", "CLAUDE.md")); err != nil {
+		t.Errorf("rules were not written for the task's agent: %v", err)
+	}
+
+	// Any other agent that -w selects conflicts with the task's agent, and nothing is written for it.
+	for _, agents := range []string{"-w=cursor", "-w=cursor,claude"} {
+		output, err := runToolWithErrorAndEnv(env, "-C", dirs.tmpDir, agents, "claude-task")
+		if err == nil {
+			t.Fatalf("%s: expected an error when -w selects an agent other than the task's:\n%s", agents, output)
+		}
+
+		if !strings.Contains(output, "task selects claude, -w selects cursor") {
+			t.Errorf("%s: error should name the conflict:\n%s", agents, output)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpHome, ".cursor", "rules", "AGENTS.md")); !os.IsNotExist(err) {
+		t.Errorf("rules should not be written for another agent, stat error: %v", err)
+	}
+}
+
+func TestWriteRulesProjectScope(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+	tmpHome := t.TempDir()
+
+	createStandardTask(t, dirs.tasksDir)
+
+	if err := os.WriteFile(filepath.Join(dirs.rulesDir, "shared.md"), []byte("# Shared Rule\n"), 0o600); err != nil {
+		t.Fatalf("failed to write rule file: %v", err)
+	}
+
+	gomodcache := os.Getenv("GOMODCACHE")
+	if gomodcache == "" {
+		gomodcache = filepath.Join(os.Getenv("HOME"), "go", "pkg", "mod")
+	}
+
+	env := []string{"HOME=" + tmpHome, "GOMODCACHE=" + gomodcache}
+
+	runToolWithEnv(t, env, "-C", dirs.tmpDir, "-w=cursor,claude", "--scope", "project", "test-task")
+
+	claudeRules, err := os.ReadFile(filepath.Join(dirs.tmpDir, "CLAUDE.local.md"))
+	if err != nil {
+		t.Fatalf("rules were not written to the project: %v", err)
+	}
+
+	if !strings.Contains(string(claudeRules), "# Shared Rule") {
+		t.Errorf("project rules file does not contain the rule:\n%s", claudeRules)
+	}
+
+	cursorRules, err := os.ReadFile(filepath.Join(dirs.tmpDir, ".cursor", "rules", "coding-context.mdc"))
+	if err != nil {
+		t.Fatalf("rules were not written to the project: %v", err)
+	}
+
+	if !strings.HasPrefix(string(cursorRules), "---\nalwaysApply: true\n---\n") {
+		t.Errorf(".mdc rules file should always apply:\n%s", cursorRules)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpHome, ".claude", "CLAUDE.md")); !os.IsNotExist(err) {
+		t.Errorf("project scope should not write to the home directory, stat error: %v", err)
+	}
+
+	// Zed reads only its project's .rules file, so it can only be written with project scope.
+	runToolWithEnv(t, env, "-C", dirs.tmpDir, "-a", "zed", "-w", "--scope", "project", "test-task")
+
+	if zedRules, err := os.ReadFile(filepath.Join(dirs.tmpDir, ".rules")); err != nil ||
+		!strings.Contains(string(zedRules), "# Shared Rule") {
+		t.Errorf("rules were not written to .rules: %v\n%s", err, zedRules)
+	}
+
+	output, err := runToolWithErrorAndEnv(env, "-C", dirs.tmpDir, "-a", "zed", "-w", "test-task")
+	if err == nil {
+		t.Errorf("expected an error for an agent without a user rules path:\n%s", output)
+	}
+
+	if !strings.Contains(output, "no user rule path") {
+		t.Errorf("expected error about the missing user rule path, got: %s", output)
+	}
+}
+
+func TestWriteRulesProjectScopeTwice(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+	tmpHome := t.TempDir()
+
+	createStandardTask(t, dirs.tasksDir)
+
+	files := map[string]string{
+		filepath.Join(dirs.rulesDir, "shared.md"): "# Shared Rule\n",
+		// AGENTS.md is both a rule file and codex's project rules file.
+		filepath.Join(dirs.tmpDir, "AGENTS.md"): "# Project Notes\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	gomodcache := os.Getenv("GOMODCACHE")
+	if gomodcache == "" {
+		gomodcache = filepath.Join(os.Getenv("HOME"), "go", "pkg", "mod")
+	}
+
+	env := []string{"HOME=" + tmpHome, "GOMODCACHE=" + gomodcache}
+	args := []string{"-C", dirs.tmpDir, "-w=codex,claude,zed", "--scope", "project", "test-task"}
+	targets := []string{"AGENTS.md", "CLAUDE.local.md", ".rules"}
+
+	readTargets := func() map[string]string {
+		t.Helper()
+
+		contents := make(map[string]string, len(targets))
+
+		for _, target := range targets {
+			content, err := os.ReadFile(filepath.Join(dirs.tmpDir, target))
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", target, err)
+			}
+
+			contents[target] = string(content)
+		}
+
+		return contents
+	}
+
+	runToolWithEnv(t, env, args...)
+	first := readTargets()
+
+	runToolWithEnv(t, env, args...)
+	second := readTargets()
+
+	for _, target := range targets {
+		if first[target] != second[target] {
+			t.Errorf("%s changed when the rules were written again:\nfirst:\n%s\nsecond:\n%s",
+				target, first[target], second[target])
+		}
+
+		if n := strings.Count(second[target], "<!-- coding-context:begin -->"); n != 1 {
+			t.Errorf("%s has %d managed sections, want 1:\n%s", target, n, second[target])
+		}
+	}
+
+	// Claude reads AGENTS.md, but not the rules written to it.
+	want := "<!-- coding-context:begin -->\n# Shared Rule\n\n# Project Notes\n<!-- coding-context:end -->\n"
+	if !strings.HasSuffix(second["CLAUDE.local.md"], want) {
+		t.Errorf("CLAUDE.local.md = %q, want it to end with %q", second["CLAUDE.local.md"], want)
+	}
+}
+
+func TestWriteRulesManagedSection(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+	tmpHome := t.TempDir()
+
+	createStandardTask(t, dirs.tasksDir)
+
+	ruleFile := filepath.Join(dirs.rulesDir, "shared.md")
+	if err := os.WriteFile(ruleFile, []byte("# Old Rule\n"), 0o600); err != nil {
+		t.Fatalf("failed to write rule file: %v", err)
+	}
+
+	rulesPath := filepath.Join(tmpHome, ".claude", "CLAUDE.md")
+	if err := os.MkdirAll(filepath.Dir(rulesPath), 0o750); err != nil {
+		t.Fatalf("failed to create rules dir: %v", err)
+	}
+
+	if err := os.WriteFile(rulesPath, []byte("# My Notes\n"), 0o600); err != nil {
+		t.Fatalf("failed to write rules file: %v", err)
+	}
+
+	gomodcache := os.Getenv("GOMODCACHE")
+	if gomodcache == "" {
+		gomodcache = filepath.Join(os.Getenv("HOME"), "go", "pkg", "mod")
+	}
+
+	env := []string{"HOME=" + tmpHome, "GOMODCACHE=" + gomodcache}
+	args := []string{"-C", dirs.tmpDir, "-a", "claude", "-w"}
+
+	readRules := func() string {
+		t.Helper()
+
+		content, err := os.ReadFile(rulesPath)
+		if err != nil {
+			t.Fatalf("failed to read rules file: %v", err)
+		}
+
+		return string(content)
+	}
+
+	runToolWithEnv(t, env, append(args, "test-task")...)
+
+	want := "# My Notes\n\n<!-- coding-context:begin -->\n# Old Rule\n<!-- coding-context:end -->\n"
+	if got := readRules(); got != want {
+		t.Errorf("rules file = %q, want %q", got, want)
+	}
+
+	output := runToolWithEnv(t, env, append(args, "--check", "test-task")...)
+	if !strings.Contains(output, "Rules up to date") {
+		t.Errorf("expected the rules file to be up to date:\n%s", output)
+	}
+
+	if strings.Contains(output, "# Test Task") {
+		t.Errorf("check mode should not print the task:\n%s", output)
+	}
+
+	if err := os.WriteFile(ruleFile, []byte("# New Rule\n"), 0o600); err != nil {
+		t.Fatalf("failed to write rule file: %v", err)
+	}
+
+	output, err := runToolWithErrorAndEnv(env, append(args, "--check", "test-task")...)
+	if err == nil || !strings.Contains(output, "out of date") {
+		t.Errorf("expected check to fail for an out of date rules file, err: %v\n%s", err, output)
+	}
+
+	if got := readRules(); got != want {
+		t.Errorf("check mode changed the rules file: %q", got)
+	}
+
+	runToolWithEnv(t, env, append(args, "--backup", "test-task")...)
+
+	want = "# My Notes\n\n<!-- coding-context:begin -->\n# New Rule\n<!-- coding-context:end -->\n"
+	if got := readRules(); got != want {
+		t.Errorf("rules file = %q, want %q", got, want)
+	}
+
+	backup, err := os.ReadFile(rulesPath + ".bak")
+	if err != nil {
+		t.Fatalf("previous rules file was not backed up: %v", err)
+	}
+
+	if !strings.Contains(string(backup), "# Old Rule") {
+		t.Errorf("backup does not contain the previous rules:\n%s", backup)
+	}
+}
 func TestWriteRulesOptionWithResumeMode(t *testing.T) {
 	t.Parallel()
 	dirs := setupTestDirs(t)
@@ -2106,3 +2450,240 @@ func TestNonExistentSlashCommandPassesThrough(t *testing.T) {
 		t.Errorf("expected non-existent slash command to pass through as-is, got: %s", output)
 	}
 }
+
+func TestSkillsValidate(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+
+	skillDir := filepath.Join(dirs.tmpDir, ".agents", "skills", "pdf-processing")
+	if err := os.MkdirAll(skillDir, 0o750); err != nil {
+		t.Fatalf("failed to create skill dir: %v", err)
+	}
+
+	skillFile := filepath.Join(skillDir, "SKILL.md")
+
+	valid := "---\nname: pdf-processing\ndescription: Work with PDF files\n---\n# PDF\n"
+	if err := os.WriteFile(skillFile, []byte(valid), 0o600); err != nil {
+		t.Fatalf("failed to write skill file: %v", err)
+	}
+
+	runTool(t, "skills", "validate", "-C", dirs.tmpDir)
+
+	invalid := "---\nname: pdf\ndescription: Work with PDF files\n---\n# PDF\n"
+	if err := os.WriteFile(skillFile, []byte(invalid), 0o600); err != nil {
+		t.Fatalf("failed to write skill file: %v", err)
+	}
+
+	output, err := runToolWithError("skills", "validate", "-C", dirs.tmpDir)
+	if err == nil {
+		t.Fatalf("expected skills validate to fail, got output:\n%s", output)
+	}
+
+	if !strings.Contains(output, "SKILL.md:2: skill 'name' field must match the skill directory name") {
+		t.Errorf("expected name mismatch problem with line number, got:\n%s", output)
+	}
+}
+
+func TestSkillsPackAndInstall(t *testing.T) {
+	t.Parallel()
+	srcDir := t.TempDir()
+	workDir := t.TempDir()
+
+	skillDir := filepath.Join(srcDir, "pdf-processing")
+	if err := os.MkdirAll(skillDir, 0o750); err != nil {
+		t.Fatalf("failed to create skill dir: %v", err)
+	}
+
+	skill := "---\nname: pdf-processing\ndescription: Work with PDF files\n---\n# PDF\n"
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte(skill), 0o600); err != nil {
+		t.Fatalf("failed to write skill file: %v", err)
+	}
+
+	runTool(t, "skills", "pack", "-C", srcDir, "pdf-processing")
+
+	bundle := filepath.Join(srcDir, "pdf-processing.tar.gz")
+	runTool(t, "skills", "install", "-C", workDir, bundle)
+
+	installed, err := os.ReadFile(filepath.Join(workDir, ".agents", "skills", "pdf-processing", "SKILL.md"))
+	if err != nil {
+		t.Fatalf("skill was not installed: %v", err)
+	}
+
+	if string(installed) != skill {
+		t.Errorf("installed SKILL.md = %q, want %q", installed, skill)
+	}
+
+	lock, err := os.ReadFile(filepath.Join(workDir, ".agents", "skills-lock.json"))
+	if err != nil {
+		t.Fatalf("lock file was not written: %v", err)
+	}
+
+	if !strings.Contains(string(lock), bundle) {
+		t.Errorf("lock file does not record the source %s:\n%s", bundle, lock)
+	}
+
+	// The installed skill is discovered like any other.
+	if err := os.MkdirAll(filepath.Join(workDir, ".agents", "tasks"), 0o750); err != nil {
+		t.Fatalf("failed to create tasks dir: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(workDir, ".agents", "tasks", "t.md"), []byte("Task"), 0o600); err != nil {
+		t.Fatalf("failed to write task file: %v", err)
+	}
+
+	if output := runTool(t, "-C", workDir, "t"); !strings.Contains(output, "<name>pdf-processing</name>") {
+		t.Errorf("installed skill not in output:\n%s", output)
+	}
+}
+
+func TestConfigDefinedAgent(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+	tmpHome := t.TempDir()
+
+	agentsConfig := `agents:
+  inhouse:
+    rules_paths: [.inhouse/rules]
+    user_rule_path: .inhouse/RULES.md
+`
+	// Only the user-level config can say where -w writes in the home directory.
+	if err := os.MkdirAll(filepath.Join(tmpHome, ".agents"), 0o750); err != nil {
+		t.Fatalf("failed to create agents config dir: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmpHome, ".agents", "agents.yaml"), []byte(agentsConfig), 0o600); err != nil {
+		t.Fatalf("failed to write agents config: %v", err)
+	}
+
+	inhouseRules := filepath.Join(dirs.tmpDir, ".inhouse", "rules")
+	if err := os.MkdirAll(inhouseRules, 0o750); err != nil {
+		t.Fatalf("failed to create rules dir: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(inhouseRules, "house.md"), []byte("# House Rule\n"), 0o600); err != nil {
+		t.Fatalf("failed to write rule file: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dirs.tasksDir, "task.md"), []byte("# Task\n"), 0o600); err != nil {
+		t.Fatalf("failed to write task file: %v", err)
+	}
+
+	gomodcache := os.Getenv("GOMODCACHE")
+	if gomodcache == "" {
+		gomodcache = filepath.Join(os.Getenv("HOME"), "go", "pkg", "mod")
+	}
+
+	output := runToolWithEnv(t, []string{"HOME=" + tmpHome, "GOMODCACHE=" + gomodcache},
+		"-C", dirs.tmpDir, "-a", "inhouse", "-w", "task")
+
+	if !strings.Contains(output, "# Task") {
+		t.Errorf("task not in output:\n%s", output)
+	}
+
+	rules, err := os.ReadFile(filepath.Join(tmpHome, ".inhouse", "RULES.md"))
+	if err != nil {
+		t.Fatalf("rules were not written to the agent's user rule path: %v", err)
+	}
+
+	if !strings.Contains(string(rules), "# House Rule") {
+		t.Errorf("rules file does not contain the agent's rule:\n%s", rules)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+
+	taskContent := "---\nselectors:\n  team: platform\n---\n# Task\n"
+	if err := os.WriteFile(filepath.Join(dirs.tasksDir, "task.md"), []byte(taskContent), 0o600); err != nil {
+		t.Fatalf("failed to write task file: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dirs.rulesDir, "data.md"), []byte("---\nteam: data\n---\n# Data Rule\n"),
+		0o600); err != nil {
+		t.Fatalf("failed to write rule file: %v", err)
+	}
+
+	output := runTool(t, "-C", dirs.tmpDir, "--explain", "task")
+
+	for _, want := range []string{"Task: task", "  - " + filepath.Join(dirs.rulesDir, "data.md"),
+		"selectors did not match: team=data (expected team=platform)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	if strings.Contains(output, "# Task") {
+		t.Errorf("expected the report instead of the prompt:\n%s", output)
+	}
+
+	output = runTool(t, "-C", dirs.tmpDir, "--explain=json", "task")
+	if !strings.Contains(output, `"reason": "selectors did not match: team=data (expected team=platform)"`) {
+		t.Errorf("expected the decision as JSON in output:\n%s", output)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+	home := t.TempDir()
+
+	git := func(args ...string) {
+		t.Helper()
+
+		// #nosec G204 -- integration test runs git with controlled args
+		cmd := exec.CommandContext(t.Context(), "git", append([]string{"-C", dirs.tmpDir,
+			"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	writeFile := func(path, content string) {
+		t.Helper()
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	writeFile(filepath.Join(dirs.tasksDir, "fix-bug.md"), "Fix the bug.\n")
+	writeFile(filepath.Join(dirs.tasksDir, "review.md"), "Review the code.\n")
+	writeFile(filepath.Join(dirs.rulesDir, "style.md"), "---\ntask_name: fix-bug\n---\nUse tabs.\n")
+
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	writeFile(filepath.Join(dirs.rulesDir, "style.md"), "---\ntask_name: fix-bug\n---\nUse spaces.\n")
+
+	gomodcache := os.Getenv("GOMODCACHE")
+	if gomodcache == "" {
+		gomodcache = filepath.Join(os.Getenv("HOME"), "go", "pkg", "mod")
+	}
+
+	env := []string{"HOME=" + home, "GOMODCACHE=" + gomodcache}
+
+	output := runToolWithEnv(t, env, "diff", "-C", dirs.tmpDir, "--old-ref", "HEAD")
+	for _, want := range []string{"=== fix-bug\n", "Rules changed: style\n", "--- fix-bug (HEAD)\n", "-Use tabs.\n", "+Use spaces.\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	// Tasks that no rule change affects are left out.
+	if strings.Contains(output, "=== review") {
+		t.Errorf("expected the unchanged review task to be left out:\n%s", output)
+	}
+
+	output, err := runToolWithErrorAndEnv(env, "diff", "-C", dirs.tmpDir, "--exit-code",
+		"--old-ref", "HEAD", "--new-ref", "HEAD", "fix-bug")
+	if err != nil {
+		t.Errorf("expected no difference between the same ref, got %v:\n%s", err, output)
+	}
+
+	output, err = runToolWithErrorAndEnv(env, "diff", "-C", dirs.tmpDir, "--exit-code", "--old-ref", "HEAD", "fix-bug")
+	if err == nil {
+		t.Errorf("expected --exit-code to fail when the task differs:\n%s", output)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
+)
+
+var (
+	errNoTasks        = errors.New("no tasks found")
+	errNoTaskChosen   = errors.New("no task chosen")
+	errPromptCanceled = errors.New("canceled")
+)
+
+// maxPickerMatches is how many matching tasks the picker lists at once.
+const maxPickerMatches = 20
+
+// isTerminal returns whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// prompter asks questions on out and reads the answers, one per line, from in.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask writes question and returns the answer, without surrounding whitespace.
+func (p *prompter) ask(question string) (string, error) {
+	if _, err := fmt.Fprint(p.out, question); err != nil {
+		return "", fmt.Errorf("writing prompt: %w", err)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if errors.Is(err, io.EOF) && line != "" {
+		err = nil
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("reading answer: %w", err)
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+// pickTask asks for the task, searching the tasks of cc, and then for each parameter the
+// task and its commands reference, pre-filled with the value given with -p. It shows the
+// estimated tokens and asks to continue. It sets cfg.taskName and returns the parameters.
+func pickTask(
+	ctx context.Context, cc *codingcontext.Context, cfg *cliConfig, in io.Reader, out io.Writer,
+) (taskparser.Params, error) {
+	p := &prompter{in: bufio.NewReader(in), out: out}
+
+	tasks, err := cc.ListTasks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+
+	if len(tasks) == 0 {
+		return nil, errNoTasks
+	}
+
+	task, err := p.chooseTask(tasks)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.taskName = task.Name
+
+	// Linting with no parameters leaves the references that the task's own slash commands
+	// do not set, without running bootstrap scripts or shell commands.
+	linted, err := cc.With(codingcontext.WithParams(taskparser.Params{})).Lint(ctx, task.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	params := make(taskparser.Params)
+	for key, values := range cfg.params {
+		params[key] = slices.Clone(values)
+	}
+
+	for _, name := range taskparser.ParamRefs(linted.Task.Content) {
+		value, ok := params.Lookup(name)
+
+		question := name + ": "
+		if ok {
+			question = fmt.Sprintf("%s [%s]: ", name, value)
+		}
+
+		answer, err := p.ask(question)
+		if err != nil {
+			return nil, err
+		}
+
+		if answer != "" {
+			params[strings.ToLower(name)] = []string{answer}
+		}
+	}
+
+	linted, err = cc.With(codingcontext.WithParams(params)).Lint(ctx, task.Name)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	answer, err := p.ask(fmt.Sprintf("Estimated tokens: %d (before bootstrap scripts). Continue? [Y/n] ",
+		linted.Tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.ToLower(answer), "n") {
+		return nil, errPromptCanceled
+	}
+
+	return params, nil
+}
+
+// chooseTask lists the tasks matching a search and asks for the number of one of them,
+// until a task is chosen. An answer that is not a listed number is a new search.
+func (p *prompter) chooseTask(tasks []codingcontext.DiscoveredTask) (codingcontext.DiscoveredTask, error) {
+	query, err := p.ask("Search tasks (Enter lists all): ")
+	if err != nil {
+		return codingcontext.DiscoveredTask{}, err
+	}
+
+	for {
+		matches := matchTasks(tasks, query)
+		if len(matches) == 0 {
+			if _, err := fmt.Fprintf(p.out, "No tasks match %q.\n", query); err != nil {
+				return codingcontext.DiscoveredTask{}, fmt.Errorf("writing prompt: %w", err)
+			}
+		}
+
+		var list strings.Builder
+
+		for i, task := range matches[:min(len(matches), maxPickerMatches)] {
+			fmt.Fprintf(&list, "%3d) %s", i+1, task.Name)
+
+			if task.Description != "" {
+				fmt.Fprintf(&list, " - %s", task.Description)
+			}
+
+			list.WriteString("\n")
+		}
+
+		if len(matches) > maxPickerMatches {
+			fmt.Fprintf(&list, "     ... and %d more; refine the search\n", len(matches)-maxPickerMatches)
+		}
+
+		if _, err := io.WriteString(p.out, list.String()); err != nil {
+			return codingcontext.DiscoveredTask{}, fmt.Errorf("writing prompt: %w", err)
+		}
+
+		answer, err := p.ask("Task number, or a new search: ")
+		if errors.Is(err, io.EOF) {
+			return codingcontext.DiscoveredTask{}, errNoTaskChosen
+		} else if err != nil {
+			return codingcontext.DiscoveredTask{}, err
+		}
+
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= min(len(matches), maxPickerMatches) {
+			return matches[n-1], nil
+		}
+
+		query = answer
+	}
+}
+
+// matchTasks returns the tasks that fuzzily match query, best first. A task matches if the
+// characters of query appear in order in its name or description; name matches rank above
+// description matches. An empty query matches every task, in order.
+func matchTasks(tasks []codingcontext.DiscoveredTask, query string) []codingcontext.DiscoveredTask {
+	type match struct {
+		task  codingcontext.DiscoveredTask
+		score int
+	}
+
+	var matches []match
+
+	for _, task := range tasks {
+		score, ok := fuzzyScore(query, task.Name)
+		if ok {
+			// Rank every name match above every description match.
+			score += len(query) * 4
+		} else {
+			score, ok = fuzzyScore(query, task.Description)
+		}
+
+		if ok {
+			matches = append(matches, match{task: task, score: score})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+
+	result := make([]codingcontext.DiscoveredTask, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.task)
+	}
+
+	return result
+}
+
+// fuzzyScore returns whether the characters of query appear in order in text, ignoring
+// case, and a score that is higher when they are consecutive or start words.
+func fuzzyScore(query, text string) (int, bool) {
+	query, text = strings.ToLower(query), strings.ToLower(text)
+	textRunes := []rune(text)
+
+	score, pos := 0, 0
+
+	for _, r := range query {
+		if r == ' ' {
+			continue
+		}
+
+		found := slices.Index(textRunes[pos:], r)
+		if found < 0 {
+			return 0, false
+		}
+
+		at := pos + found
+
+		score++
+
+		if at > 0 && found == 0 && pos > 0 {
+			score++ // Follows the previous match
+		}
+
+		if at == 0 || strings.ContainsRune(" -_/.", textRunes[at-1]) {
+			score++ // Starts a word
+		}
+
+		pos = at + 1
+	}
+
+	return score, true
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
+)
+
+func TestPickTask(t *testing.T) {
+	t.Parallel()
+	dirs := setupTestDirs(t)
+	commandsDir := filepath.Join(dirs.tmpDir, ".agents", "commands")
+
+	if err := os.MkdirAll(commandsDir, 0o750); err != nil {
+		t.Fatalf("failed to create commands dir: %v", err)
+	}
+
+	files := map[string]string{
+		filepath.Join(dirs.tasksDir, "deploy.md"): "---\ndescription: Deploy a service\n---\n" +
+			"Deploy ${service} to ${env}.\n/notify channel=ops\n",
+		filepath.Join(dirs.tasksDir, "fix-bug.md"): "---\ndescription: Fix a bug\n---\nFix ${issue_key}.\n",
+		filepath.Join(commandsDir, "notify.md"):    "Notify ${channel} as ${user}.\n",
+		filepath.Join(dirs.rulesDir, "style.md"):   "Use tabs.\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	cc := codingcontext.New(
+		codingcontext.WithSearchPaths(dirs.tmpDir),
+		codingcontext.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	cfg := &cliConfig{params: taskparser.Params{"service": {"api"}}}
+
+	// Search for "dep", choose the only match, keep the pre-filled service,
+	// and answer the env and user; the channel is set by the task's slash command.
+	in := strings.NewReader("dep\n1\n\nproduction\nalice\n\n")
+
+	var out bytes.Buffer
+
+	params, err := pickTask(t.Context(), cc, cfg, in, &out)
+	if err != nil {
+		t.Fatalf("pickTask() error: %v\n%s", err, out.String())
+	}
+
+	if cfg.taskName != "deploy" {
+		t.Errorf("task = %q, want deploy", cfg.taskName)
+	}
+
+	for key, want := range map[string]string{"service": "api", "env": "production", "user": "alice"} {
+		if got := params.Value(key); got != want {
+			t.Errorf("param %s = %q, want %q", key, got, want)
+		}
+	}
+
+	for _, want := range []string{"1) deploy - Deploy a service", "service [api]: ", "env: ", "user: ", "Estimated tokens: "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	if strings.Contains(out.String(), "fix-bug") || strings.Contains(out.String(), "channel") {
+		t.Errorf("output lists a task that does not match or asks for a parameter that is set:\n%s", out.String())
+	}
+
+	// Declining to continue cancels.
+	in = strings.NewReader("fix\n1\nBUG-1\nn\n")
+	if _, err := pickTask(t.Context(), cc, &cliConfig{}, in, io.Discard); err == nil {
+		t.Error("pickTask() did not fail when declined")
+	}
+}
+
+func TestMatchTasks(t *testing.T) {
+	t.Parallel()
+
+	tasks := []codingcontext.DiscoveredTask{
+		{Name: "refactor", Description: "Restructure code for the bug tracker"},
+		{Name: "fix-bug", Description: "Fix a bug"},
+		{Name: "deploy", Description: "Deploy a service"},
+	}
+
+	names := func(matches []codingcontext.DiscoveredTask) string {
+		var b strings.Builder
+		for _, m := range matches {
+			b.WriteString(m.Name + " ")
+		}
+
+		return strings.TrimSpace(b.String())
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: "refactor fix-bug deploy"},
+		{query: "fb", want: "fix-bug refactor"},
+		{query: "BUG", want: "fix-bug refactor"},
+		{query: "service", want: "deploy"},
+		{query: "zzz", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := names(matchTasks(tasks, tt.query)); got != tt.want {
+			t.Errorf("matchTasks(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
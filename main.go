@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,8 +10,11 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
 	"github.com/kitproj/coding-context-cli/pkg/codingcontext/selectors"
@@ -18,25 +22,52 @@ import (
 )
 
 var (
-	errInvalidUsage          = errors.New("invalid usage: expected one task name argument and optional user-prompt")
-	errWriteRulesNoAgent     = errors.New("-w flag requires an agent to be specified (via task 'agent' field or -a flag)")
-	errNoUserRulePath        = errors.New("no user rule path available for agent")
-	errRulesPathEscapesHome  = errors.New("rules path escapes home directory")
-	errAgentFlagsMutExcl     = errors.New("-a and -A flags are mutually exclusive")
+	errInvalidUsage         = errors.New("invalid usage: expected one task name argument and optional user-prompt")
+	errWriteRulesNoAgent    = errors.New("-w flag requires an agent to be specified (via task 'agent' field or -a flag)")
+	errNoUserRulePath       = errors.New("no user rule path available for agent")
+	errNoProjectRulePath    = errors.New("no project rule path available for agent")
+	errRulesPathEscapesHome = errors.New("rules path escapes home directory")
+	errRulesPathEscapesWork = errors.New("rules path escapes working directory")
+	errInvalidScope         = errors.New("invalid --scope: expected project or user")
+	errCheckWithoutWrite    = errors.New("--check requires -w")
+	errAgentFlagsMutExcl    = errors.New("-a and -A flags are mutually exclusive")
+	errWriteRulesAgent      = errors.New("task agent conflicts with -w agent")
+	errInvalidWriteRules    = errors.New("invalid -w: expected true, false, all, or a comma-separated list of agents")
+	errInvalidExplain       = errors.New("invalid --explain: expected text or json")
+	errExplainWithWrite     = errors.New("--explain cannot be used with -w")
+	errWatchWithCheck       = errors.New("--watch cannot be used with --check")
 )
 
 type cliConfig struct {
 	workDir            string
 	resume             bool
 	skipBootstrap      bool
-	writeRules         bool
+	forceBootstrap     bool
+	bootstrapJobs      int
+	writeRules         writeRulesFlag
+	writeAgents        []codingcontext.Agent
+	scope              writeScope
+	checkRules         bool
+	backupRules        bool
+	explain            explainFormat
+	watch              bool
+	interactive        bool
+	agentName          string
+	lenientAgentName   string
 	agent              codingcontext.Agent
 	lenientAgent       codingcontext.Agent
+	agentSkills        bool
 	params             taskparser.Params
 	includes           selectors.Selectors
 	searchPaths        []string
 	lenientSearchPaths []string
+	inlineSkills       []string
+	skillsListing      codingcontext.SkillsListing
+	skillsTemplate     string
+	omitSkillsFor      []string
 	manifestURL        string
+	sandbox            codingcontext.BootstrapSandbox
+	sources            []codingcontext.Source
 	taskName           string
 	userPrompt         string
 }
@@ -56,6 +87,22 @@ func main() {
 }
 
 func run(ctx context.Context, logger *slog.Logger) error {
+	if len(os.Args) > 1 && os.Args[1] == "skills" {
+		return runSkills(ctx, os.Args[2:], os.Stdout, logger)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		return runDiff(ctx, os.Args[2:], os.Stdout, logger)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		return runServe(ctx, os.Args[2:], logger)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "new" {
+		return runNew(ctx, os.Args[2:], os.Stdout, logger)
+	}
+
 	cfg, err := parseFlags(logger)
 	if err != nil {
 		return err
@@ -69,7 +116,7 @@ func run(ctx context.Context, logger *slog.Logger) error {
 	cfg.searchPaths = append(cfg.searchPaths, "file://"+cfg.workDir)
 	cfg.searchPaths = append(cfg.searchPaths, "file://"+homeDir)
 
-	cc := codingcontext.New(
+	opts := []codingcontext.Option{
 		codingcontext.WithParams(cfg.params),
 		codingcontext.WithSelectors(cfg.includes),
 		codingcontext.WithSearchPaths(cfg.searchPaths...),
@@ -77,11 +124,67 @@ func run(ctx context.Context, logger *slog.Logger) error {
 		codingcontext.WithLogger(logger),
 		codingcontext.WithResume(cfg.resume),
 		codingcontext.WithBootstrap(!cfg.skipBootstrap),
+		codingcontext.WithBootstrapSandbox(cfg.sandbox),
+		codingcontext.WithForceBootstrap(cfg.forceBootstrap),
+		codingcontext.WithBootstrapConcurrency(cfg.bootstrapJobs),
 		codingcontext.WithManifestURL(cfg.manifestURL),
 		codingcontext.WithUserPrompt(cfg.userPrompt),
+		codingcontext.WithInlineSkills(cfg.inlineSkills...),
+		codingcontext.WithSkillsListing(cfg.skillsListing),
+		codingcontext.WithAgentSkills(cfg.agentSkills),
+		codingcontext.WithSources(cfg.sources...),
+		// The agent reads its own rules, so they are not copied into its user rules file.
+		codingcontext.WithExcludeAgentRules(cfg.writeRules.enabled),
+	}
+
+	if cfg.interactive {
+		// Only warnings are logged while asking questions, so that they are not lost in the log.
+		quiet := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+		cc := codingcontext.New(append(slices.Clone(opts),
+			codingcontext.WithAgent(cfg.agent),
+			codingcontext.WithLenientAgent(cfg.lenientAgent),
+			codingcontext.WithLogger(quiet),
+			codingcontext.WithSession(true),
+		)...)
+
+		params, err := pickTask(ctx, cc, cfg, os.Stdin, os.Stderr)
+		closeContext(cc, logger)
+
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, codingcontext.WithParams(params))
+	}
+
+	if cfg.watch {
+		paths := codingcontext.New(opts...).WatchPaths()
+
+		return watch(ctx, paths, watchInterval, func() error {
+			return generate(ctx, cfg, opts, homeDir, logger)
+		}, logger)
+	}
+
+	return generate(ctx, cfg, opts, homeDir, logger)
+}
+
+// generate assembles the context and writes the rules files or prints the output.
+func generate(
+	ctx context.Context, cfg *cliConfig, opts []codingcontext.Option, homeDir string, logger *slog.Logger,
+) error {
+	if len(cfg.writeAgents) > 0 {
+		return writeRulesForAgents(ctx, cfg, opts, homeDir, logger)
+	}
+
+	cc := codingcontext.New(append(opts,
 		codingcontext.WithAgent(cfg.agent),
 		codingcontext.WithLenientAgent(cfg.lenientAgent),
-	)
+	)...)
+	defer closeContext(cc, logger)
+
+	if cfg.explain != "" {
+		return explain(ctx, cc, cfg)
+	}
 
 	result, err := cc.Run(ctx, cfg.taskName)
 	if err != nil {
@@ -95,6 +198,10 @@ func run(ctx context.Context, logger *slog.Logger) error {
 		return err
 	}
 
+	if cfg.checkRules {
+		return nil
+	}
+
 	if _, err := os.Stdout.Write(append([]byte(outputContent), '\n')); err != nil {
 		return fmt.Errorf("writing output: %w", err)
 	}
@@ -105,11 +212,11 @@ func run(ctx context.Context, logger *slog.Logger) error {
 func buildOutputContent(
 	result *codingcontext.Result, cfg *cliConfig, homeDir string, logger *slog.Logger,
 ) (string, error) {
-	if !cfg.writeRules {
+	if !cfg.writeRules.enabled {
 		return result.Prompt, nil
 	}
 
-	if err := writeRulesToAgent(result, homeDir, cfg.skipBootstrap, logger); err != nil {
+	if err := writeRulesToAgent(result, cfg, homeDir, logger); err != nil {
 		return "", err
 	}
 
@@ -120,6 +227,7 @@ func parseFlags(logger *slog.Logger) (*cliConfig, error) {
 	cfg := &cliConfig{
 		params:   make(taskparser.Params),
 		includes: make(selectors.Selectors),
+		scope:    scopeUser,
 	}
 
 	flag.StringVar(&cfg.workDir, "C", ".", "Change to directory before doing anything.")
@@ -127,15 +235,37 @@ func parseFlags(logger *slog.Logger) (*cliConfig, error) {
 		"Resume mode: set 'resume=true' selector to filter tasks by their frontmatter resume field.")
 	flag.BoolVar(&cfg.skipBootstrap, "skip-bootstrap", false,
 		"Skip bootstrap: skip discovering rules, skills, and running bootstrap scripts.")
-	flag.BoolVar(&cfg.writeRules, "w", false,
-		"Write rules to the agent's user rules path and only print the prompt to stdout. "+
-			"Requires agent (via task 'agent' field or -a flag).")
-	flag.Var(&cfg.agent, "a",
+	flag.BoolVar(&cfg.forceBootstrap, "force-bootstrap", false,
+		"Run bootstrap scripts even if run_once or creates in their frontmatter would skip them.")
+	flag.IntVar(&cfg.bootstrapJobs, "bootstrap-jobs", 0,
+		"Maximum number of bootstrap scripts to run in parallel. "+
+			"Zero runs them one at a time, or up to the number of CPUs if a rule uses depends_on.")
+	flag.Var(&cfg.writeRules, "w",
+		"Write rules to the agent's user rules path and only print the task to stdout. "+
+			"Requires agent (via task 'agent' field or -a flag). Use -w=cursor,claude to write for each listed agent, "+
+			"or -w=all to write for every agent.")
+	flag.Var(&cfg.scope, "scope",
+		"Where -w writes rules: user (the agent's rules file in the home directory, shared by every project) "+
+			"or project (the agent's repo-local rules file in the working directory, e.g. CLAUDE.local.md).")
+	flag.BoolVar(&cfg.checkRules, "check", false,
+		"With -w, report whether each rules file is out of date instead of writing it, and fail if any is.")
+	flag.BoolVar(&cfg.backupRules, "backup", false,
+		"With -w, keep the previous version of each rules file it changes as <file>.bak.")
+	flag.Var(&cfg.explain, "explain",
+		"Instead of the prompt, print a report of every task, command, rule, and skill file considered: where it "+
+			"was found, whether it was included, why, and its tokens. Use --explain=json for JSON.")
+	flag.BoolVar(&cfg.watch, "watch", false,
+		"Keep running, and assemble the context again when the local rules, tasks, commands, skills, or manifest "+
+			"change: rewrite the rules files with -w, or print the output again.")
+	flag.StringVar(&cfg.agentName, "a", "",
 		"Target agent to use. Required when using -w to write rules to the agent's user rules path. "+
-			"Supported agents: cursor, opencode, copilot, claude, gemini, augment, windsurf, codex.")
-	flag.Var(&cfg.lenientAgent, "A",
+			"Supported agents: "+supportedAgents()+".")
+	flag.StringVar(&cfg.lenientAgentName, "A", "",
 		"Target agent with lenient error handling (errors are warnings, missing skill names inferred from directory). "+
-			"Mutually exclusive with -a. Supported agents: cursor, opencode, copilot, claude, gemini, augment, windsurf, codex.")
+			"Mutually exclusive with -a. Supported agents: "+supportedAgents()+".")
+	flag.BoolVar(&cfg.agentSkills, "include-agent-skills", false,
+		"Include skills from the target agent's own skills path (e.g. .claude/skills for claude), "+
+			"which are excluded by default because the agent loads them itself.")
 	flag.Var(&cfg.params, "p", "Parameter to substitute in the prompt. Can be specified multiple times as key=value.")
 	flag.Var(&cfg.includes, "s", "Include rules with matching frontmatter. Can be specified multiple times as key=value.")
 	flag.Func("d",
@@ -152,10 +282,52 @@ func parseFlags(logger *slog.Logger) (*cliConfig, error) {
 		func(s string) error {
 			cfg.lenientSearchPaths = append(cfg.lenientSearchPaths, s)
 
+			return nil
+		})
+	flag.Func("skill",
+		"Inline the full content of the named skill, including its references/ and scripts/ files, into the prompt. "+
+			"Can be specified multiple times.",
+		func(s string) error {
+			cfg.inlineSkills = append(cfg.inlineSkills, s)
+
+			return nil
+		})
+	flag.Var(&cfg.skillsListing.Format, "skills-format",
+		"Format of the list of available skills in the prompt: xml (default), markdown, or json.")
+	flag.StringVar(&cfg.skillsTemplate, "skills-template", "",
+		"File containing a Go text/template that renders the list of available skills instead of -skills-format.")
+	flag.Func("skills-preamble",
+		"Text written before the list of available skills, replacing the default. An empty value removes it.",
+		func(s string) error {
+			cfg.skillsListing.Preamble = &s
+
+			return nil
+		})
+	flag.Func("omit-skills-for",
+		"Leave the list of available skills out of the prompt when targeting this agent, e.g. because the agent "+
+			"discovers skills itself. Can be specified multiple times.",
+		func(s string) error {
+			cfg.omitSkillsFor = append(cfg.omitSkillsFor, s)
+
 			return nil
 		})
 	flag.StringVar(&cfg.manifestURL, "m", "",
 		"Go Getter URL to a manifest file containing search paths (one per line). Every line is included as-is.")
+	flag.BoolVar(&cfg.sandbox.Enabled, "sandbox", false,
+		"Run bootstrap scripts in a sandbox (Linux only): files outside the working directory, a scratch directory, "+
+			"and system directories cannot be read or written, the environment is scrubbed, and network access is disabled.")
+	flag.BoolVar(&cfg.sandbox.AllowNetwork, "sandbox-network", false,
+		"Allow network access for sandboxed bootstrap scripts.")
+	flag.Func("sandbox-env",
+		"Environment variable to pass through to sandboxed bootstrap scripts. Can be specified multiple times. "+
+			"Defaults to "+strings.Join(codingcontext.DefaultSandboxEnv, ", ")+".",
+		func(s string) error {
+			cfg.sandbox.EnvAllowlist = append(cfg.sandbox.EnvAllowlist, s)
+
+			return nil
+		})
+	flag.DurationVar(&cfg.sandbox.Timeout, "bootstrap-timeout", time.Duration(0),
+		"Kill bootstrap scripts that run longer than this duration (e.g. 30s, 5m). Zero means no timeout.")
 
 	setupUsage(logger)
 	flag.Parse()
@@ -167,6 +339,13 @@ func setupUsage(logger *slog.Logger) {
 	flag.Usage = func() {
 		logger.Info("Usage:")
 		logger.Info("  coding-context [options] <task-name> [user-prompt]")
+		logger.Info("  coding-context [options]  (at a terminal: choose the task and parameters interactively)")
+		logger.Info("  coding-context skills validate [-C dir] [path...]")
+		logger.Info("  coding-context skills install [-C dir] <url>...")
+		logger.Info("  coding-context skills pack [-C dir] [-o file] <skill-dir>")
+		logger.Info("  coding-context diff [-C dir] [--old-ref ref] [--new-ref ref] [options] [task-name...]")
+		logger.Info("  coding-context serve [-C dir] [--addr addr] [--timeout duration] [--refresh duration] [options]")
+		logger.Info("  coding-context new [-C dir] [--namespace name] [options] rule|task|command|skill <name>")
 		logger.Info("")
 		logger.Info("The task-name is the name of a task file to look up in task search paths (.agents/tasks).")
 		logger.Info("The user-prompt is optional text to append to the task. It can contain slash commands")
@@ -181,12 +360,55 @@ func setupUsage(logger *slog.Logger) {
 }
 
 func parseFlagArgs(cfg *cliConfig) (*cliConfig, error) {
-	if cfg.agent.IsSet() && cfg.lenientAgent.IsSet() {
+	if cfg.agentName != "" && cfg.lenientAgentName != "" {
 		return nil, errAgentFlagsMutExcl
 	}
 
+	if cfg.checkRules && !cfg.writeRules.enabled {
+		return nil, errCheckWithoutWrite
+	}
+
+	if cfg.explain != "" && cfg.writeRules.enabled {
+		return nil, errExplainWithWrite
+	}
+
+	if cfg.watch && cfg.checkRules {
+		return nil, errWatchWithCheck
+	}
+
+	if err := parseAgents(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := parseSources(cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.sandbox.WorkDir = cfg.workDir
+
+	if cfg.skillsTemplate != "" {
+		path := cfg.skillsTemplate
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cfg.workDir, path)
+		}
+
+		tmpl, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read skills template: %w", err)
+		}
+
+		cfg.skillsListing.Template = string(tmpl)
+	}
+
 	args := flag.Args()
 
+	// Without a task, a person at a terminal is asked for it.
+	if len(args) == 0 && isTerminal(os.Stdin) && isTerminal(os.Stderr) {
+		cfg.interactive = true
+
+		return cfg, nil
+	}
+
 	const maxArgs = 2
 
 	if len(args) < 1 || len(args) > maxArgs {
@@ -204,57 +426,433 @@ func parseFlagArgs(cfg *cliConfig) (*cliConfig, error) {
 	return cfg, nil
 }
 
-func writeRulesToAgent(result *codingcontext.Result, homeDir string, skipBootstrap bool, logger *slog.Logger) error {
-	if !result.Agent.IsSet() {
-		return errWriteRulesNoAgent
+// supportedAgents lists the built-in agents for flag help.
+func supportedAgents() string {
+	agents := codingcontext.Agents()
+
+	names := make([]string, 0, len(agents))
+	for _, agent := range agents {
+		names = append(names, agent.String())
+	}
+
+	return strings.Join(names, ", ") + ", and agents declared in " + codingcontext.AgentConfigFile
+}
+
+// loadAgentConfigs loads the user-level agent config and then the repo-level one, which
+// can only add agents.
+func loadAgentConfigs(homeDir, workDir string) error {
+	if err := codingcontext.LoadAgentConfig(filepath.Join(homeDir, codingcontext.AgentConfigFile)); err != nil {
+		return fmt.Errorf("failed to load agents: %w", err)
+	}
+
+	if err := codingcontext.LoadProjectAgentConfig(filepath.Join(workDir, codingcontext.AgentConfigFile)); err != nil {
+		return fmt.Errorf("failed to load agents: %w", err)
+	}
+
+	return nil
+}
+
+// parseAgents loads the user-level and repo-level agent configs, and then parses the agent
+// flags, which may name those agents.
+func parseAgents(cfg *cliConfig) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	if err := loadAgentConfigs(homeDir, cfg.workDir); err != nil {
+		return err
+	}
+
+	if cfg.agentName != "" {
+		if err := cfg.agent.Set(cfg.agentName); err != nil {
+			return fmt.Errorf("invalid -a: %w", err)
+		}
+	}
+
+	if cfg.lenientAgentName != "" {
+		if err := cfg.lenientAgent.Set(cfg.lenientAgentName); err != nil {
+			return fmt.Errorf("invalid -A: %w", err)
+		}
+	}
+
+	writeAgents, err := parseWriteAgents(cfg.writeRules, cfg.scope)
+	if err != nil {
+		return err
 	}
 
-	if skipBootstrap {
+	cfg.writeAgents = writeAgents
+
+	for _, name := range cfg.omitSkillsFor {
+		agent, err := codingcontext.ParseAgent(name)
+		if err != nil {
+			return fmt.Errorf("invalid -omit-skills-for: %w", err)
+		}
+
+		cfg.skillsListing.OmitForAgents = append(cfg.skillsListing.OmitForAgents, agent)
+	}
+
+	return nil
+}
+
+// parseSources loads the user-level and then the repo-level source config, so that repo
+// sources replace user sources of the same name, and then adds the sources found on PATH
+// that are not declared.
+func parseSources(cfg *cliConfig) error {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	var sources []codingcontext.Source
+
+	for _, dir := range []string{homeDir, cfg.workDir} {
+		declared, err := codingcontext.LoadSourceConfig(filepath.Join(dir, codingcontext.SourceConfigFile))
+		if err != nil {
+			return fmt.Errorf("failed to load sources: %w", err)
+		}
+
+		for _, source := range declared {
+			sources = slices.DeleteFunc(sources, func(s codingcontext.Source) bool { return s.Name == source.Name })
+			sources = append(sources, source)
+		}
+	}
+
+	for _, source := range codingcontext.FindSources(os.Getenv("PATH")) {
+		if !slices.ContainsFunc(sources, func(s codingcontext.Source) bool { return s.Name == source.Name }) {
+			sources = append(sources, source)
+		}
+	}
+
+	cfg.sources = sources
+
+	return nil
+}
+
+// writeRulesFlag is the value of -w: bare -w writes for the target agent, and -w=all or
+// -w=cursor,claude writes for every or each listed agent.
+type writeRulesFlag struct {
+	enabled bool
+	all     bool
+	agents  []string
+}
+
+func (f *writeRulesFlag) String() string {
+	switch {
+	case f.all:
+		return "all"
+	case len(f.agents) > 0:
+		return strings.Join(f.agents, ",")
+	default:
+		return strconv.FormatBool(f.enabled)
+	}
+}
+
+func (f *writeRulesFlag) Set(value string) error {
+	*f = writeRulesFlag{}
+
+	if enabled, err := strconv.ParseBool(value); err == nil {
+		f.enabled = enabled
+
 		return nil
 	}
 
-	relativePath := result.Agent.UserRulePath()
-	if relativePath == "" {
-		return errNoUserRulePath
+	if value == "all" {
+		f.enabled, f.all = true, true
+
+		return nil
 	}
 
-	rulesFile := filepath.Join(homeDir, relativePath)
-	rulesFile = filepath.Clean(rulesFile)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return errInvalidWriteRules
+		}
 
-	homeDirAbs := filepath.Clean(homeDir) + string(filepath.Separator)
-	if !strings.HasPrefix(rulesFile, homeDirAbs) && rulesFile != filepath.Clean(homeDir) {
-		return fmt.Errorf("%w: %s", errRulesPathEscapesHome, rulesFile)
+		f.agents = append(f.agents, name)
 	}
 
-	rulesDir := filepath.Dir(rulesFile)
+	f.enabled = true
 
-	const dirMode = 0o750
+	return nil
+}
+
+// IsBoolFlag lets -w be used without a value.
+func (f *writeRulesFlag) IsBoolFlag() bool {
+	return true
+}
+
+// parseWriteAgents returns the agents that -w=all or -w=<agents> writes for. For all, agents
+// without a rules path for the scope are left out.
+func parseWriteAgents(f writeRulesFlag, scope writeScope) ([]codingcontext.Agent, error) {
+	if f.all {
+		var agents []codingcontext.Agent
+
+		for _, agent := range codingcontext.Agents() {
+			path := agent.UserRulePath()
+			if scope == scopeProject {
+				path = agent.ProjectRulePath()
+			}
+
+			if path != "" {
+				agents = append(agents, agent)
+			}
+		}
 
-	// #nosec G703 -- rulesDir is validated to be within homeDir via rulesFile check above
-	if err := os.MkdirAll(rulesDir, dirMode); err != nil {
-		return fmt.Errorf("failed to create rules directory %s: %w", rulesDir, err)
+		return agents, nil
 	}
 
-	var rulesContent strings.Builder
+	agents := make([]codingcontext.Agent, 0, len(f.agents))
 
-	for i, rule := range result.Rules {
-		if i > 0 {
-			rulesContent.WriteString("\n\n")
+	for _, name := range f.agents {
+		agent, err := codingcontext.ParseAgent(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -w: %w", err)
 		}
 
-		rulesContent.WriteString(strings.TrimSpace(rule.Content))
+		if !slices.Contains(agents, agent) {
+			agents = append(agents, agent)
+		}
 	}
 
-	rulesContent.WriteString("\n")
+	return agents, nil
+}
+
+// closeContext removes the remote search paths downloaded by cc. A failure leaves only
+// temporary files behind, so it is logged rather than returned.
+func closeContext(cc *codingcontext.Context, logger *slog.Logger) {
+	if err := cc.Close(); err != nil {
+		logger.Warn("Failed to remove downloaded directories", "error", err)
+	}
+}
+
+// writeRulesForAgents assembles the context for each agent, so that each agent's own rules
+// are left out of what is written for it, and then writes the rules to each agent's rules
+// path. The runs share one session, so remote search paths are downloaded once. Nothing is
+// written until every context is assembled, because the home directory is a search path and
+// a written rules file would be read for the next agent.
+func writeRulesForAgents(
+	ctx context.Context, cfg *cliConfig, opts []codingcontext.Option, homeDir string, logger *slog.Logger,
+) error {
+	for _, agent := range cfg.writeAgents {
+		if _, err := rulesFilePath(agent, cfg, homeDir); err != nil {
+			return fmt.Errorf("agent %s: %w", agent, err)
+		}
+	}
+
+	cc := codingcontext.New(append(slices.Clone(opts), codingcontext.WithSession(true))...)
+	defer closeContext(cc, logger)
 
-	const fileMode = 0o600
+	results := make([]*codingcontext.Result, 0, len(cfg.writeAgents))
+
+	for _, agent := range cfg.writeAgents {
+		agentOpt := codingcontext.WithAgent(agent)
+		if cfg.lenientAgent.IsSet() {
+			agentOpt = codingcontext.WithLenientAgent(agent)
+		}
+
+		result, err := cc.With(agentOpt).Run(ctx, cfg.taskName)
+		if err != nil {
+			flag.Usage()
+
+			return fmt.Errorf("agent %s: %w", agent, err)
+		}
+
+		if result.Agent != agent {
+			return fmt.Errorf("%w: task selects %s, -w selects %s", errWriteRulesAgent, result.Agent, agent)
+		}
 
-	// #nosec G703 -- rulesFile is validated to be within homeDir via HasPrefix check above
-	if err := os.WriteFile(rulesFile, []byte(rulesContent.String()), fileMode); err != nil {
-		return fmt.Errorf("failed to write rules to %s: %w", rulesFile, err)
+		results = append(results, result)
 	}
 
-	logger.Info("Rules written", "path", rulesFile)
+	var outOfDate []error
+
+	for _, result := range results {
+		err := writeRulesToAgent(result, cfg, homeDir, logger)
+		if errors.Is(err, errRulesOutOfDate) {
+			// Check every agent before failing, so that all stale files are reported.
+			outOfDate = append(outOfDate, err)
+		} else if err != nil {
+			return fmt.Errorf("agent %s: %w", result.Agent, err)
+		}
+	}
+
+	if len(outOfDate) > 0 {
+		return errors.Join(outOfDate...)
+	}
+
+	if cfg.checkRules {
+		return nil
+	}
+
+	if _, err := os.Stdout.Write(append([]byte(results[0].Task.Content), '\n')); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+
+	return nil
+}
+
+// explainFormat is the value of --explain: bare --explain reports as text, and
+// --explain=json as JSON. Empty means no report.
+type explainFormat string
+
+const (
+	explainText explainFormat = "text"
+	explainJSON explainFormat = "json"
+)
+
+func (f *explainFormat) String() string {
+	return string(*f)
+}
+
+func (f *explainFormat) Set(value string) error {
+	switch format := explainFormat(value); format {
+	case "true", explainText:
+		*f = explainText
+	case "false":
+		*f = ""
+	case explainJSON:
+		*f = format
+	default:
+		return fmt.Errorf("%w: %q", errInvalidExplain, value)
+	}
+
+	return nil
+}
+
+// IsBoolFlag lets --explain be used without a value.
+func (f *explainFormat) IsBoolFlag() bool {
+	return true
+}
+
+// explain assembles the context and prints the report of what was included and why,
+// instead of the prompt.
+func explain(ctx context.Context, cc *codingcontext.Context, cfg *cliConfig) error {
+	explanation, err := cc.Explain(ctx, cfg.taskName)
+	if err != nil {
+		flag.Usage()
+
+		return fmt.Errorf("%w", err)
+	}
+
+	if cfg.explain == explainJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(explanation); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+
+		return nil
+	}
+
+	return explanation.WriteText(os.Stdout)
+}
+
+// writeScope is the value of --scope: where -w writes rules.
+type writeScope string
+
+const (
+	scopeUser    writeScope = "user"
+	scopeProject writeScope = "project"
+)
+
+func (s *writeScope) String() string {
+	return string(*s)
+}
+
+func (s *writeScope) Set(value string) error {
+	switch scope := writeScope(value); scope {
+	case scopeUser, scopeProject:
+		*s = scope
+
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", errInvalidScope, value)
+	}
+}
+
+// rulesFilePath returns the file that -w writes the agent's rules to: under the home directory
+// for user scope, or under the working directory for project scope. The file must not escape
+// that directory.
+func rulesFilePath(agent codingcontext.Agent, cfg *cliConfig, homeDir string) (string, error) {
+	root, relativePath := homeDir, agent.UserRulePath()
+	errNoPath, errEscapes := errNoUserRulePath, errRulesPathEscapesHome
+
+	if cfg.scope == scopeProject {
+		workDir, err := filepath.Abs(cfg.workDir)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve working directory: %w", err)
+		}
+
+		root, relativePath = workDir, agent.ProjectRulePath()
+		errNoPath, errEscapes = errNoProjectRulePath, errRulesPathEscapesWork
+	}
+
+	if relativePath == "" {
+		return "", errNoPath
+	}
+
+	rulesFile := filepath.Clean(filepath.Join(root, relativePath))
+
+	rootPrefix := filepath.Clean(root) + string(filepath.Separator)
+	if !strings.HasPrefix(rulesFile, rootPrefix) && rulesFile != filepath.Clean(root) {
+		return "", fmt.Errorf("%w: %s", errEscapes, rulesFile)
+	}
+
+	return rulesFile, nil
+}
+
+func writeRulesToAgent(result *codingcontext.Result, cfg *cliConfig, homeDir string, logger *slog.Logger) error {
+	if !result.Agent.IsSet() {
+		return errWriteRulesNoAgent
+	}
+
+	if cfg.skipBootstrap {
+		return nil
+	}
+
+	rulesFile, err := rulesFilePath(result.Agent, cfg, homeDir)
+	if err != nil {
+		return err
+	}
+
+	rulesDir := filepath.Dir(rulesFile)
+
+	const dirMode = 0o750
+
+	if !cfg.checkRules {
+		// #nosec G703 -- rulesDir is validated to be within its root by rulesFilePath
+		if err := os.MkdirAll(rulesDir, dirMode); err != nil {
+			return fmt.Errorf("failed to create rules directory %s: %w", rulesDir, err)
+		}
+	}
+
+	rules := make([]string, 0, len(result.Rules))
+	for _, rule := range result.Rules {
+		rules = append(rules, strings.TrimSpace(rule.Content))
+	}
+
+	// Cursor only applies .mdc rules without frontmatter when they are mentioned.
+	var header string
+	if filepath.Ext(rulesFile) == ".mdc" {
+		header = "---\nalwaysApply: true\n---\n\n"
+	}
+
+	changed, err := updateRulesFile(rulesFile, rules, header, cfg.checkRules, cfg.backupRules)
+
+	switch {
+	case errors.Is(err, errRulesOutOfDate):
+		logger.Warn("Rules out of date", "agent", result.Agent, "scope", cfg.scope, "path", rulesFile)
+
+		return err
+	case err != nil:
+		return err
+	case changed:
+		logger.Info("Rules written", "agent", result.Agent, "scope", cfg.scope, "path", rulesFile, "rules", len(result.Rules))
+	default:
+		logger.Info("Rules up to date", "agent", result.Agent, "scope", cfg.scope, "path", rulesFile)
+	}
 
 	return nil
 }
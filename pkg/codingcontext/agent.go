@@ -23,22 +23,29 @@ const (
 	AgentAugment  Agent = "augment"
 	AgentWindsurf Agent = "windsurf"
 	AgentCodex    Agent = "codex"
+	AgentCline    Agent = "cline"
+	AgentRoo      Agent = "roo"
+	AgentKiro     Agent = "kiro"
+	AgentZed      Agent = "zed"
+	AgentContinue Agent = "continue"
+	AgentAider    Agent = "aider"
+	AgentJunie    Agent = "junie"
 )
 
-// ParseAgent parses a string into an Agent type.
+// ParseAgent parses a string into an Agent type. Built-in agents and agents loaded
+// with LoadAgentConfig or RegisterAgent are accepted.
 func ParseAgent(s string) (Agent, error) {
 	agent := Agent(s)
 
-	patterns := getAgentPathPatterns()
-
-	// Check if agent exists in the path patterns map
-	if _, exists := patterns[agent]; exists {
+	if _, exists := registry.get(agent); exists {
 		return agent, nil
 	}
 
 	// Build list of supported agents for error message
-	supported := make([]string, 0, len(patterns))
-	for a := range patterns {
+	agents := Agents()
+
+	supported := make([]string, 0, len(agents))
+	for _, a := range agents {
 		supported = append(supported, a.String())
 	}
 
@@ -56,24 +63,24 @@ func (a *Agent) String() string {
 
 // PathPatterns returns the path patterns associated with this agent.
 func (a *Agent) PathPatterns() []string {
-	if a == nil {
-		return nil
-	}
+	config, _ := a.Config()
 
-	return getAgentPathPatterns()[*a]
+	return config.ExcludePatterns
 }
 
 // MatchesPath returns true if the given path matches any of the agent's patterns.
+// A pattern matches whole path elements: CONVENTIONS.md matches docs/CONVENTIONS.md
+// but not docs/MY_CONVENTIONS.md, and .cursor/ matches .cursor/rules/a.md.
 func (a *Agent) MatchesPath(path string) bool {
 	if a == nil {
 		return false
 	}
 
-	normalizedPath := filepath.ToSlash(path)
+	normalizedPath := "/" + strings.Trim(filepath.ToSlash(path), "/") + "/"
 	patterns := a.PathPatterns()
 
 	for _, pattern := range patterns {
-		if strings.Contains(normalizedPath, pattern) {
+		if strings.Contains(normalizedPath, "/"+strings.Trim(pattern, "/")+"/") {
 			return true
 		}
 	}
@@ -81,43 +88,6 @@ func (a *Agent) MatchesPath(path string) bool {
 	return false
 }
 
-// getAgentPathPatterns returns the map of agents to their associated path patterns.
-func getAgentPathPatterns() map[Agent][]string {
-	return map[Agent][]string{
-		AgentCursor: {
-			".cursor/",
-			".cursorrules",
-		},
-		AgentOpenCode: {
-			".opencode/",
-		},
-		AgentCopilot: {
-			".github/copilot-instructions.md",
-			".github/agents/",
-		},
-		AgentClaude: {
-			".claude/",
-			"CLAUDE.md",
-			"CLAUDE.local.md",
-		},
-		AgentGemini: {
-			".gemini/",
-			"GEMINI.md",
-		},
-		AgentAugment: {
-			".augment/",
-		},
-		AgentWindsurf: {
-			".windsurf/",
-			".windsurfrules",
-		},
-		AgentCodex: {
-			".codex/",
-			"AGENTS.md",
-		},
-	}
-}
-
 // Set implements the flag.Value interface for Agent.
 func (a *Agent) Set(value string) error {
 	agent, err := ParseAgent(value)
@@ -148,27 +118,25 @@ func (a *Agent) IsSet() bool {
 	return a != nil && *a != ""
 }
 
-// getAgentUserRulePaths returns the map of each agent to its primary user rules path (relative to home directory).
-func getAgentUserRulePaths() map[Agent]string {
-	return map[Agent]string{
-		AgentCursor:   filepath.Join(".cursor", "rules", "AGENTS.md"),
-		AgentOpenCode: filepath.Join(".opencode", "rules", "AGENTS.md"),
-		AgentCopilot:  filepath.Join(".github", "agents", "AGENTS.md"),
-		AgentClaude:   filepath.Join(".claude", "CLAUDE.md"),
-		AgentGemini:   filepath.Join(".gemini", "GEMINI.md"),
-		AgentAugment:  filepath.Join(".augment", "rules", "AGENTS.md"),
-		AgentWindsurf: filepath.Join(".windsurf", "rules", "AGENTS.md"),
-		AgentCodex:    filepath.Join(".codex", "AGENTS.md"),
-	}
-}
-
 // UserRulePath returns the primary user-level rules path for this agent relative to home directory.
 // Returns an empty string if the agent is not set.
 // The path is relative and should be joined with the home directory.
 func (a *Agent) UserRulePath() string {
-	if a == nil || !a.IsSet() {
+	config, ok := a.Config()
+	if !ok {
+		return ""
+	}
+
+	return filepath.FromSlash(config.UserRulePath)
+}
+
+// ProjectRulePath returns the project-level rules path for this agent relative to the working
+// directory. Returns an empty string if the agent is not set or has no project rules path.
+func (a *Agent) ProjectRulePath() string {
+	config, ok := a.Config()
+	if !ok {
 		return ""
 	}
 
-	return getAgentUserRulePaths()[*a]
+	return filepath.FromSlash(config.ProjectRulePath)
 }
@@ -0,0 +1,309 @@
+package codingcontext
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// AgentConfigFile is the path of an agent config file, relative to the working
+// directory (repo-level) or the home directory (user-level).
+const AgentConfigFile = ".agents/agents.yaml"
+
+var (
+	// ErrInvalidAgentConfig is returned when an agent config file or definition is invalid.
+	ErrInvalidAgentConfig = errors.New("invalid agent config")
+
+	agentNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+//go:embed agents.yaml
+var builtinAgentsYAML []byte
+
+// MCPConfigFormat is the file format of an agent's MCP server configuration.
+type MCPConfigFormat string
+
+// Supported MCP config formats.
+const (
+	MCPConfigFormatJSON MCPConfigFormat = "json"
+	MCPConfigFormatTOML MCPConfigFormat = "toml"
+	MCPConfigFormatYAML MCPConfigFormat = "yaml"
+)
+
+// MCPConfig is the location and format of an agent's MCP server configuration.
+type MCPConfig struct {
+	// Path is relative to the working directory, or to the home directory when it starts with ~/.
+	Path   string          `yaml:"path"`
+	Format MCPConfigFormat `yaml:"format"`
+}
+
+// agentConfigScope is where an agent declaration comes from, which limits what it may declare.
+type agentConfigScope int
+
+const (
+	// scopeBuiltin is agents.yaml, which is trusted.
+	scopeBuiltin agentConfigScope = iota
+	// scopeUser is the user-level config file, or RegisterAgent. Its agents' user rule paths
+	// must stay in the agent's own config directory in the home directory.
+	scopeUser
+	// scopeProject is the repo-level config file, which the repository controls. It can only
+	// declare new agents, and cannot write outside the repository.
+	scopeProject
+)
+
+// AgentConfig declares an agent: where it keeps its rules, skills, commands, and tasks,
+// where it reads user and project rules from, and where it reads MCP servers from. Built-in
+// agents are declared the same way, in agents.yaml.
+type AgentConfig struct {
+	// RulesPaths are rule files or directories searched in each search path.
+	RulesPaths []string `yaml:"rules_paths"`
+	// SkillsPath is the directory of skill directories searched in each search path.
+	SkillsPath string `yaml:"skills_path"`
+	// CommandsPath is the directory of command files searched in each search path.
+	CommandsPath string `yaml:"commands_path"`
+	// TasksPath is the directory of task files searched in each search path.
+	TasksPath string `yaml:"tasks_path"`
+	// ExcludePatterns identify paths that belong to the agent, which it reads itself.
+	// A path matches if it contains any of the patterns as whole path elements.
+	ExcludePatterns []string `yaml:"exclude_patterns"`
+	// UserRulePath is where -w writes rules, relative to the home directory. Outside of
+	// agents.yaml, it must be in the agent's config directory, .<name>/ or .config/<name>/,
+	// and a repo-level config cannot set it.
+	UserRulePath string `yaml:"user_rule_path"`
+	// ProjectRulePath is where -w --scope project writes rules, relative to the working directory.
+	ProjectRulePath string `yaml:"project_rule_path"`
+	// MCPConfig is where the agent reads MCP servers from, if anywhere. A repo-level config
+	// cannot put it in the home directory.
+	MCPConfig *MCPConfig `yaml:"mcp_config"`
+}
+
+// agentConfigFile is the format of an agent config file.
+type agentConfigFile struct {
+	Agents map[string]AgentConfig `yaml:"agents"`
+}
+
+// agentRegistry holds the agents that ParseAgent accepts.
+type agentRegistry struct {
+	mu     sync.RWMutex
+	agents map[Agent]AgentConfig
+}
+
+// registry holds the built-in agents and every agent registered or loaded since.
+var registry = newBuiltinRegistry()
+
+func newBuiltinRegistry() *agentRegistry {
+	r := &agentRegistry{agents: make(map[Agent]AgentConfig)}
+
+	if err := r.load(builtinAgentsYAML, "agents.yaml", scopeBuiltin); err != nil {
+		panic(fmt.Sprintf("invalid built-in agents: %v", err))
+	}
+
+	return r
+}
+
+// load parses an agent config file and registers its agents. Nothing is
+// registered if any agent is invalid.
+func (r *agentRegistry) load(data []byte, source string, scope agentConfigScope) error {
+	var file agentConfigFile
+
+	if err := yaml.UnmarshalWithOptions(data, &file, yaml.DisallowUnknownField()); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidAgentConfig, source, err)
+	}
+
+	for name, config := range file.Agents {
+		if err := validateAgentConfig(name, config, scope); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrInvalidAgentConfig, source, err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A repository must not change where the agents the user already has write their rules.
+	if scope == scopeProject {
+		for name := range file.Agents {
+			if _, exists := r.agents[Agent(name)]; exists {
+				return fmt.Errorf("%w: %s: agent %s is already defined and cannot be redefined by a repository",
+					ErrInvalidAgentConfig, source, name)
+			}
+		}
+	}
+
+	for name, config := range file.Agents {
+		r.agents[Agent(name)] = config
+	}
+
+	return nil
+}
+
+func (r *agentRegistry) get(agent Agent) (AgentConfig, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	config, ok := r.agents[agent]
+
+	return config, ok
+}
+
+// all returns a copy of the registered agents.
+func (r *agentRegistry) all() map[Agent]AgentConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	agents := make(map[Agent]AgentConfig, len(r.agents))
+	for agent, config := range r.agents {
+		agents[agent] = config
+	}
+
+	return agents
+}
+
+// validateAgentConfig checks the name and paths of an agent declaration from scope.
+func validateAgentConfig(name string, config AgentConfig, scope agentConfigScope) error {
+	if !agentNamePattern.MatchString(name) {
+		return fmt.Errorf("agent name %q must be lowercase letters, digits, hyphens, and underscores", name)
+	}
+
+	paths := slices.Concat(config.RulesPaths,
+		[]string{config.SkillsPath, config.CommandsPath, config.TasksPath, config.UserRulePath, config.ProjectRulePath})
+
+	for _, path := range paths {
+		if filepath.IsAbs(path) || strings.HasPrefix(filepath.ToSlash(filepath.Clean(path)), "../") {
+			return fmt.Errorf("agent %s: path %q must be relative and must not leave its directory", name, path)
+		}
+	}
+
+	// Writing into .git could install hooks, which git runs.
+	if project := filepath.ToSlash(filepath.Clean(config.ProjectRulePath)); project == ".git" ||
+		strings.HasPrefix(project, ".git/") {
+		return fmt.Errorf("agent %s: project_rule_path %q must not be in .git", name, config.ProjectRulePath)
+	}
+
+	if err := validateMCPConfig(name, config.MCPConfig, scope); err != nil {
+		return err
+	}
+
+	switch {
+	case scope == scopeBuiltin || config.UserRulePath == "":
+	case scope == scopeProject:
+		return fmt.Errorf("agent %s: user_rule_path can only be set in the user-level %s", name, AgentConfigFile)
+	case !isInAgentConfigDir(name, config.UserRulePath):
+		return fmt.Errorf("agent %s: user_rule_path %q must be in .%s/ or .config/%s/ in the home directory",
+			name, config.UserRulePath, name, name)
+	}
+
+	return nil
+}
+
+// validateMCPConfig checks the MCP config of an agent declaration from scope, if it has one.
+func validateMCPConfig(name string, mcp *MCPConfig, scope agentConfigScope) error {
+	if mcp == nil {
+		return nil
+	}
+
+	switch mcp.Format {
+	case MCPConfigFormatJSON, MCPConfigFormatTOML, MCPConfigFormatYAML:
+	default:
+		return fmt.Errorf("agent %s: unknown mcp_config format %q (supported: json, toml, yaml)", name, mcp.Format)
+	}
+
+	if mcp.Path == "" {
+		return fmt.Errorf("agent %s: mcp_config requires a path", name)
+	}
+
+	path, inHome := strings.CutPrefix(filepath.ToSlash(mcp.Path), "~/")
+	if inHome && scope == scopeProject {
+		return fmt.Errorf("agent %s: mcp_config path %q can only be in the home directory in the user-level %s",
+			name, mcp.Path, AgentConfigFile)
+	}
+
+	if filepath.IsAbs(path) || strings.HasPrefix(filepath.ToSlash(filepath.Clean(path)), "../") {
+		return fmt.Errorf("agent %s: mcp_config path %q must be relative and must not leave its directory",
+			name, mcp.Path)
+	}
+
+	return nil
+}
+
+// isInAgentConfigDir returns whether path, relative to the home directory, is a file in the
+// config directory of the agent name.
+func isInAgentConfigDir(name, path string) bool {
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	for _, dir := range []string{"." + name + "/", ".config/" + name + "/"} {
+		if strings.HasPrefix(path, dir) && len(path) > len(dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LoadAgentConfig registers the agents declared in the user-level agent config file at
+// path, so that ParseAgent accepts them. Agents with the name of an existing agent replace
+// it. A missing file is not an error.
+func LoadAgentConfig(path string) error {
+	return loadAgentConfigFile(path, scopeUser)
+}
+
+// LoadProjectAgentConfig registers the agents declared in the repo-level agent config file
+// at path. Because the repository controls the file, its agents must have new names and
+// cannot set user_rule_path. A missing file is not an error.
+func LoadProjectAgentConfig(path string) error {
+	return loadAgentConfigFile(path, scopeProject)
+}
+
+func loadAgentConfigFile(path string, scope agentConfigScope) error {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read agent config %s: %w", path, err)
+	}
+
+	return registry.load(data, path, scope)
+}
+
+// RegisterAgent registers an agent, replacing any existing agent with the same name. Its
+// declaration is checked like one in the user-level agent config file.
+func RegisterAgent(agent Agent, config AgentConfig) error {
+	if err := validateAgentConfig(string(agent), config, scopeUser); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidAgentConfig, err)
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+
+	registry.agents[agent] = config
+
+	return nil
+}
+
+// Agents returns the names of all supported agents, sorted.
+func Agents() []Agent {
+	agents := make([]Agent, 0)
+	for agent := range registry.all() {
+		agents = append(agents, agent)
+	}
+
+	slices.Sort(agents)
+
+	return agents
+}
+
+// Config returns the declaration of the agent.
+func (a *Agent) Config() (AgentConfig, bool) {
+	if a == nil {
+		return AgentConfig{}, false
+	}
+
+	return registry.get(*a)
+}
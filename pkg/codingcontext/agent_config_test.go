@@ -0,0 +1,192 @@
+package codingcontext
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestLoadAgentConfig is not parallel because it registers an agent globally; parallel
+// tests only resume once it has finished and removed the agent again.
+func TestLoadAgentConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, AgentConfigFile), `agents:
+  inhouse:
+    rules_paths: [.inhouse/rules]
+    skills_path: .inhouse/skills
+    exclude_patterns: [.inhouse/]
+    user_rule_path: .inhouse/RULES.md
+    mcp_config:
+      path: ~/.inhouse/mcp.yaml
+      format: yaml
+`)
+
+	if err := LoadAgentConfig(filepath.Join(dir, AgentConfigFile)); err != nil {
+		t.Fatalf("LoadAgentConfig() error: %v", err)
+	}
+
+	t.Cleanup(func() {
+		registry.mu.Lock()
+		defer registry.mu.Unlock()
+
+		delete(registry.agents, "inhouse")
+	})
+
+	var agent Agent
+	if err := agent.Set("inhouse"); err != nil {
+		t.Fatalf("Set(inhouse) error: %v", err)
+	}
+
+	if !slices.Contains(Agents(), agent) {
+		t.Errorf("Agents() = %v, want it to contain inhouse", Agents())
+	}
+
+	if got := agent.UserRulePath(); got != filepath.Join(".inhouse", "RULES.md") {
+		t.Errorf("UserRulePath() = %q", got)
+	}
+
+	if config, _ := agent.Config(); config.MCPConfig == nil ||
+		*config.MCPConfig != (MCPConfig{Path: "~/.inhouse/mcp.yaml", Format: MCPConfigFormatYAML}) {
+		t.Errorf("Config().MCPConfig = %+v", config.MCPConfig)
+	}
+
+	if !agent.ShouldExcludePath("/repo/.inhouse/rules/a.md") {
+		t.Error("ShouldExcludePath() = false for the agent's own rules")
+	}
+
+	// The agent's paths are searched.
+	createTask(t, dir, "task", "", "Task")
+	writeFile(t, filepath.Join(dir, ".inhouse/rules/rule.md"), "In-house rule")
+	createSkill(t, dir, ".inhouse/skills/tool", "---\nname: tool\ndescription: A tool\n---\n")
+
+	result, err := New(WithSearchPaths(dir)).Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(result.Rules) != 1 || len(result.Skills.Skills) != 1 {
+		t.Errorf("got %d rules and %d skills, want 1 of each", len(result.Rules), len(result.Skills.Skills))
+	}
+}
+
+func TestAgentRegistry_LoadInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{name: "unknown field", config: "agents:\n  a:\n    rule_paths: [x]\n"},
+		{name: "invalid name", config: "agents:\n  My Agent:\n    skills_path: x\n"},
+		{name: "absolute path", config: "agents:\n  a:\n    skills_path: /etc/skills\n"},
+		{name: "path leaves directory", config: "agents:\n  a:\n    user_rule_path: ../outside.md\n"},
+		{name: "project path leaves directory", config: "agents:\n  a:\n    project_rule_path: ../outside.md\n"},
+		{name: "project path in .git", config: "agents:\n  a:\n    project_rule_path: .git/hooks/pre-commit\n"},
+		{name: "user path outside config dir", config: "agents:\n  a:\n    user_rule_path: .bashrc\n"},
+		{name: "user path in another config dir", config: "agents:\n  a:\n    user_rule_path: .ssh/config\n"},
+		{name: "unknown mcp format", config: "agents:\n  a:\n    mcp_config: {path: mcp.ini, format: ini}\n"},
+		{name: "mcp config without path", config: "agents:\n  a:\n    mcp_config: {format: json}\n"},
+		{name: "absolute mcp path", config: "agents:\n  a:\n    mcp_config: {path: /etc/mcp.json, format: json}\n"},
+		{name: "mcp path leaves home", config: "agents:\n  a:\n    mcp_config: {path: ~/../mcp.json, format: json}\n"},
+		{name: "one invalid agent", config: "agents:\n  a:\n    skills_path: x\n  b:\n    skills_path: /x\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &agentRegistry{agents: make(map[Agent]AgentConfig)}
+
+			err := r.load([]byte(tt.config), "agents.yaml", scopeUser)
+			if !errors.Is(err, ErrInvalidAgentConfig) {
+				t.Fatalf("load() error = %v, want ErrInvalidAgentConfig", err)
+			}
+
+			if len(r.all()) != 0 {
+				t.Errorf("agents were registered from an invalid config: %v", r.all())
+			}
+		})
+	}
+}
+
+func TestAgentRegistry_LoadProject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "new agent", config: "agents:\n  inhouse:\n    rules_paths: [.inhouse/rules]\n" +
+			"    project_rule_path: .inhouse/rules/generated.md\n"},
+		{name: "redefines a built-in agent", config: "agents:\n  claude:\n    rules_paths: [.claude]\n", wantErr: true},
+		{name: "redefines a user agent", config: "agents:\n  mine:\n    rules_paths: [.mine]\n", wantErr: true},
+		{name: "sets user rule path", config: "agents:\n  inhouse:\n    user_rule_path: .inhouse/RULES.md\n",
+			wantErr: true},
+		{name: "mcp config in the project", config: "agents:\n  inhouse:\n" +
+			"    mcp_config: {path: .inhouse/mcp.json, format: json}\n"},
+		{name: "mcp config in the home directory", config: "agents:\n  inhouse:\n" +
+			"    mcp_config: {path: ~/.inhouse/mcp.json, format: json}\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := newBuiltinRegistry()
+			if err := r.load([]byte("agents:\n  mine:\n    user_rule_path: .mine/RULES.md\n"), "user", scopeUser); err != nil {
+				t.Fatalf("load() user config error: %v", err)
+			}
+
+			builtin, _ := r.get(AgentClaude)
+
+			err := r.load([]byte(tt.config), "project", scopeProject)
+			if gotErr := errors.Is(err, ErrInvalidAgentConfig); gotErr != tt.wantErr {
+				t.Fatalf("load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got, _ := r.get(AgentClaude); got.UserRulePath != builtin.UserRulePath {
+				t.Errorf("claude user rule path = %q, want %q", got.UserRulePath, builtin.UserRulePath)
+			}
+		})
+	}
+}
+
+func TestBuiltinAgentsDeclared(t *testing.T) {
+	t.Parallel()
+
+	builtins := []Agent{
+		AgentCursor, AgentOpenCode, AgentCopilot, AgentClaude,
+		AgentGemini, AgentAugment, AgentWindsurf, AgentCodex,
+		AgentCline, AgentRoo, AgentKiro, AgentZed, AgentContinue, AgentAider, AgentJunie,
+	}
+
+	for _, agent := range builtins {
+		config, ok := agent.Config()
+		if !ok {
+			t.Errorf("built-in agent %s is not declared", agent)
+
+			continue
+		}
+
+		if len(config.ExcludePatterns) == 0 || config.UserRulePath == "" && config.ProjectRulePath == "" {
+			t.Errorf("built-in agent %s has no exclude patterns or rule path to write", agent)
+		}
+	}
+
+	codex := AgentCodex
+	if config, _ := codex.Config(); config.MCPConfig == nil ||
+		*config.MCPConfig != (MCPConfig{Path: "~/.codex/config.toml", Format: MCPConfigFormatTOML}) {
+		t.Errorf("codex MCPConfig = %+v", config.MCPConfig)
+	}
+}
+
+func TestLoadAgentConfig_MissingFile(t *testing.T) {
+	t.Parallel()
+
+	if err := LoadAgentConfig(filepath.Join(t.TempDir(), AgentConfigFile)); err != nil {
+		t.Errorf("LoadAgentConfig() on a missing file error: %v", err)
+	}
+}
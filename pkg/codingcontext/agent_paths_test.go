@@ -48,6 +48,34 @@ func TestAgentPaths_Structure(t *testing.T) {
 			name:  "codex agent",
 			agent: AgentCodex,
 		},
+		{
+			name:  "cline agent",
+			agent: AgentCline,
+		},
+		{
+			name:  "roo agent",
+			agent: AgentRoo,
+		},
+		{
+			name:  "kiro agent",
+			agent: AgentKiro,
+		},
+		{
+			name:  "zed agent",
+			agent: AgentZed,
+		},
+		{
+			name:  "continue agent",
+			agent: AgentContinue,
+		},
+		{
+			name:  "aider agent",
+			agent: AgentAider,
+		},
+		{
+			name:  "junie agent",
+			agent: AgentJunie,
+		},
 	}
 
 	for _, tt := range tests {
@@ -135,8 +163,8 @@ func TestAgentPaths_NoAbsolutePaths(t *testing.T) {
 
 func TestAgentPaths_Count(t *testing.T) {
 	t.Parallel()
-	// Should have 9 entries: 1 empty agent + 8 named agents
-	expectedCount := 9
+	// Should have 16 entries: 1 empty agent + 15 named agents
+	expectedCount := 16
 	if len(getAgentsPaths()) != expectedCount {
 		t.Errorf("agents paths should have %d entries, got %d", expectedCount, len(getAgentsPaths()))
 	}
@@ -163,6 +191,41 @@ func TestAgent_Paths(t *testing.T) {
 			wantRulesPaths: []string{".agents/rules"},
 			wantSkillsPath: ".agents/skills",
 		},
+		{
+			name:           "cline agent",
+			agent:          AgentCline,
+			wantRulesPaths: []string{".clinerules"},
+		},
+		{
+			name:           "roo agent",
+			agent:          AgentRoo,
+			wantRulesPaths: []string{".roo/rules", ".roorules"},
+		},
+		{
+			name:           "kiro agent",
+			agent:          AgentKiro,
+			wantRulesPaths: []string{".kiro/steering"},
+		},
+		{
+			name:           "zed agent",
+			agent:          AgentZed,
+			wantRulesPaths: []string{".rules"},
+		},
+		{
+			name:           "continue agent",
+			agent:          AgentContinue,
+			wantRulesPaths: []string{".continue/rules"},
+		},
+		{
+			name:           "aider agent",
+			agent:          AgentAider,
+			wantRulesPaths: []string{"CONVENTIONS.md"},
+		},
+		{
+			name:           "junie agent",
+			agent:          AgentJunie,
+			wantRulesPaths: []string{".junie/guidelines.md"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -25,6 +25,13 @@ func parseAgentCases() []struct {
 		{name: "valid - augment", input: "augment", want: AgentAugment},
 		{name: "valid - windsurf", input: "windsurf", want: AgentWindsurf},
 		{name: "valid - codex", input: "codex", want: AgentCodex},
+		{name: "valid - cline", input: "cline", want: AgentCline},
+		{name: "valid - roo", input: "roo", want: AgentRoo},
+		{name: "valid - kiro", input: "kiro", want: AgentKiro},
+		{name: "valid - zed", input: "zed", want: AgentZed},
+		{name: "valid - continue", input: "continue", want: AgentContinue},
+		{name: "valid - aider", input: "aider", want: AgentAider},
+		{name: "valid - junie", input: "junie", want: AgentJunie},
 		{name: "uppercase should fail", input: "CURSOR", want: "", wantErr: true},
 		{name: "mixed case should fail", input: "OpenCode", want: "", wantErr: true},
 		{name: "with spaces should fail", input: "  cursor  ", want: "", wantErr: true},
@@ -84,6 +91,27 @@ func agentMatchesPathCases() []struct {
 		{name: "windsurf matches .windsurfrules", agent: AgentWindsurf, path: ".windsurfrules", wantMatch: true},
 		{name: "codex matches .codex dir", agent: AgentCodex, path: ".codex/AGENTS.md", wantMatch: true},
 		{name: "codex matches AGENTS.md", agent: AgentCodex, path: "AGENTS.md", wantMatch: true},
+		{name: "cline matches .clinerules file", agent: AgentCline, path: ".clinerules", wantMatch: true},
+		{name: "cline matches .clinerules dir", agent: AgentCline, path: ".clinerules/style.md", wantMatch: true},
+		{name: "roo matches .roo/rules", agent: AgentRoo, path: ".roo/rules/style.md", wantMatch: true},
+		{name: "roo matches .roorules", agent: AgentRoo, path: ".roorules", wantMatch: true},
+		{name: "kiro matches .kiro/steering", agent: AgentKiro, path: ".kiro/steering/tech.md", wantMatch: true},
+		{name: "zed matches .rules", agent: AgentZed, path: "/repo/.rules", wantMatch: true},
+		{name: "zed does not match .agents/rules", agent: AgentZed, path: ".agents/rules/a.md", wantMatch: false},
+		{name: "continue matches .continue/rules", agent: AgentContinue,
+			path: ".continue/rules/style.md", wantMatch: true},
+		{name: "aider matches CONVENTIONS.md", agent: AgentAider, path: "CONVENTIONS.md", wantMatch: true},
+		{name: "aider does not match MY_CONVENTIONS.md", agent: AgentAider,
+			path: "/repo/docs/MY_CONVENTIONS.md", wantMatch: false},
+		{name: "aider does not match CONVENTIONS.md.bak", agent: AgentAider,
+			path: "/repo/CONVENTIONS.md.bak", wantMatch: false},
+		{name: "zed does not match foo.rules", agent: AgentZed, path: "/repo/foo.rules", wantMatch: false},
+		{name: "zed does not match .rules.d", agent: AgentZed, path: "/repo/.rules.d/a.md", wantMatch: false},
+		{name: "claude does not match NOT_CLAUDE.md", agent: AgentClaude, path: "/repo/NOT_CLAUDE.md", wantMatch: false},
+		{name: "cursor does not match .cursor-backup", agent: AgentCursor,
+			path: "/repo/.cursor-backup/rules/a.md", wantMatch: false},
+		{name: "junie matches guidelines", agent: AgentJunie, path: ".junie/guidelines.md", wantMatch: true},
+		{name: "junie does not match AGENTS.md", agent: AgentJunie, path: "AGENTS.md", wantMatch: false},
 		{name: "absolute path matching", agent: AgentCursor,
 			path: "/home/user/project/.cursor/rules/example.md", wantMatch: true},
 	}
@@ -316,6 +344,41 @@ func TestAgent_UserRulePath(t *testing.T) {
 			agent:    AgentCodex,
 			wantPath: filepath.Join(".codex", "AGENTS.md"),
 		},
+		{
+			name:     "cline agent",
+			agent:    AgentCline,
+			wantPath: filepath.Join("Documents", "Cline", "Rules", "AGENTS.md"),
+		},
+		{
+			name:     "roo agent",
+			agent:    AgentRoo,
+			wantPath: filepath.Join(".roo", "rules", "AGENTS.md"),
+		},
+		{
+			name:     "kiro agent",
+			agent:    AgentKiro,
+			wantPath: filepath.Join(".kiro", "steering", "AGENTS.md"),
+		},
+		{
+			name:     "zed agent, which has no user rules file",
+			agent:    AgentZed,
+			wantPath: "",
+		},
+		{
+			name:     "continue agent",
+			agent:    AgentContinue,
+			wantPath: filepath.Join(".continue", "rules", "AGENTS.md"),
+		},
+		{
+			name:     "aider agent, which has no user rules file",
+			agent:    AgentAider,
+			wantPath: "",
+		},
+		{
+			name:     "junie agent, which has no user rules file",
+			agent:    AgentJunie,
+			wantPath: "",
+		},
 		{
 			name:     "empty agent",
 			agent:    Agent(""),
@@ -334,3 +397,59 @@ func TestAgent_UserRulePath(t *testing.T) {
 		})
 	}
 }
+
+func TestAgent_ProjectRulePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		agent    Agent
+		wantPath string
+	}{
+		{
+			name:     "claude agent",
+			agent:    AgentClaude,
+			wantPath: "CLAUDE.local.md",
+		},
+		{
+			name:     "cursor agent",
+			agent:    AgentCursor,
+			wantPath: filepath.Join(".cursor", "rules", "coding-context.mdc"),
+		},
+		{
+			name:     "copilot agent",
+			agent:    AgentCopilot,
+			wantPath: filepath.Join(".github", "agents", "coding-context.md"),
+		},
+		{
+			name:     "codex agent",
+			agent:    AgentCodex,
+			wantPath: "AGENTS.md",
+		},
+		{
+			name:     "aider agent",
+			agent:    AgentAider,
+			wantPath: "CONVENTIONS.md",
+		},
+		{
+			name:     "junie agent",
+			agent:    AgentJunie,
+			wantPath: filepath.Join(".junie", "guidelines.md"),
+		},
+		{
+			name:     "empty agent",
+			agent:    Agent(""),
+			wantPath: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.agent.ProjectRulePath(); got != tt.wantPath {
+				t.Errorf("ProjectRulePath() = %q, want %q", got, tt.wantPath)
+			}
+		})
+	}
+}
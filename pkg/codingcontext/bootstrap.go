@@ -0,0 +1,310 @@
+package codingcontext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
+)
+
+// executablePerm is the permission for executable scripts (0755) - required for direct execution and shebang support.
+const executablePerm = 0o755
+
+// defaultBootstrapOutputMaxBytes is the capture limit used when bootstrap_output.max_bytes is not set.
+const defaultBootstrapOutputMaxBytes = 16 * 1024
+
+// runBootstrapScript runs the bootstrap for the markdown file at path, preferring the
+// frontmatter script over a companion <name>-bootstrap file. When bootstrap_output is
+// configured, the captured stdout is returned; otherwise the returned string is empty.
+func (cc *Context) runBootstrapScript(
+	ctx context.Context, path string, bootstrap markdown.BootstrapFrontMatter,
+) (string, error) {
+	// In lint mode, skip execution but stat-check companion bootstrap files.
+	if cc.lintMode {
+		cc.recordLintBootstrap(path, bootstrap.Bootstrap)
+
+		return "", nil
+	}
+
+	script, bootstrapFilePath, err := cc.readBootstrapScript(path, bootstrap)
+	if err != nil || script == nil {
+		return "", err
+	}
+
+	// Checked under the lock, so a run_once bootstrap run by a concurrent run is skipped.
+	unlock, err := cc.shared.lockBootstrap(ctx, path)
+	if err != nil {
+		return "", err
+	}
+
+	defer unlock()
+
+	cacheKey, cached, skip, err := cc.checkBootstrapCache(path, bootstrap, script)
+	if err != nil {
+		return "", err
+	}
+
+	if skip {
+		return cc.captureResult(path, bootstrap.BootstrapOutput, cached), nil
+	}
+
+	var stdout io.Writer = os.Stderr
+
+	var captured *cappedBuffer
+	if bootstrap.BootstrapOutput != nil {
+		captured = newCappedBuffer(bootstrap.BootstrapOutput.MaxBytes)
+		stdout = captured
+	}
+
+	switch {
+	case bootstrapFilePath == "":
+		err = cc.runFrontmatterBootstrap(ctx, path, bootstrap.Bootstrap, stdout)
+	case cc.isFSPath(bootstrapFilePath):
+		// A script in an fs.FS cannot be executed in place, so it is run like a frontmatter script.
+		err = cc.runFrontmatterBootstrap(ctx, path, string(script), stdout)
+	default:
+		err = cc.runBootstrapFile(ctx, path, bootstrapFilePath, stdout)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	output := captured.result(cc, path)
+
+	if err := cc.recordBootstrapSuccess(path, cacheKey, output); err != nil {
+		return "", err
+	}
+
+	return cc.captureResult(path, bootstrap.BootstrapOutput, output), nil
+}
+
+// runTaskBootstrap runs the bootstrap of the loaded task before rules are discovered,
+// recording any captured output for the task content.
+func (cc *Context) runTaskBootstrap(ctx context.Context) error {
+	if !cc.doBootstrap || cc.taskPath == "" {
+		return nil
+	}
+
+	output, err := cc.runBootstrapScript(ctx, cc.taskPath, cc.task.FrontMatter.BootstrapFrontMatter)
+	if err != nil {
+		return err
+	}
+
+	cc.captureBootstrapParam(cc.task.FrontMatter.BootstrapOutput, output)
+	cc.taskOutput = output
+
+	return nil
+}
+
+// readBootstrapScript returns the bootstrap script for the markdown file at path.
+// The frontmatter script is returned with an empty file path; a companion
+// <name>-bootstrap file is returned with its path. A nil script means there is no bootstrap.
+func (cc *Context) readBootstrapScript(path string, bootstrap markdown.BootstrapFrontMatter) ([]byte, string, error) {
+	// Prefer frontmatter bootstrap if present
+	if bootstrap.Bootstrap != "" {
+		return []byte(bootstrap.Bootstrap), "", nil
+	}
+
+	// Fall back to file-based bootstrap
+	baseNameWithoutExt := strings.TrimSuffix(path, filepath.Ext(path))
+	bootstrapFilePath := baseNameWithoutExt + "-bootstrap"
+
+	script, err := cc.readFile(bootstrapFilePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", nil
+	} else if err != nil {
+		return nil, "", fmt.Errorf("failed to read bootstrap file %s: %w", bootstrapFilePath, err)
+	}
+
+	return script, bootstrapFilePath, nil
+}
+
+// runFrontmatterBootstrap writes a frontmatter bootstrap script to a temporary executable and runs it.
+func (cc *Context) runFrontmatterBootstrap(ctx context.Context, path, script string, stdout io.Writer) error {
+	cc.logger.Info("Running bootstrap from frontmatter", "path", path)
+
+	tmpFile, err := os.CreateTemp("", "bootstrap-*.sh")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for bootstrap script from %s: %w", path, err)
+	}
+
+	tmpFilePath := tmpFile.Name()
+
+	defer func() { _ = os.Remove(tmpFilePath) }()
+
+	if _, err := tmpFile.WriteString(script); err != nil {
+		_ = tmpFile.Close()
+
+		return fmt.Errorf("failed to write bootstrap script from %s: %w", path, err)
+	}
+
+	_ = tmpFile.Close()
+
+	// Scripts must be executable to run; supports shebangs (e.g. #!/usr/bin/python3)
+	// #nosec G302 G703 -- bootstrap scripts require 0755; tmpFilePath from CreateTemp is system-generated
+	if err := os.Chmod(tmpFilePath, executablePerm); err != nil {
+		return fmt.Errorf("failed to chmod bootstrap script from %s: %w", path, err)
+	}
+
+	if err := cc.execBootstrap(ctx, tmpFilePath, stdout); err != nil {
+		return fmt.Errorf("frontmatter bootstrap script failed for %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// runBootstrapFile runs a companion <name>-bootstrap file.
+func (cc *Context) runBootstrapFile(ctx context.Context, path, bootstrapFilePath string, stdout io.Writer) error {
+	cc.logger.Info("Running bootstrap script", "path", bootstrapFilePath)
+
+	// #nosec G302 -- bootstrap scripts require executablePerm for direct execution and shebang support
+	if err := os.Chmod(bootstrapFilePath, executablePerm); err != nil {
+		return fmt.Errorf("failed to chmod bootstrap file %s: %w", bootstrapFilePath, err)
+	}
+
+	if err := cc.execBootstrap(ctx, bootstrapFilePath, stdout); err != nil {
+		return fmt.Errorf("file-based bootstrap script failed for %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// execBootstrap executes a bootstrap script, applying the configured timeout and,
+// when enabled, the sandbox. Script stdout goes to stdout; stderr goes to our stderr.
+func (cc *Context) execBootstrap(ctx context.Context, script string, stdout io.Writer) error {
+	if cc.sandbox.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, cc.sandbox.Timeout)
+		defer cancel()
+	}
+
+	// #nosec G204 G702 -- intentionally executing user-defined bootstrap scripts
+	cmd := exec.CommandContext(ctx, script)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+
+	if !cc.sandbox.Enabled {
+		if err := cc.cmdRunner(cmd); err != nil {
+			return wrapTimeout(ctx, err)
+		}
+
+		return nil
+	}
+
+	if err := cc.runSandboxed(cmd); err != nil {
+		return wrapTimeout(ctx, err)
+	}
+
+	return nil
+}
+
+// wrapTimeout annotates err with the context error when the script was killed
+// because its deadline expired, so callers see why the script failed.
+func wrapTimeout(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+
+	return err
+}
+
+// captureResult returns the output to use for a bootstrap with bootstrap_output configured,
+// or an empty string when output is not captured.
+func (cc *Context) captureResult(path string, out *markdown.BootstrapOutput, output string) string {
+	if out == nil {
+		return ""
+	}
+
+	cc.logger.Info("Captured bootstrap output", "path", path, "param", out.Param, "append", out.Append,
+		"bytes", len(output))
+
+	return output
+}
+
+// captureBootstrapParam records the parameter defined by bootstrap_output.param so that
+// rules, the task, and commands can use it. It does nothing when output is not captured
+// into a parameter.
+func (cc *Context) captureBootstrapParam(out *markdown.BootstrapOutput, output string) {
+	if out == nil || out.Param == "" {
+		return
+	}
+
+	if cc.capturedParams == nil {
+		cc.capturedParams = make(taskparser.Params)
+	}
+
+	cc.capturedParams[out.Param] = []string{output}
+}
+
+// appendBootstrapOutput appends captured output to rule content when bootstrap_output.append is set.
+// The output is appended after parameter expansion, so it is included verbatim.
+func appendBootstrapOutput(content string, out *markdown.BootstrapOutput, output string) string {
+	if out == nil || !out.Append || output == "" {
+		return content
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+
+	return content + "\n" + output + "\n"
+}
+
+// cappedBuffer collects up to limit bytes of output and discards the rest,
+// so a chatty script cannot blow up the assembled context.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func newCappedBuffer(limit int) *cappedBuffer {
+	if limit <= 0 {
+		limit = defaultBootstrapOutputMaxBytes
+	}
+
+	return &cappedBuffer{limit: limit}
+}
+
+// Write implements io.Writer. It never fails, so the script is not killed by SIGPIPE.
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if len(p) > remaining {
+		b.truncated = true
+		b.buf.Write(p[:max(remaining, 0)])
+
+		return len(p), nil
+	}
+
+	b.buf.Write(p)
+
+	return len(p), nil
+}
+
+// result returns the captured output with trailing newlines trimmed, marking truncation.
+// It returns an empty string for a nil buffer.
+func (b *cappedBuffer) result(cc *Context, path string) string {
+	if b == nil {
+		return ""
+	}
+
+	output := strings.TrimRight(b.buf.String(), "\r\n")
+	if b.truncated {
+		cc.logger.Warn("Bootstrap output truncated", "path", path, "max_bytes", b.limit)
+
+		output += "\n[output truncated]"
+	}
+
+	return output
+}
@@ -0,0 +1,239 @@
+package codingcontext
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+)
+
+// errUnknownBootstrapCache is returned for a bootstrap_cache value other than project or machine.
+var errUnknownBootstrapCache = errors.New("unknown bootstrap_cache (supported: project, machine)")
+
+// checkBootstrapCache decides whether a bootstrap script can be skipped.
+// A script is skipped when its `creates` path already exists, or when it is marked
+// `run_once` (or `bootstrap_cache`) and a previous run of the same script and input
+// content, for the same project unless the cache is machine-wide, succeeded.
+// The returned cache key is non-empty for run_once scripts and must be passed to
+// recordBootstrapSuccess once the script has run. When a run_once script is skipped,
+// the output captured by its last successful run is returned. Every decision is logged.
+func (cc *Context) checkBootstrapCache(
+	path string, bootstrap markdown.BootstrapFrontMatter, script []byte,
+) (string, string, bool, error) {
+	var cacheKey string
+
+	switch bootstrap.BootstrapCache {
+	case "", markdown.BootstrapCacheProject, markdown.BootstrapCacheMachine:
+	default:
+		return "", "", false, fmt.Errorf("%s: %w: %q", path, errUnknownBootstrapCache, bootstrap.BootstrapCache)
+	}
+
+	runOnce := bootstrap.RunOnce || bootstrap.BootstrapCache != ""
+	if runOnce {
+		key, err := cc.bootstrapCacheKey(path, script, bootstrap)
+		if err != nil {
+			return "", "", false, fmt.Errorf("failed to compute bootstrap cache key for %s: %w", path, err)
+		}
+
+		cacheKey = key
+	}
+
+	if cc.forceBootstrap {
+		if runOnce || bootstrap.Creates != "" {
+			cc.logger.Info("Running bootstrap", "path", path, "reason", "forced by --force-bootstrap")
+		}
+
+		return cacheKey, "", false, nil
+	}
+
+	if bootstrap.Creates != "" {
+		created := cc.resolveBootstrapPath(bootstrap.Creates)
+		if _, err := os.Stat(created); err == nil {
+			cc.logger.Info("Skipping bootstrap", "path", path,
+				"reason", fmt.Sprintf("'creates' path %s already exists", created))
+
+			if bootstrap.BootstrapOutput != nil {
+				cc.logger.Warn("No bootstrap output to capture because the bootstrap was skipped", "path", path)
+			}
+
+			return "", "", true, nil
+		}
+	}
+
+	if cacheKey == "" {
+		return "", "", false, nil
+	}
+
+	markerPath, err := cc.bootstrapMarkerPath(cacheKey)
+	if err != nil {
+		return "", "", false, err
+	}
+
+	if data, err := os.ReadFile(filepath.Clean(markerPath)); err == nil {
+		var marker bootstrapMarker
+		if err := json.Unmarshal(data, &marker); err == nil {
+			cc.logger.Info("Skipping bootstrap", "path", path,
+				"reason", "script and inputs unchanged since last successful run", "cache_key", cacheKey)
+
+			return "", marker.Output, true, nil
+		}
+	}
+
+	cc.logger.Info("Running bootstrap", "path", path,
+		"reason", "no successful run recorded for current script and inputs", "cache_key", cacheKey)
+
+	return cacheKey, "", false, nil
+}
+
+// bootstrapMarker is the content of a run_once marker file.
+type bootstrapMarker struct {
+	Path   string `json:"path"`
+	Output string `json:"output,omitempty"` // Captured stdout, replayed when the run is skipped
+}
+
+// recordBootstrapSuccess writes the marker for a successful run_once bootstrap, including
+// any captured output. It does nothing when cacheKey is empty.
+func (cc *Context) recordBootstrapSuccess(path, cacheKey, output string) error {
+	if cacheKey == "" {
+		return nil
+	}
+
+	markerPath, err := cc.bootstrapMarkerPath(cacheKey)
+	if err != nil {
+		return err
+	}
+
+	const dirMode = 0o750
+	if err := os.MkdirAll(filepath.Dir(markerPath), dirMode); err != nil {
+		return fmt.Errorf("failed to create bootstrap cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(bootstrapMarker{Path: path, Output: output})
+	if err != nil {
+		return fmt.Errorf("failed to encode bootstrap result for %s: %w", path, err)
+	}
+
+	const fileMode = 0o600
+	if err := os.WriteFile(markerPath, data, fileMode); err != nil {
+		return fmt.Errorf("failed to record bootstrap result for %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// bootstrapCacheKey hashes the script together with the content of each declared input
+// and the output capture settings (so enabling capture re-runs the script).
+// Missing inputs are hashed as missing, so creating them later invalidates the cache.
+// Unless the cache is machine-wide, the directory the script runs in and the rule file
+// are hashed too, so that the same rule in another project runs there as well.
+func (cc *Context) bootstrapCacheKey(
+	path string, script []byte, bootstrap markdown.BootstrapFrontMatter,
+) (string, error) {
+	h := sha256.New()
+	h.Write([]byte("script\x00"))
+	h.Write(script)
+
+	if bootstrap.BootstrapCache != markdown.BootstrapCacheMachine {
+		workDir, err := cc.bootstrapWorkDir()
+		if err != nil {
+			return "", err
+		}
+
+		rulePath, err := filepath.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve rule path %s: %w", path, err)
+		}
+
+		h.Write([]byte("\x00workdir\x00" + workDir + "\x00rule\x00" + rulePath))
+	}
+
+	if out := bootstrap.BootstrapOutput; out != nil {
+		fmt.Fprintf(h, "\x00output\x00%s\x00%t\x00%d", out.Param, out.Append, out.MaxBytes)
+	}
+
+	for _, input := range bootstrap.BootstrapInputs {
+		h.Write([]byte("\x00input\x00" + input + "\x00"))
+
+		root := cc.resolveBootstrapPath(input)
+
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			content, err := os.ReadFile(filepath.Clean(p))
+			if err != nil {
+				return fmt.Errorf("failed to read bootstrap input %s: %w", p, err)
+			}
+
+			rel, _ := filepath.Rel(root, p)
+			h.Write([]byte("\x00file\x00" + rel + "\x00"))
+			h.Write(content)
+
+			return nil
+		})
+		if os.IsNotExist(err) {
+			h.Write([]byte("\x00missing"))
+		} else if err != nil {
+			return "", fmt.Errorf("failed to hash bootstrap input %s: %w", input, err)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// bootstrapMarkerPath returns the marker file recording a successful run for cacheKey.
+func (cc *Context) bootstrapMarkerPath(cacheKey string) (string, error) {
+	dir := cc.bootstrapCache
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to determine bootstrap cache directory: %w", err)
+		}
+
+		dir = filepath.Join(userCacheDir, "coding-context", "bootstrap")
+	}
+
+	return filepath.Join(dir, cacheKey), nil
+}
+
+// bootstrapWorkDir returns the absolute directory bootstrap scripts run in: the sandbox's
+// working directory, or else the current directory.
+func (cc *Context) bootstrapWorkDir() (string, error) {
+	workDir := cc.sandbox.WorkDir
+	if !cc.sandbox.Enabled || workDir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get working directory for bootstrap: %w", err)
+		}
+
+		workDir = wd
+	}
+
+	workDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve bootstrap working directory %s: %w", workDir, err)
+	}
+
+	return workDir, nil
+}
+
+// resolveBootstrapPath resolves a path from bootstrap frontmatter against the
+// directory bootstrap scripts run in.
+func (cc *Context) resolveBootstrapPath(path string) string {
+	if filepath.IsAbs(path) || !cc.sandbox.Enabled || cc.sandbox.WorkDir == "" {
+		return path
+	}
+
+	return filepath.Join(cc.sandbox.WorkDir, path)
+}
@@ -0,0 +1,256 @@
+package codingcontext
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// countingRunner returns a cmdRunner that counts executions without running anything.
+func countingRunner(count *atomic.Int32) func(*exec.Cmd) error {
+	return func(_ *exec.Cmd) error {
+		count.Add(1)
+
+		return nil
+	}
+}
+
+func TestBootstrapCache(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		frontmatter func(dir string) string
+		between     func(t *testing.T, dir string) // runs between the first and second Run
+		force       bool
+		wantRuns    int32
+	}{
+		{
+			name:        "without run_once runs every time",
+			frontmatter: func(string) string { return "bootstrap: echo hi" },
+			wantRuns:    2,
+		},
+		{
+			name:        "run_once skips unchanged script",
+			frontmatter: func(string) string { return "bootstrap: echo hi\nrun_once: true" },
+			wantRuns:    1,
+		},
+		{
+			name:        "run_once reruns when script changes",
+			frontmatter: func(string) string { return "bootstrap: echo hi\nrun_once: true" },
+			between: func(t *testing.T, dir string) {
+				t.Helper()
+				createRule(t, dir, ".agents/rules/rule.md", "bootstrap: echo changed\nrun_once: true", "Rule")
+			},
+			wantRuns: 2,
+		},
+		{
+			name: "run_once reruns when an input changes",
+			frontmatter: func(dir string) string {
+				return "bootstrap: echo hi\nrun_once: true\nbootstrap_inputs: [" + filepath.Join(dir, "go.mod") + "]"
+			},
+			between: func(t *testing.T, dir string) {
+				t.Helper()
+				writeFile(t, filepath.Join(dir, "go.mod"), "module changed")
+			},
+			wantRuns: 2,
+		},
+		{
+			name: "run_once skips when inputs are unchanged",
+			frontmatter: func(dir string) string {
+				return "bootstrap: echo hi\nrun_once: true\nbootstrap_inputs: [" + filepath.Join(dir, "go.mod") + "]"
+			},
+			wantRuns: 1,
+		},
+		{
+			name: "creates skips when path exists",
+			frontmatter: func(dir string) string {
+				return "bootstrap: echo hi\ncreates: " + filepath.Join(dir, "go.mod")
+			},
+			wantRuns: 0,
+		},
+		{
+			name: "creates runs when path is missing",
+			frontmatter: func(dir string) string {
+				return "bootstrap: echo hi\ncreates: " + filepath.Join(dir, "bin", "tool")
+			},
+			wantRuns: 2,
+		},
+		{
+			name:        "force-bootstrap ignores run_once",
+			frontmatter: func(string) string { return "bootstrap: echo hi\nrun_once: true" },
+			force:       true,
+			wantRuns:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			cacheDir := t.TempDir()
+
+			writeFile(t, filepath.Join(dir, "go.mod"), "module example")
+			createTask(t, dir, "task", "", "Task")
+			createRule(t, dir, ".agents/rules/rule.md", tt.frontmatter(dir), "Rule")
+
+			var runs atomic.Int32
+
+			for i := range 2 {
+				if i == 1 && tt.between != nil {
+					tt.between(t, dir)
+				}
+
+				c := New(WithSearchPaths(dir), WithBootstrapCacheDir(cacheDir), WithForceBootstrap(tt.force))
+				c.cmdRunner = countingRunner(&runs)
+
+				if _, err := c.Run(context.Background(), "task"); err != nil {
+					t.Fatalf("Run() #%d error: %v", i+1, err)
+				}
+			}
+
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Errorf("bootstrap ran %d times, want %d", got, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestBootstrapCache_Scope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		frontmatter string
+		wantRuns    int32
+	}{
+		{name: "run_once runs in each project", frontmatter: "bootstrap: echo hi\nrun_once: true", wantRuns: 2},
+		{name: "project cache runs in each project", frontmatter: "bootstrap: echo hi\nbootstrap_cache: project", wantRuns: 2},
+		{name: "machine cache runs once", frontmatter: "bootstrap: echo hi\nbootstrap_cache: machine", wantRuns: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := t.TempDir()
+
+			var runs atomic.Int32
+
+			// The same rule in two projects, sharing the user's cache.
+			for range 2 {
+				dir := t.TempDir()
+				createTask(t, dir, "task", "", "Task")
+				createRule(t, dir, ".agents/rules/rule.md", tt.frontmatter, "Rule")
+
+				c := New(WithSearchPaths(dir), WithBootstrapCacheDir(cacheDir))
+				c.cmdRunner = countingRunner(&runs)
+
+				if _, err := c.Run(context.Background(), "task"); err != nil {
+					t.Fatalf("Run() error: %v", err)
+				}
+			}
+
+			if got := runs.Load(); got != tt.wantRuns {
+				t.Errorf("bootstrap ran %d times, want %d", got, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestBootstrapCache_UnknownScope(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	createTask(t, dir, "task", "", "Task")
+	createRule(t, dir, ".agents/rules/rule.md", "bootstrap: echo hi\nbootstrap_cache: forever", "Rule")
+
+	var runs atomic.Int32
+
+	c := New(WithSearchPaths(dir), WithBootstrapCacheDir(t.TempDir()))
+	c.cmdRunner = countingRunner(&runs)
+
+	if _, err := c.Run(context.Background(), "task"); !errors.Is(err, errUnknownBootstrapCache) {
+		t.Errorf("Run() error = %v, want %v", err, errUnknownBootstrapCache)
+	}
+
+	if runs.Load() != 0 {
+		t.Error("bootstrap ran with an unknown bootstrap_cache")
+	}
+}
+
+func TestBootstrapCache_FileBasedScript(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	createTask(t, dir, "task", "", "Task")
+	createRule(t, dir, ".agents/rules/rule.md", "run_once: true", "Rule")
+	createBootstrapScript(t, dir, ".agents/rules/rule.md", "#!/bin/sh\necho one")
+
+	var runs atomic.Int32
+
+	run := func() {
+		c := New(WithSearchPaths(dir), WithBootstrapCacheDir(cacheDir))
+		c.cmdRunner = countingRunner(&runs)
+
+		if _, err := c.Run(context.Background(), "task"); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+	}
+
+	run()
+	run()
+
+	createBootstrapScript(t, dir, ".agents/rules/rule.md", "#!/bin/sh\necho two")
+	run()
+
+	if got := runs.Load(); got != 2 {
+		t.Errorf("bootstrap ran %d times, want 2 (initial run and after script change)", got)
+	}
+}
+
+func TestBootstrapCache_FailedRunNotRecorded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+
+	createTask(t, dir, "task", "", "Task")
+	createRule(t, dir, ".agents/rules/rule.md", "bootstrap: exit 1\nrun_once: true", "Rule")
+
+	c := New(WithSearchPaths(dir), WithBootstrapCacheDir(cacheDir))
+	c.cmdRunner = func(_ *exec.Cmd) error { return os.ErrPermission }
+
+	if _, err := c.Run(context.Background(), "task"); err == nil {
+		t.Fatal("expected Run() to fail")
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		t.Fatalf("failed to read cache dir: %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("expected no cache entries after a failed bootstrap, got %d", len(entries))
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		t.Fatalf("failed to create directory for %s: %v", path, err)
+	}
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
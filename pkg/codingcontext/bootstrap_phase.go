@@ -0,0 +1,272 @@
+package codingcontext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+)
+
+// selectedRule is a rule that matched the selectors, waiting for its bootstrap to run
+// before its content is expanded and included.
+type selectedRule struct {
+	path        string
+	frontMatter markdown.RuleFrontMatter
+	content     string
+	reason      string // Why the rule matched the selectors
+	lenient     bool   // Whether the rule came from a lenient search path
+	output      string // Captured bootstrap output
+	err         error  // Bootstrap error; the rule is not included when set
+}
+
+// runBootstraps runs the bootstrap of every selected rule. A rule's bootstrap starts once
+// the bootstraps of the rules named in its depends_on have succeeded. Bootstraps run one at
+// a time in discovery order unless a rule uses depends_on or the caller sets a concurrency,
+// and then independent bootstraps run in parallel. All bootstraps run to completion and
+// their failures are aggregated: failures of rules from lenient search paths are logged as
+// warnings and the rules are skipped, while failures of rules from strict search paths are
+// returned.
+func (cc *Context) runBootstraps(ctx context.Context, rules []*selectedRule) error {
+	deps := cc.bootstrapDependencies(rules)
+	if err := checkBootstrapCycles(rules, deps); err != nil {
+		return err
+	}
+
+	if workers := cc.bootstrapWorkers(deps); workers == 1 {
+		for _, i := range bootstrapOrder(deps) {
+			cc.runBootstrap(ctx, rules, deps, i)
+		}
+	} else {
+		cc.runBootstrapsInParallel(ctx, rules, deps, workers)
+	}
+
+	var errs []error
+
+	for _, rule := range rules {
+		if rule.err == nil {
+			continue
+		}
+
+		if rule.lenient {
+			cc.logger.Warn("Skipping rule because its bootstrap failed", "path", rule.path, "error", rule.err)
+
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("failed to run bootstrap script for %s: %w", rule.path, rule.err))
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to run bootstrap scripts: %w", err)
+	}
+
+	return nil
+}
+
+// runBootstrapsInParallel runs up to workers bootstraps at once, each after its dependencies.
+func (cc *Context) runBootstrapsInParallel(ctx context.Context, rules []*selectedRule, deps [][]int, workers int) {
+	done := make([]chan struct{}, len(rules))
+	for i := range rules {
+		done[i] = make(chan struct{})
+	}
+
+	sem := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+
+	for i := range rules {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			for _, j := range deps[i] {
+				<-done[j]
+			}
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			cc.runBootstrap(ctx, rules, deps, i)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// runBootstrap runs the bootstrap of rules[i], whose dependencies have finished, unless one of
+// them failed.
+func (cc *Context) runBootstrap(ctx context.Context, rules []*selectedRule, deps [][]int, i int) {
+	rule := rules[i]
+
+	for _, j := range deps[i] {
+		if rules[j].err != nil {
+			rule.err = fmt.Errorf("%w: %s", ErrBootstrapDependencyFailed, rules[j].frontMatter.Name)
+
+			return
+		}
+	}
+
+	rule.output, rule.err = cc.runBootstrapScript(ctx, rule.path, rule.frontMatter.BootstrapFrontMatter)
+}
+
+// bootstrapOrder returns the indexes of the rules in discovery order, except that each rule
+// comes after its dependencies. deps must not contain a cycle.
+func bootstrapOrder(deps [][]int) []int {
+	order := make([]int, 0, len(deps))
+	added := make([]bool, len(deps))
+
+	var add func(i int)
+	add = func(i int) {
+		if added[i] {
+			return
+		}
+
+		added[i] = true
+
+		for _, j := range deps[i] {
+			add(j)
+		}
+
+		order = append(order, i)
+	}
+
+	for i := range deps {
+		add(i)
+	}
+
+	return order
+}
+
+// bootstrapDependencies resolves each rule's depends_on names to indexes into rules.
+// Dependencies on rules that were not selected are logged and ignored.
+func (cc *Context) bootstrapDependencies(rules []*selectedRule) [][]int {
+	index := make(map[string]int, len(rules))
+
+	for i, rule := range rules {
+		if _, ok := index[rule.frontMatter.Name]; !ok {
+			index[rule.frontMatter.Name] = i
+		}
+	}
+
+	deps := make([][]int, len(rules))
+
+	for i, rule := range rules {
+		for _, name := range rule.frontMatter.DependsOn {
+			j, ok := index[name]
+			if !ok {
+				cc.logger.Warn("Ignoring bootstrap dependency on a rule that was not selected",
+					"path", rule.path, "depends_on", name)
+
+				continue
+			}
+
+			deps[i] = append(deps[i], j)
+		}
+	}
+
+	return deps
+}
+
+// checkBootstrapCycles returns ErrBootstrapCycle, naming the rules involved, if deps contains a cycle.
+func checkBootstrapCycles(rules []*selectedRule, deps [][]int) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make([]int, len(rules))
+
+	var stack []int
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			names := []string{rules[i].frontMatter.Name}
+			for k := len(stack) - 1; stack[k] != i; k-- {
+				names = append([]string{rules[stack[k]].frontMatter.Name}, names...)
+			}
+
+			names = append([]string{rules[i].frontMatter.Name}, names...)
+
+			return fmt.Errorf("%w: %s", ErrBootstrapCycle, strings.Join(names, " -> "))
+		}
+
+		state[i] = visiting
+		stack = append(stack, i)
+
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+
+		return nil
+	}
+
+	for i := range rules {
+		if err := visit(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bootstrapWorkers returns how many bootstraps may run at once. Bootstraps may expect the
+// ones before them to have finished, so they run one at a time unless the rules declare their
+// order with depends_on or the caller sets a concurrency.
+func (cc *Context) bootstrapWorkers(deps [][]int) int {
+	// The lint collector is not safe for concurrent use.
+	if cc.lintMode {
+		return 1
+	}
+
+	if cc.bootstrapJobs > 0 {
+		return cc.bootstrapJobs
+	}
+
+	if slices.ContainsFunc(deps, func(d []int) bool { return len(d) > 0 }) {
+		return runtime.NumCPU()
+	}
+
+	return 1
+}
+
+// isLenientPath reports whether path was found in a lenient search path. When search paths
+// are nested, the innermost one containing path decides; a directory that is both a strict and
+// a lenient search path is strict.
+func (cc *Context) isLenientPath(path string) bool {
+	lenient := false
+	longest := -1
+
+	for _, sp := range cc.downloadedPaths {
+		rel, err := filepath.Rel(sp.Path, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		switch n := len(filepath.Clean(sp.Path)); {
+		case n > longest:
+			longest, lenient = n, sp.Lenient
+		case n == longest:
+			lenient = lenient && sp.Lenient
+		}
+	}
+
+	return lenient
+}
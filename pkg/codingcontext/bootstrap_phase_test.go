@@ -0,0 +1,232 @@
+package codingcontext
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRunBootstraps_DependsOnOrdering(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "order.log")
+
+	createTask(t, dir, "task", "", "Task")
+	// "setup" sleeps so that "build" would finish first if it did not wait for it.
+	createRule(t, dir, ".agents/rules/build.md",
+		shellBootstrap("echo build >> "+log)+"\ndepends_on: [setup]", "Build")
+	createRule(t, dir, ".agents/rules/setup.md",
+		shellBootstrap("sleep 0.2; echo setup >> "+log), "Setup")
+
+	result, err := New(WithSearchPaths(dir)).Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	content, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	if got := strings.Fields(string(content)); strings.Join(got, ",") != "setup,build" {
+		t.Errorf("bootstrap order = %v, want [setup build]", got)
+	}
+
+	// Rules keep their discovery order regardless of bootstrap order.
+	if len(result.Rules) != 2 || result.Rules[0].FrontMatter.Name != "build" {
+		t.Errorf("expected rules in discovery order starting with build, got %d rules", len(result.Rules))
+	}
+}
+
+func TestRunBootstraps_SequentialByDefault(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "order.log")
+
+	createTask(t, dir, "task", "", "Task")
+	// "a" sleeps so that "b" would finish first if they ran at the same time.
+	createRule(t, dir, ".agents/rules/a.md", shellBootstrap("sleep 0.2; echo a >> "+log), "Rule a")
+	createRule(t, dir, ".agents/rules/b.md", shellBootstrap("echo b >> "+log), "Rule b")
+
+	if _, err := New(WithSearchPaths(dir)).Run(context.Background(), "task"); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	content, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+
+	if got := strings.Fields(string(content)); strings.Join(got, ",") != "a,b" {
+		t.Errorf("bootstrap order = %v, want [a b]", got)
+	}
+}
+
+func TestBootstrapOrder(t *testing.T) {
+	t.Parallel()
+
+	// 0 depends on 2, which depends on 1.
+	got := bootstrapOrder([][]int{{2}, nil, {1}, nil})
+	if want := []int{1, 2, 0, 3}; !slices.Equal(got, want) {
+		t.Errorf("bootstrapOrder() = %v, want %v", got, want)
+	}
+}
+
+func TestRunBootstraps_Parallel(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	createTask(t, dir, "task", "", "Task")
+
+	// Each script waits for the other's file, so both can only succeed if they run at the same time.
+	for _, name := range []string{"a", "b"} {
+		other := map[string]string{"a": "b", "b": "a"}[name]
+		script := "touch " + filepath.Join(dir, name) + "; i=0; while [ ! -e " + filepath.Join(dir, other) +
+			" ]; do i=$((i+1)); [ $i -gt 50 ] && exit 1; sleep 0.1; done"
+		createRule(t, dir, ".agents/rules/"+name+".md", shellBootstrap(script), "Rule "+name)
+	}
+
+	if _, err := New(WithSearchPaths(dir), WithBootstrapConcurrency(2)).Run(context.Background(), "task"); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+}
+
+func TestRunBootstraps_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		rules     map[string]string // rule name → frontmatter
+		lenient   bool
+		wantErrs  []error
+		wantText  []string
+		wantRules int
+	}{
+		{
+			name: "cycle is rejected",
+			rules: map[string]string{
+				"a": "bootstrap: exit 0\ndepends_on: [b]",
+				"b": "bootstrap: exit 0\ndepends_on: [a]",
+			},
+			wantErrs: []error{ErrBootstrapCycle},
+		},
+		{
+			name: "failures are aggregated",
+			rules: map[string]string{
+				"a": shellBootstrap("exit 1"),
+				"b": shellBootstrap("exit 2"),
+			},
+			wantText: []string{"a.md", "b.md"},
+		},
+		{
+			name: "dependents of a failed bootstrap do not run",
+			rules: map[string]string{
+				"a": shellBootstrap("exit 1"),
+				"b": shellBootstrap("exit 0") + "\ndepends_on: [a]",
+			},
+			wantErrs: []error{ErrBootstrapDependencyFailed},
+		},
+		{
+			name: "lenient failures skip the rule",
+			rules: map[string]string{
+				"a": shellBootstrap("exit 1"),
+				"b": shellBootstrap("exit 0"),
+			},
+			lenient:   true,
+			wantRules: 1,
+		},
+		{
+			name: "dependency on an unselected rule is ignored",
+			rules: map[string]string{
+				"a": shellBootstrap("exit 0") + "\ndepends_on: [missing]",
+			},
+			wantRules: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "task", "", "Task")
+
+			for name, fm := range tt.rules {
+				createRule(t, dir, ".agents/rules/"+name+".md", fm, "Rule "+name)
+			}
+
+			opt := WithSearchPaths(dir)
+			if tt.lenient {
+				opt = WithLenientSearchPaths(dir)
+			}
+
+			result, err := New(opt).Run(context.Background(), "task")
+
+			if len(tt.wantErrs) == 0 && len(tt.wantText) == 0 {
+				if err != nil {
+					t.Fatalf("Run() error: %v", err)
+				}
+
+				if len(result.Rules) != tt.wantRules {
+					t.Errorf("got %d rules, want %d", len(result.Rules), tt.wantRules)
+				}
+
+				return
+			}
+
+			if err == nil {
+				t.Fatal("expected Run() to fail")
+			}
+
+			for _, want := range tt.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("error %v does not wrap %v", err, want)
+				}
+			}
+
+			for _, want := range tt.wantText {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestIsLenientPath(t *testing.T) {
+	t.Parallel()
+
+	cc := New()
+	cc.downloadedPaths = []SearchPath{
+		{Path: "/home/user"},
+		{Path: "/home/user/shared", Lenient: true},
+		{Path: "/both", Lenient: true},
+		{Path: "/both"},
+		{Path: "/lenient", Lenient: true},
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/home/user/.agents/rules/a.md", want: false},
+		{path: "/home/user/shared/.agents/rules/a.md", want: true},
+		{path: "/home/user/shared-other/a.md", want: false},
+		{path: "/both/.agents/rules/a.md", want: false},
+		{path: "/lenient/.agents/rules/a.md", want: true},
+		{path: "/elsewhere/a.md", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := cc.isLenientPath(tt.path); got != tt.want {
+			t.Errorf("isLenientPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package codingcontext
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// shellBootstrap returns frontmatter for a /bin/sh bootstrap script running body.
+func shellBootstrap(body string) string {
+	return "bootstrap: |\n  #!/bin/sh\n  " + body
+}
+
+func TestBootstrapOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		ruleFM      string
+		ruleContent string
+		taskContent string
+		wantRule    string
+		wantTask    string
+	}{
+		{
+			name:        "param is available in the rule",
+			ruleFM:      shellBootstrap("echo 1.22") + "\nbootstrap_output:\n  param: go_version",
+			ruleContent: "Use Go ${go_version}.",
+			taskContent: "Task",
+			wantRule:    "Use Go 1.22.",
+			wantTask:    "Task",
+		},
+		{
+			name:        "param is available in the task",
+			ruleFM:      shellBootstrap("echo 1.22") + "\nbootstrap_output:\n  param: go_version",
+			ruleContent: "Rule",
+			taskContent: "Upgrade from Go ${go_version}.",
+			wantRule:    "Rule",
+			wantTask:    "Upgrade from Go 1.22.",
+		},
+		{
+			name:        "append adds output to the rule",
+			ruleFM:      shellBootstrap("echo 'main.go'") + "\nbootstrap_output:\n  append: true",
+			ruleContent: "Files:",
+			taskContent: "Task",
+			wantRule:    "Files:\n\nmain.go\n",
+			wantTask:    "Task",
+		},
+		{
+			name:        "appended output is not expanded",
+			ruleFM:      shellBootstrap("echo '${secret}'") + "\nbootstrap_output:\n  append: true",
+			ruleContent: "Output:",
+			taskContent: "Task",
+			wantRule:    "Output:\n\n${secret}\n",
+			wantTask:    "Task",
+		},
+		{
+			name:        "output is truncated at max_bytes",
+			ruleFM:      shellBootstrap("echo 0123456789") + "\nbootstrap_output:\n  param: out\n  max_bytes: 4",
+			ruleContent: "${out}",
+			taskContent: "Task",
+			wantRule:    "0123\n[output truncated]",
+			wantTask:    "Task",
+		},
+		{
+			name:        "without bootstrap_output the param is not defined",
+			ruleFM:      shellBootstrap("echo 1.22"),
+			ruleContent: "Use Go ${go_version}.",
+			taskContent: "Task",
+			wantRule:    "Use Go ${go_version}.",
+			wantTask:    "Task",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "task", "", tt.taskContent)
+			createRule(t, dir, ".agents/rules/rule.md", tt.ruleFM, tt.ruleContent)
+
+			result, err := New(WithSearchPaths(dir)).Run(context.Background(), "task")
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			if len(result.Rules) != 1 {
+				t.Fatalf("expected 1 rule, got %d", len(result.Rules))
+			}
+
+			if got := strings.TrimSpace(result.Rules[0].Content); got != strings.TrimSpace(tt.wantRule) {
+				t.Errorf("rule content = %q, want %q", got, tt.wantRule)
+			}
+
+			if got := strings.TrimSpace(result.Task.Content); got != tt.wantTask {
+				t.Errorf("task content = %q, want %q", got, tt.wantTask)
+			}
+		})
+	}
+}
+
+func TestBootstrapOutput_Command(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "/deploy env=prod\n")
+	createCommand(t, dir, "deploy", "", "Deploy ${env} at ${revision}.")
+	createRule(t, dir, ".agents/rules/rule.md",
+		shellBootstrap("echo abc123")+"\nbootstrap_output:\n  param: revision", "Rule")
+
+	result, err := New(WithSearchPaths(dir)).Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if want := "Deploy prod at abc123."; !strings.Contains(result.Task.Content, want) {
+		t.Errorf("task content = %q, want it to contain %q", result.Task.Content, want)
+	}
+}
+
+func TestBootstrapOutput_ReplayedWhenSkipped(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cacheDir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+
+	createTask(t, dir, "task", "", "Version ${version}")
+	createRule(t, dir, ".agents/rules/rule.md",
+		shellBootstrap("echo ran >> "+marker+"; echo 2.0")+"\nrun_once: true\nbootstrap_output:\n  param: version", "Rule")
+
+	for i := range 2 {
+		result, err := New(WithSearchPaths(dir), WithBootstrapCacheDir(cacheDir)).Run(context.Background(), "task")
+		if err != nil {
+			t.Fatalf("Run() #%d error: %v", i+1, err)
+		}
+
+		if got := strings.TrimSpace(result.Task.Content); got != "Version 2.0" {
+			t.Errorf("Run() #%d task content = %q, want %q", i+1, got, "Version 2.0")
+		}
+	}
+
+	content, err := os.ReadFile(marker)
+	if err != nil {
+		t.Fatalf("failed to read marker: %v", err)
+	}
+
+	if runs := strings.Count(string(content), "ran"); runs != 1 {
+		t.Errorf("bootstrap ran %d times, want 1", runs)
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		limit  int
+		writes []string
+		want   string
+	}{
+		{name: "under the limit", limit: 10, writes: []string{"abc\n"}, want: "abc"},
+		{name: "exactly the limit", limit: 3, writes: []string{"abc"}, want: "abc"},
+		{name: "over the limit", limit: 4, writes: []string{"ab", "cdef"}, want: "abcd\n[output truncated]"},
+		{name: "writes after the limit are dropped", limit: 2, writes: []string{"ab", "c", "d"}, want: "ab\n[output truncated]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			b := newCappedBuffer(tt.limit)
+
+			for _, w := range tt.writes {
+				if n, err := b.Write([]byte(w)); err != nil || n != len(w) {
+					t.Fatalf("Write(%q) = %d, %v; want %d, nil", w, n, err, len(w))
+				}
+			}
+
+			if got := b.result(New(), "rule.md"); got != tt.want {
+				t.Errorf("result() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBootstrap_TasksAndSkills(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, dir string)
+		lenient    bool
+		wantErr    bool
+		wantSkills int
+		wantPrompt string
+	}{
+		{
+			name: "task bootstrap runs before rule bootstraps",
+			setup: func(t *testing.T, dir string) {
+				t.Helper()
+				createTask(t, dir, "task", shellBootstrap("touch "+filepath.Join(dir, "branch")), "Task")
+				createRule(t, dir, ".agents/rules/rule.md", shellBootstrap("test -e "+filepath.Join(dir, "branch")), "Rule")
+			},
+			wantPrompt: "Rule",
+		},
+		{
+			name: "task companion bootstrap file runs",
+			setup: func(t *testing.T, dir string) {
+				t.Helper()
+				createTask(t, dir, "task", "", "Task")
+				createBootstrapScript(t, dir, ".agents/tasks/task.md", "#!/bin/sh\nexit 1\n")
+			},
+			wantErr: true,
+		},
+		{
+			name: "task bootstrap output is available to rules and appended to the task",
+			setup: func(t *testing.T, dir string) {
+				t.Helper()
+				createTask(t, dir, "task",
+					shellBootstrap("echo feature-x")+"\nbootstrap_output:\n  param: branch\n  append: true", "Task")
+				createRule(t, dir, ".agents/rules/rule.md", "", "On ${branch}.")
+			},
+			wantPrompt: "On feature-x.\nTask\n\nfeature-x",
+		},
+		{
+			name: "skill bootstrap runs when the skill is discovered",
+			setup: func(t *testing.T, dir string) {
+				t.Helper()
+				createTask(t, dir, "task", "", "Using ${pdf_tool}.")
+				createSkill(t, dir, ".agents/skills/pdf", "---\nname: pdf\ndescription: PDF processing\n"+
+					shellBootstrap("echo pdftotext")+"\nbootstrap_output:\n  param: pdf_tool\n---\nBody")
+			},
+			wantSkills: 1,
+			wantPrompt: "Using pdftotext.",
+		},
+		{
+			name: "failed skill companion bootstrap is fatal in strict paths",
+			setup: func(t *testing.T, dir string) {
+				t.Helper()
+				createTask(t, dir, "task", "", "Task")
+				createSkill(t, dir, ".agents/skills/pdf", "---\nname: pdf\ndescription: PDF processing\n---\nBody")
+				createBootstrapScript(t, dir, ".agents/skills/pdf/SKILL.md", "#!/bin/sh\nexit 1\n")
+			},
+			wantErr: true,
+		},
+		{
+			name: "failed skill bootstrap skips the skill in lenient paths",
+			setup: func(t *testing.T, dir string) {
+				t.Helper()
+				createTask(t, dir, "task", "", "Task")
+				createSkill(t, dir, ".agents/skills/pdf", "---\nname: pdf\ndescription: PDF processing\n---\nBody")
+				createBootstrapScript(t, dir, ".agents/skills/pdf/SKILL.md", "#!/bin/sh\nexit 1\n")
+			},
+			lenient:    true,
+			wantSkills: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			tt.setup(t, dir)
+
+			opt := WithSearchPaths(dir)
+			if tt.lenient {
+				opt = WithLenientSearchPaths(dir)
+			}
+
+			result, err := New(opt).Run(context.Background(), "task")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected Run() to fail")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			if len(result.Skills.Skills) != tt.wantSkills {
+				t.Errorf("got %d skills, want %d", len(result.Skills.Skills), tt.wantSkills)
+			}
+
+			if !strings.Contains(result.Prompt, tt.wantPrompt) {
+				t.Errorf("prompt %q does not contain %q", result.Prompt, tt.wantPrompt)
+			}
+		})
+	}
+}
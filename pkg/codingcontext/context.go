@@ -8,12 +8,14 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-getter/v2"
 	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
@@ -47,6 +49,12 @@ var (
 	ErrInvalidTaskNameBase = errors.New("task base name must not be empty")
 	// ErrInvalidTaskNameDepth is returned when the task name has more than one level of namespacing.
 	ErrInvalidTaskNameDepth = errors.New("only one level of namespacing is supported (expected \"namespace/task\")")
+
+	// ErrBootstrapCycle is returned when depends_on between rules forms a cycle.
+	ErrBootstrapCycle = errors.New("bootstrap dependency cycle")
+	// ErrBootstrapDependencyFailed is returned for a bootstrap that did not run because
+	// the bootstrap of a rule it depends on failed.
+	ErrBootstrapDependencyFailed = errors.New("bootstrap dependency failed")
 )
 
 const (
@@ -54,15 +62,23 @@ const (
 	splitLimit         = 3
 )
 
-// Context holds the configuration and state for assembling coding context.
 // SearchPath represents a search path with an optional lenient flag.
 // When Lenient is true, errors encountered while processing files from this path
 // are logged as warnings and skipped rather than treated as fatal errors.
+// When FS is set, files are read from FS instead of the local file system, and Path
+// is the name under which FS's files appear in logs, results, and lint output.
 type SearchPath struct {
 	Path    string
 	Lenient bool
+	FS      fs.FS
 }
 
+// Context holds the configuration for assembling coding context. A Context is not changed
+// by running it: each Run, Lint, or ListTasks works on its own session (a copy holding that
+// run's state), so a Context can be run again, or concurrently from multiple goroutines.
+// Runs share parsed files. Each run downloads remote search paths and removes them when it
+// finishes, unless the Context is a session (see WithSession), whose runs share the downloads
+// until Close.
 type Context struct {
 	params           taskparser.Params
 	includes         selectors.Selectors
@@ -70,21 +86,44 @@ type Context struct {
 	searchPaths      []SearchPath
 	downloadedPaths  []SearchPath
 	task             markdown.Markdown[markdown.TaskFrontMatter]   // Parsed task
+	taskPath         string                                        // Path of the task file
+	taskBlocks       taskparser.Task                               // Task blocks, built into task content by buildTask
+	commands         map[string]*command                           // Commands by name; nil entries are missing commands
+	capturedParams   taskparser.Params                             // Parameters captured from bootstrap output
+	taskOutput       string                                        // Output captured from the task's bootstrap
 	rules            []markdown.Markdown[markdown.RuleFrontMatter] // Collected rule files
-	skills           skills.AvailableSkills                        // Discovered skills (metadata only)
+	skills           skills.AvailableSkills                        // Discovered skills
+	inlineSkills     []string                                      // Names of skills whose full content is inlined
+	skillsListing    SkillsListing                                 // How available skills are listed in the prompt
 	totalTokens      int
 	logger           *slog.Logger
 	cmdRunner        func(cmd *exec.Cmd) error
+	sandbox          BootstrapSandbox // Isolation applied to bootstrap scripts
+	bootstrapCache   string           // Directory holding run_once markers; defaults to the user cache dir
+	forceBootstrap   bool             // Run bootstrap scripts even when run_once/creates would skip them
+	bootstrapJobs    int              // Maximum bootstraps run at once; zero means 1, or the CPUs with depends_on
 	resume           bool
 	doBootstrap      bool // Controls whether to discover rules, skills, and run bootstrap scripts
 	includeByDefault bool // Controls whether unmatched rules/skills are included by default
 	agent            Agent
 	lenientAgent     bool   // When true, agent-specific paths are treated as lenient
+	agentSkills      bool   // When true, skills from the agent's own skills path are included
+	excludeOwnRules  bool   // When true, rules from the agent's own paths are left out
 	agentSetCount    int    // Incremented by WithAgent and WithLenientAgent; >1 means conflict
 	namespace        string // Active namespace derived from task name (e.g. "myteam" from "myteam/fix-bug")
 	userPrompt       string // User-provided prompt to append to task
+	literalPrompt    bool   // When true, the user prompt is appended as-is after expansion
 	lintMode         bool
 	lintCollector    *lintCollector
+	explainCollector *explainCollector
+	hooks            []Hook        // Called at each stage of assembly
+	sources          []Source      // Run once the task is found, for rules and skills
+	shared           *sharedState  // Search paths and parsed files shared by every run
+	keepSession      bool          // Set by WithSession: runs share downloads until Close
+	refresh          time.Duration // Set by WithRefresh: how long a session reuses its downloads
+	usingDownloads   bool          // The run holds the session's downloads, released by endRun
+	runSearchPaths   []SearchPath  // Search paths downloaded for this run only, removed by endRun
+	runSourceDir     string        // The run's source directory, which endRun lets be removed
 }
 
 // parseNamespacedTaskName splits a task name into its optional namespace and base name.
@@ -123,6 +162,7 @@ func New(opts ...Option) *Context {
 		logger:      slog.New(slog.NewTextHandler(os.Stderr, nil)),
 		doBootstrap:      true, // Default to true for backward compatibility
 		includeByDefault: true, // Default to true for backward compatibility
+		shared:           newSharedState(),
 		cmdRunner: func(cmd *exec.Cmd) error {
 			return cmd.Run()
 		},
@@ -144,29 +184,34 @@ func nameFromPath(path string) string {
 
 type markdownVisitor func(path string, fm *markdown.BaseFrontMatter) error
 
+// markdownCandidates describes the files a visit of markdown files looks for, so that
+// files left out by the selectors can be explained: those of kind, and named name if set.
+type markdownCandidates struct {
+	kind LoadedFileKind
+	name string
+}
+
 // Run executes the context assembly for the given taskName and returns the assembled result.
 // The taskName is looked up in task search paths and its content is parsed into blocks.
 // If the taskName cannot be found as a task file, an error is returned.
+// Run is safe for concurrent use.
 func (cc *Context) Run(ctx context.Context, taskName string) (*Result, error) {
-	if cc.agentSetCount > 1 {
-		return nil, ErrMultipleAgents
-	}
+	s := cc.session()
+	defer s.endRun()
 
-	// Parse manifest file first to get additional search paths
-	manifestPaths, err := cc.parseManifestFile(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse manifest file: %w", err)
-	}
+	return s.run(ctx, taskName)
+}
 
-	for _, p := range manifestPaths {
-		cc.searchPaths = append(cc.searchPaths, SearchPath{Path: p})
+// run assembles the context for taskName on a session returned by session.
+func (cc *Context) run(ctx context.Context, taskName string) (*Result, error) {
+	if cc.agentSetCount > 1 {
+		return nil, ErrMultipleAgents
 	}
 
-	// Download all remote directories (including those from manifest)
-	if err := cc.downloadRemoteDirectories(ctx); err != nil {
-		return nil, fmt.Errorf("failed to download remote directories: %w", err)
+	// Resolve the search paths, including those from the manifest, downloading remote ones
+	if err := cc.useSearchPaths(ctx); err != nil {
+		return nil, err
 	}
-	defer cc.cleanupDownloadedDirectories()
 
 	// If resume mode is enabled, add resume=true as a selector
 	if cc.resume {
@@ -174,7 +219,7 @@ func (cc *Context) Run(ctx context.Context, taskName string) (*Result, error) {
 	}
 
 	// Get the task by name
-	if err := cc.findTask(taskName); err != nil {
+	if err := cc.findTask(ctx, taskName); err != nil {
 		return nil, fmt.Errorf("task not found: %w", err)
 	}
 
@@ -183,15 +228,28 @@ func (cc *Context) Run(ctx context.Context, taskName string) (*Result, error) {
 	cc.logger.Info("Parameters", "params", cc.params.String())
 	cc.logger.Info("Selectors", "selectors", cc.includes.String())
 
+	if err := cc.runTaskBootstrap(ctx); err != nil {
+		return nil, fmt.Errorf("failed to run task bootstrap: %w", err)
+	}
+
+	if err := cc.runSources(ctx); err != nil {
+		return nil, fmt.Errorf("failed to run sources: %w", err)
+	}
+
 	if err := cc.findExecuteRuleFiles(ctx); err != nil {
 		return nil, fmt.Errorf("failed to find and execute rule files: %w", err)
 	}
 
 	// Discover skills (load metadata only for progressive disclosure)
-	if err := cc.discoverSkills(); err != nil {
+	if err := cc.discoverSkills(ctx); err != nil {
 		return nil, fmt.Errorf("failed to discover skills: %w", err)
 	}
 
+	// Build the task content last so that it can use parameters captured from bootstrap output
+	if err := cc.buildTask(ctx, taskName); err != nil {
+		return nil, fmt.Errorf("failed to build task: %w", err)
+	}
+
 	// Estimate tokens for task
 	cc.logger.Info("Total estimated tokens", "tokens", cc.totalTokens)
 
@@ -203,24 +261,12 @@ func (cc *Context) Run(ctx context.Context, taskName string) (*Result, error) {
 	}
 
 	// Add skills section if there are any skills
-	if len(cc.skills.Skills) > 0 {
-		promptBuilder.WriteString("\n# Skills\n\n")
-		promptBuilder.WriteString("You have access to the following skills. Skills are specialized capabilities ")
-		promptBuilder.WriteString("that provide ")
-		promptBuilder.WriteString("domain expertise, workflows, and procedural knowledge. When a task matches a skill's ")
-		promptBuilder.WriteString("description, you can load the full skill content by reading the SKILL.md file at the ")
-		promptBuilder.WriteString("location provided.\n\n")
-
-		skillsXML, err := cc.skills.AsXML()
-		if err != nil {
-			return nil, fmt.Errorf("failed to encode skills as XML: %w", err)
-		}
-
-		promptBuilder.WriteString(skillsXML)
-		promptBuilder.WriteString("\n\n")
+	if err := cc.writeSkills(&promptBuilder); err != nil {
+		return nil, err
 	}
 
 	promptBuilder.WriteString(cc.task.Content)
+	prompt := promptBuilder.String()
 
 	// Build and return the result
 	result := &Result{
@@ -231,13 +277,49 @@ func (cc *Context) Run(ctx context.Context, taskName string) (*Result, error) {
 		Skills:    cc.skills,
 		Tokens:    cc.totalTokens,
 		Agent:     cc.agent,
-		Prompt:    promptBuilder.String(),
+		Prompt:    prompt,
+	}
+
+	err := cc.runHooks("prompt built", "", func(h Hook) error { return h.PromptBuilt(ctx, result) })
+	if err != nil {
+		return nil, err
+	}
+
+	// Count what hooks added to or removed from the prompt.
+	if result.Prompt != prompt {
+		result.Tokens = cc.totalTokens + tokencount.EstimateTokens(result.Prompt) - tokencount.EstimateTokens(prompt)
 	}
 
 	return result, nil
 }
 
-func (cc *Context) visitMarkdownFiles(searchDirFn func(path string) []string, visitor markdownVisitor) error {
+// useSearchPaths resolves the search paths of the run (see resolveSearchPaths) and lets
+// hooks change them.
+func (cc *Context) useSearchPaths(ctx context.Context) error {
+	paths, err := cc.resolveSearchPaths(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = cc.runHooks("search paths resolved", "", func(h Hook) error {
+		var err error
+
+		paths, err = h.SearchPathsResolved(ctx, paths)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	cc.downloadedPaths = paths
+
+	return nil
+}
+
+func (cc *Context) visitMarkdownFiles(
+	searchDirFn func(path string) []string, candidates markdownCandidates, visitor markdownVisitor,
+) error {
 	type searchDir struct {
 		path    string
 		lenient bool
@@ -252,7 +334,7 @@ func (cc *Context) visitMarkdownFiles(searchDirFn func(path string) []string, vi
 	}
 
 	for _, dir := range searchDirs {
-		if err := cc.visitMarkdownInDir(dir.path, visitor); err != nil {
+		if err := cc.visitMarkdownInDir(dir.path, candidates, visitor); err != nil {
 			if dir.lenient {
 				cc.logger.Warn("skipping directory", "path", dir.path, "error", err)
 
@@ -266,33 +348,33 @@ func (cc *Context) visitMarkdownFiles(searchDirFn func(path string) []string, vi
 	return nil
 }
 
-func (cc *Context) visitMarkdownInDir(dir string, visitor markdownVisitor) error {
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
+func (cc *Context) visitMarkdownInDir(dir string, candidates markdownCandidates, visitor markdownVisitor) error {
+	if _, err := cc.stat(dir); errors.Is(err, fs.ErrNotExist) {
 		return nil
 	} else if err != nil {
 		return fmt.Errorf("failed to stat directory %s: %w", dir, err)
 	}
 
-	if err := filepath.Walk(dir, cc.makeMarkdownWalkFunc(visitor)); err != nil {
+	if err := cc.walkDir(dir, cc.makeMarkdownWalkFunc(candidates, visitor)); err != nil {
 		return fmt.Errorf("failed to walk directory %s: %w", dir, err)
 	}
 
 	return nil
 }
 
-func (cc *Context) makeMarkdownWalkFunc(visitor markdownVisitor) filepath.WalkFunc {
-	return func(path string, info os.FileInfo, err error) error {
+func (cc *Context) makeMarkdownWalkFunc(candidates markdownCandidates, visitor markdownVisitor) fs.WalkDirFunc {
+	return func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return fmt.Errorf("failed to walk path %s: %w", path, err)
 		}
 
 		ext := filepath.Ext(path) // .md or .mdc
-		if info.IsDir() || (ext != ".md" && ext != ".mdc") {
+		if d.IsDir() || (ext != ".md" && ext != ".mdc") {
 			return nil
 		}
 
 		var fm markdown.BaseFrontMatter
-		if _, parseErr := markdown.ParseMarkdownFile(path, &fm); parseErr != nil {
+		if _, parseErr := parseMarkdownFile(cc, path, &fm); parseErr != nil {
 			if cc.lintCollector != nil {
 				var pe *markdown.ParseError
 				if errors.As(parseErr, &pe) {
@@ -315,6 +397,10 @@ func (cc *Context) makeMarkdownWalkFunc(visitor markdownVisitor) filepath.WalkFu
 				cc.logger.Info("Skipping file", "path", path, "reason", reason)
 			}
 
+			if candidates.name == "" || nameFromPath(path) == candidates.name {
+				cc.explainDecision(candidates.kind, path, false, reason, 0)
+			}
+
 			return nil
 		}
 
@@ -323,7 +409,7 @@ func (cc *Context) makeMarkdownWalkFunc(visitor markdownVisitor) filepath.WalkFu
 }
 
 // findTask searches for a task markdown file and returns it with parameters substituted.
-func (cc *Context) findTask(taskName string) error {
+func (cc *Context) findTask(ctx context.Context, taskName string) error {
 	namespace, baseName, err := parseNamespacedTaskName(taskName)
 	if err != nil {
 		return err
@@ -347,19 +433,15 @@ func (cc *Context) findTask(taskName string) error {
 	// the selector matching logic (their value won't be in {"": true}).
 	cc.includes.SetValue("namespace", namespace)
 
-	taskFound := false
+	var taskPath string
 
 	namespacedPaths := func(dir string) []string {
 		return namespacedTaskSearchPaths(dir, namespace)
 	}
 
-	err = cc.visitMarkdownFiles(namespacedPaths, func(path string, _ *markdown.BaseFrontMatter) error {
-		// Stop after the first matching file so that a namespace task takes
-		// precedence over a global task with the same base name.
-		if taskFound {
-			return nil
-		}
+	taskFiles := markdownCandidates{kind: LoadedFileKindTask, name: baseName}
 
+	err = cc.visitMarkdownFiles(namespacedPaths, taskFiles, func(path string, _ *markdown.BaseFrontMatter) error {
 		base := filepath.Base(path)
 		ext := filepath.Ext(base)
 
@@ -367,15 +449,25 @@ func (cc *Context) findTask(taskName string) error {
 			return nil
 		}
 
-		taskFound = true
+		// Stop after the first matching file so that a namespace task takes
+		// precedence over a global task with the same base name.
+		if taskPath != "" {
+			cc.explainDecision(LoadedFileKindTask, path, false, "shadowed by "+taskPath, 0)
+
+			return nil
+		}
+
+		taskPath = path
 
-		return cc.loadTask(path, taskName)
+		cc.explainDecision(LoadedFileKindTask, path, true, fmt.Sprintf("task name matches '%s'", taskName), 0)
+
+		return cc.loadTask(ctx, path, taskName)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to find task: %w", err)
 	}
 
-	if !taskFound {
+	if taskPath == "" {
 		return fmt.Errorf("%w: %s", ErrTaskNotFound, taskName)
 	}
 
@@ -383,10 +475,10 @@ func (cc *Context) findTask(taskName string) error {
 }
 
 // loadTask parses and processes a task file, populating cc.task.
-func (cc *Context) loadTask(path, taskName string) error {
+func (cc *Context) loadTask(ctx context.Context, path, taskName string) error {
 	var frontMatter markdown.TaskFrontMatter
 
-	md, err := markdown.ParseMarkdownFile(path, &frontMatter)
+	md, err := parseMarkdownFile(cc, path, &frontMatter)
 	if err != nil {
 		return fmt.Errorf("failed to parse task file %s: %w", path, err)
 	}
@@ -399,6 +491,15 @@ func (cc *Context) loadTask(path, taskName string) error {
 		frontMatter.Name = nameFromPath(path)
 	}
 
+	md.FrontMatter = frontMatter
+	parsedContent := md.Content
+
+	if err := cc.runHooks("task found", path, func(h Hook) error { return h.TaskFound(ctx, path, &md) }); err != nil {
+		return err
+	}
+
+	frontMatter = md.FrontMatter
+
 	// Extract selector labels from task frontmatter and add them to cc.includes.
 	// This combines CLI selectors (from -s flag) with task selectors using OR logic:
 	// rules match if their frontmatter value matches ANY selector value for a given key.
@@ -420,11 +521,15 @@ func (cc *Context) loadTask(path, taskName string) error {
 	}
 
 	// Use the task already parsed by the goldmark extension in ParseMarkdownFile.
-	// If a user prompt was appended the content changed, so re-parse the combined string
-	// to pick up any slash commands in the appended prompt.
+	// If a user prompt was appended or a hook changed the content, re-parse the combined
+	// string to pick up any slash commands in the new content. A literal user prompt is
+	// left out here and appended by buildTask instead.
 	task := md.Task
-	if cc.userPrompt != "" {
-		taskContent := cc.appendUserPrompt(md.Content)
+	if (cc.userPrompt != "" && !cc.literalPrompt) || md.Content != parsedContent {
+		taskContent := md.Content
+		if !cc.literalPrompt {
+			taskContent = cc.appendUserPrompt(taskContent)
+		}
 
 		var parseErr error
 
@@ -434,14 +539,38 @@ func (cc *Context) loadTask(path, taskName string) error {
 		}
 	}
 
-	finalContent, err := cc.buildFinalContent(task, path, frontMatter.ExpandParams)
+	// Resolve slash commands now so that their selectors apply to rule discovery.
+	// The task content is built by buildTask once bootstrap output has been captured.
+	if err := cc.resolveCommands(task); err != nil {
+		return err
+	}
+
+	cc.task = markdown.Markdown[markdown.TaskFrontMatter]{FrontMatter: frontMatter}
+	cc.taskPath = path
+	cc.taskBlocks = task
+
+	return nil
+}
+
+// buildTask builds the content of the task loaded by loadTask, expanding parameters
+// (including those captured from bootstrap output) and slash commands.
+func (cc *Context) buildTask(ctx context.Context, taskName string) error {
+	finalContent, err := cc.buildFinalContent(ctx, cc.taskBlocks, cc.taskPath, cc.task.FrontMatter.ExpandParams)
 	if err != nil {
 		return err
 	}
 
-	cc.task = markdown.FromContent(frontMatter, finalContent)
+	if cc.literalPrompt {
+		finalContent = cc.appendUserPrompt(finalContent)
+	}
+
+	finalContent = appendBootstrapOutput(finalContent, cc.task.FrontMatter.BootstrapOutput, cc.taskOutput)
+
+	cc.task = markdown.FromContent(cc.task.FrontMatter, finalContent)
 	cc.totalTokens += cc.task.Tokens
 
+	cc.explainTokens(LoadedFileKindTask, cc.taskPath, cc.task.Tokens)
+
 	cc.logger.Info("Including task", "name", taskName,
 		"reason", fmt.Sprintf("task name matches '%s'", taskName), "tokens", cc.task.Tokens)
 
@@ -464,11 +593,13 @@ func (cc *Context) appendUserPrompt(content string) string {
 }
 
 // buildFinalContent processes each block of a pre-parsed task into a final string.
-func (cc *Context) buildFinalContent(task taskparser.Task, path string, expandParams *bool) (string, error) {
+func (cc *Context) buildFinalContent(
+	ctx context.Context, task taskparser.Task, path string, expandParams *bool,
+) (string, error) {
 	var finalContent strings.Builder
 
 	for _, block := range task {
-		blockContent, err := cc.processTaskBlock(block, path, expandParams)
+		blockContent, err := cc.processTaskBlock(ctx, block, path, expandParams)
 		if err != nil {
 			return "", err
 		}
@@ -480,14 +611,16 @@ func (cc *Context) buildFinalContent(task taskparser.Task, path string, expandPa
 }
 
 // processTaskBlock processes a single task block (text or slash command) and returns its content.
-func (cc *Context) processTaskBlock(block taskparser.Block, path string, expandParams *bool) (string, error) {
+func (cc *Context) processTaskBlock(
+	ctx context.Context, block taskparser.Block, path string, expandParams *bool,
+) (string, error) {
 	if block.Text != nil {
 		textContent := block.Text.Content()
 
 		if shouldExpandParams(expandParams) {
 			var err error
 
-			textContent, err = cc.expandParams(textContent, nil)
+			textContent, err = cc.expandParams(path, textContent, cc.capturedParams)
 			if err != nil {
 				return "", fmt.Errorf("failed to expand parameters in task file %s: %w", path, err)
 			}
@@ -497,7 +630,7 @@ func (cc *Context) processTaskBlock(block taskparser.Block, path string, expandP
 	}
 
 	if block.SlashCommand != nil {
-		commandContent, err := cc.findCommand(block.SlashCommand.Name, block.SlashCommand.Params())
+		command, err := cc.findCommand(block.SlashCommand.Name)
 		if err != nil {
 			if errors.Is(err, ErrCommandNotFound) {
 				if cc.lintMode {
@@ -514,32 +647,90 @@ func (cc *Context) processTaskBlock(block taskparser.Block, path string, expandP
 			return "", fmt.Errorf("failed to find command %s: %w", block.SlashCommand.Name, err)
 		}
 
-		return commandContent, nil
+		return cc.expandCommand(ctx, command, block.SlashCommand.Params())
 	}
 
 	return "", nil
 }
 
-// findCommand searches for a command markdown file and returns its content.
-// Commands now support optional frontmatter with the expand field and selectors.
+// command is a parsed command file, cached so that each command is located and parsed once.
+type command struct {
+	path        string
+	frontMatter markdown.CommandFrontMatter
+	content     string
+}
+
+// resolveCommands locates the command file for every slash command in task, merging
+// their selectors into cc.includes. Missing commands are reported when the task is built.
+func (cc *Context) resolveCommands(task taskparser.Task) error {
+	for _, block := range task {
+		if block.SlashCommand == nil {
+			continue
+		}
+
+		_, err := cc.findCommand(block.SlashCommand.Name)
+		if err != nil && !errors.Is(err, ErrCommandNotFound) {
+			return fmt.Errorf("failed to find command %s: %w", block.SlashCommand.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// expandCommand returns the content of a command with the slash command's parameters substituted.
 // Parameters are substituted by default (when expand is nil or true).
 // Substitution is skipped only when expand is explicitly set to false.
+func (cc *Context) expandCommand(ctx context.Context, cmd *command, params taskparser.Params) (string, error) {
+	content := cmd.content
+
+	if shouldExpandParams(cmd.frontMatter.ExpandParams) {
+		merged := make(taskparser.Params, len(cc.capturedParams)+len(params))
+		maps.Copy(merged, cc.capturedParams)
+		maps.Copy(merged, params)
+
+		var err error
+
+		content, err = cc.expandParams(cmd.path, cmd.content, merged)
+		if err != nil {
+			return "", fmt.Errorf("failed to expand parameters in command file %s: %w", cmd.path, err)
+		}
+	}
+
+	expanded := markdown.FromContent(cmd.frontMatter, content)
+
+	err := cc.runHooks("command expanded", cmd.path, func(h Hook) error {
+		return h.CommandExpanded(ctx, cmd.path, &expanded)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return expanded.Content, nil
+}
+
+// findCommand searches for a command markdown file and returns it.
+// Commands support optional frontmatter with the expand field and selectors.
 // If the command has selectors in its frontmatter, they are merged into cc.includes
 // to allow commands to specify which rules they need.
-func (cc *Context) findCommand(commandName string, params taskparser.Params) (string, error) {
-	var content *string
+// Results, including missing commands, are cached for the rest of the run.
+func (cc *Context) findCommand(commandName string) (*command, error) {
+	if found, ok := cc.commands[commandName]; ok {
+		if found == nil {
+			return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, commandName)
+		}
+
+		return found, nil
+	}
+
+	var found *command
 
 	namespacedCmdPaths := func(dir string) []string {
 		return namespacedCommandSearchPaths(dir, cc.namespace)
 	}
 
-	err := cc.visitMarkdownFiles(namespacedCmdPaths, func(path string, _ *markdown.BaseFrontMatter) error {
-		// Stop after the first matching command so that a namespace command takes
-		// precedence over a global command with the same name.
-		if content != nil {
-			return nil
-		}
+	commandFiles := markdownCandidates{kind: LoadedFileKindCommand, name: commandName}
 
+	err := cc.visitMarkdownFiles(namespacedCmdPaths, commandFiles, func(path string, _ *markdown.BaseFrontMatter) error {
 		baseName := filepath.Base(path)
 
 		ext := filepath.Ext(baseName)
@@ -547,9 +738,17 @@ func (cc *Context) findCommand(commandName string, params taskparser.Params) (st
 			return nil
 		}
 
+		// Stop after the first matching command so that a namespace command takes
+		// precedence over a global command with the same name.
+		if found != nil {
+			cc.explainDecision(LoadedFileKindCommand, path, false, "shadowed by "+found.path, 0)
+
+			return nil
+		}
+
 		var frontMatter markdown.CommandFrontMatter
 
-		md, err := markdown.ParseMarkdownFile(path, &frontMatter)
+		md, err := parseMarkdownFile(cc, path, &frontMatter)
 		if err != nil {
 			return fmt.Errorf("failed to parse command file %s: %w", path, err)
 		}
@@ -567,33 +766,29 @@ func (cc *Context) findCommand(commandName string, params taskparser.Params) (st
 		// rules match if their frontmatter value matches ANY selector value for a given key.
 		cc.mergeSelectors(frontMatter.Selectors)
 
-		// Expand parameters only if expand is not explicitly set to false
-		var processedContent string
-		if shouldExpandParams(frontMatter.ExpandParams) {
-			processedContent, err = cc.expandParams(md.Content, params)
-			if err != nil {
-				return fmt.Errorf("failed to expand parameters in command file %s: %w", path, err)
-			}
-		} else {
-			processedContent = md.Content
-		}
-
-		content = &processedContent
+		found = &command{path: path, frontMatter: frontMatter, content: md.Content}
 
-		cc.logger.Info("Including command", "name", commandName,
-			"reason", fmt.Sprintf("referenced by slash command '/%s'", commandName), "path", path)
+		reason := fmt.Sprintf("referenced by slash command '/%s'", commandName)
+		cc.logger.Info("Including command", "name", commandName, "reason", reason, "path", path)
+		cc.explainDecision(LoadedFileKindCommand, path, true, reason, md.Tokens)
 
 		return nil
 	})
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if cc.commands == nil {
+		cc.commands = make(map[string]*command)
 	}
 
-	if content == nil {
-		return "", fmt.Errorf("%w: %s", ErrCommandNotFound, commandName)
+	cc.commands[commandName] = found
+
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrCommandNotFound, commandName)
 	}
 
-	return *content, nil
+	return found, nil
 }
 
 // mergeSelectors adds selectors from a map into cc.includes.
@@ -617,9 +812,10 @@ func (cc *Context) mergeSelectors(selectors map[string]any) {
 // - Command expansion: !`command`
 // - Path expansion: @path
 // If params is provided, it is merged with cc.params (with params taking precedence).
-func (cc *Context) expandParams(content string, params taskparser.Params) (string, error) {
+// Content from the file at path in an fs.FS reads its @path references from that fs.FS.
+func (cc *Context) expandParams(path, content string, params taskparser.Params) (string, error) {
 	if cc.lintMode {
-		return cc.expandParamsLint(content, params)
+		return cc.expandParamsLint(path, content, params)
 	}
 
 	// Merge params with cc.params
@@ -628,7 +824,9 @@ func (cc *Context) expandParams(content string, params taskparser.Params) (strin
 	maps.Copy(mergedParams, params)
 
 	// Use the expand function to handle all expansion types
-	expanded, err := mergedParams.Expand(content)
+	sp, _, _ := cc.searchFS(path)
+
+	expanded, err := mergedParams.ExpandWith(content, taskparser.ExpandOptions{FS: sp.FS})
 	if err != nil {
 		return "", fmt.Errorf("failed to expand parameters: %w", err)
 	}
@@ -638,16 +836,19 @@ func (cc *Context) expandParams(content string, params taskparser.Params) (strin
 
 // expandParamsLint is a lint-mode variant of expandParams that skips shell command
 // execution and tracks @path file references in the lint collector.
-func (cc *Context) expandParamsLint(content string, params taskparser.Params) (string, error) {
+func (cc *Context) expandParamsLint(path, content string, params taskparser.Params) (string, error) {
 	mergedParams := make(taskparser.Params)
 	maps.Copy(mergedParams, cc.params)
 	maps.Copy(mergedParams, params)
 
 	var pathRefs []string
 
+	sp, _, inFS := cc.searchFS(path)
+
 	expanded, err := mergedParams.ExpandWith(content, taskparser.ExpandOptions{
 		SkipCommands: true,
 		PathRefs:     &pathRefs,
+		FS:           sp.FS,
 	})
 	if err != nil {
 		return "", fmt.Errorf("failed to expand parameters: %w", err)
@@ -655,6 +856,10 @@ func (cc *Context) expandParamsLint(content string, params taskparser.Params) (s
 
 	if cc.lintCollector != nil {
 		for _, ref := range pathRefs {
+			if inFS {
+				ref = filepath.Join(sp.Path, filepath.FromSlash(ref))
+			}
+
 			cc.lintCollector.recordFile(ref, LoadedFileKindPathRef)
 		}
 	}
@@ -756,13 +961,26 @@ func (cc *Context) parseManifestFile(ctx context.Context) ([]string, error) {
 	return paths, nil
 }
 
-func (cc *Context) downloadRemoteDirectories(ctx context.Context) error {
-	for _, sp := range cc.searchPaths {
+// downloadRemoteDirectories returns the local directories of the search paths, downloading
+// remote ones. Lenient remote paths that fail to download are left out.
+func (cc *Context) downloadRemoteDirectories(ctx context.Context, searchPaths []SearchPath) ([]SearchPath, error) {
+	downloadedPaths := make([]SearchPath, 0, len(searchPaths))
+
+	for _, sp := range searchPaths {
 		// If the path is local, use it directly without downloading
+		// Files of an fs.FS are read in place
+		if sp.FS != nil {
+			name := filepath.Clean(sp.Path)
+			cc.logger.Info("Using file system", "name", name)
+			downloadedPaths = append(downloadedPaths, SearchPath{Path: name, Lenient: sp.Lenient, FS: sp.FS})
+
+			continue
+		}
+
 		if isLocalPath(sp.Path) {
 			localPath := normalizeLocalPath(sp.Path)
 			cc.logger.Info("Using local directory", "path", localPath)
-			cc.downloadedPaths = append(cc.downloadedPaths, SearchPath{Path: localPath, Lenient: sp.Lenient})
+			downloadedPaths = append(downloadedPaths, SearchPath{Path: localPath, Lenient: sp.Lenient})
 
 			continue
 		}
@@ -778,29 +996,14 @@ func (cc *Context) downloadRemoteDirectories(ctx context.Context) error {
 				continue
 			}
 
-			return fmt.Errorf("failed to download remote directory %s: %w", sp.Path, err)
+			return nil, fmt.Errorf("failed to download remote directory %s: %w", sp.Path, err)
 		}
 
 		cc.logger.Info("Downloaded to", "path", dst)
-		cc.downloadedPaths = append(cc.downloadedPaths, SearchPath{Path: dst, Lenient: sp.Lenient})
+		downloadedPaths = append(downloadedPaths, SearchPath{Path: dst, Lenient: sp.Lenient})
 	}
 
-	return nil
-}
-
-func (cc *Context) cleanupDownloadedDirectories() {
-	for _, sp := range cc.searchPaths {
-		// Skip cleanup for local paths - they should not be deleted
-		if isLocalPath(sp.Path) {
-			continue
-		}
-
-		// Only clean up downloaded remote directories
-		dst := downloadDir(sp.Path)
-		if err := os.RemoveAll(dst); err != nil {
-			cc.logger.Error("Error cleaning up downloaded directory", "path", dst, "error", err)
-		}
-	}
+	return downloadedPaths, nil
 }
 
 func (cc *Context) findExecuteRuleFiles(ctx context.Context) error {
@@ -813,10 +1016,22 @@ func (cc *Context) findExecuteRuleFiles(ctx context.Context) error {
 		return namespacedRuleSearchPaths(dir, cc.namespace)
 	}
 
-	err := cc.visitMarkdownFiles(namespacedRulePaths, func(path string, baseFm *markdown.BaseFrontMatter) error {
+	var selected []*selectedRule
+
+	ruleFiles := markdownCandidates{kind: LoadedFileKindRule}
+
+	err := cc.visitMarkdownFiles(namespacedRulePaths, ruleFiles, func(path string, fm *markdown.BaseFrontMatter) error {
+		if cc.excludeOwnRules && cc.agent.ShouldExcludePath(path) {
+			cc.logger.Info("Skipping the agent's own rule file", "agent", cc.agent, "path", path)
+			cc.explainDecision(LoadedFileKindRule, path, false,
+				fmt.Sprintf("the agent's own rule file (%s), which it reads itself", cc.agent), 0)
+
+			return nil
+		}
+
 		var frontmatter markdown.RuleFrontMatter
 
-		md, err := markdown.ParseMarkdownFile(path, &frontmatter)
+		md, err := parseMarkdownFile(cc, path, &frontmatter)
 		if err != nil {
 			return fmt.Errorf("failed to parse markdown file %s: %w", path, err)
 		}
@@ -829,122 +1044,139 @@ func (cc *Context) findExecuteRuleFiles(ctx context.Context) error {
 			frontmatter.Name = nameFromPath(path)
 		}
 
-		// Expand parameters only if expand is not explicitly set to false
-		var processedContent string
-		if shouldExpandParams(frontmatter.ExpandParams) {
-			processedContent, err = cc.expandParams(md.Content, nil)
-			if err != nil {
-				return fmt.Errorf("failed to expand parameters in file %s: %w", path, err)
+		md.FrontMatter = frontmatter
+
+		// The rules that -w wrote to this file come from other rule files.
+		if content := stripManagedSections(md.Content); content != md.Content {
+			if strings.TrimSpace(content) == "" {
+				cc.logger.Info("Skipping file", "path", path, "reason", "only has rules written by -w")
+				cc.explainDecision(LoadedFileKindRule, path, false, "only has rules written by -w", 0)
+
+				return nil
 			}
-		} else {
-			processedContent = md.Content
+
+			md.Content = content
 		}
 
-		tokens := tokencount.EstimateTokens(processedContent)
+		include := true
 
-		cc.rules = append(cc.rules, markdown.FromContent(frontmatter, processedContent))
+		err = cc.runHooks("rule candidate", path, func(h Hook) error {
+			if !include {
+				return nil
+			}
 
-		cc.totalTokens += tokens
+			var err error
 
-		// Get match reason to explain why this rule was included
-		_, reason := cc.includes.MatchesIncludes(*baseFm, cc.includeByDefault)
-		cc.logger.Info("Including rule file", "path", path, "reason", reason, "tokens", tokens)
+			include, err = h.RuleCandidate(ctx, path, md)
 
-		if err := cc.runBootstrapScript(ctx, path, frontmatter.Bootstrap); err != nil {
-			return fmt.Errorf("failed to run bootstrap script: %w", err)
+			return err
+		})
+		if err != nil {
+			return err
 		}
 
+		if !include {
+			cc.logger.Info("Skipping file", "path", path, "reason", "left out by a hook")
+			cc.explainDecision(LoadedFileKindRule, path, false, "left out by a hook", 0)
+
+			return nil
+		}
+
+		// Get match reason to explain why this rule was included
+		_, reason := cc.includes.MatchesIncludes(*fm, cc.includeByDefault)
+
+		selected = append(selected, &selectedRule{
+			path:        path,
+			frontMatter: frontmatter,
+			content:     md.Content,
+			reason:      reason,
+			lenient:     cc.isLenientPath(path),
+		})
+
 		return nil
 	})
 	if err != nil {
 		return fmt.Errorf("failed to find and execute rule files: %w", err)
 	}
 
-	return nil
-}
-
-func (cc *Context) runBootstrapScript(ctx context.Context, path string, frontmatterBootstrap string) error {
-	// executablePerm is the permission for executable scripts (0755) - required for direct execution and shebang support.
-	const executablePerm = 0o755
-
-	// In lint mode, skip execution but stat-check companion bootstrap files.
-	if cc.lintMode {
-		cc.recordLintBootstrap(path, frontmatterBootstrap)
+	// Bootstraps run as a separate phase so that they can be ordered by depends_on and run in parallel.
+	if err := cc.runBootstraps(ctx, selected); err != nil {
+		return err
+	}
 
-		return nil
+	for _, rule := range selected {
+		if rule.err == nil {
+			cc.captureBootstrapParam(rule.frontMatter.BootstrapOutput, rule.output)
+		}
 	}
 
-	// Prefer frontmatter bootstrap if present
-	if frontmatterBootstrap != "" {
-		cc.logger.Info("Running bootstrap from frontmatter", "path", path)
+	for _, rule := range selected {
+		if rule.err != nil {
+			cc.explainDecision(LoadedFileKindRule, rule.path, false, "bootstrap failed: "+rule.err.Error(), 0)
 
-		tmpFile, err := os.CreateTemp("", "bootstrap-*.sh")
-		if err != nil {
-			return fmt.Errorf("failed to create temp file for bootstrap script from %s: %w", path, err)
+			continue
 		}
 
-		tmpFilePath := tmpFile.Name()
+		if err := cc.includeRule(ctx, rule); err != nil {
+			return err
+		}
+	}
 
-		defer func() { _ = os.Remove(tmpFilePath) }()
+	return nil
+}
 
-		if _, err := tmpFile.WriteString(frontmatterBootstrap); err != nil {
-			_ = tmpFile.Close()
+// includeRule expands a selected rule's content, including any captured bootstrap output,
+// and adds it to the assembled rules.
+func (cc *Context) includeRule(ctx context.Context, rule *selectedRule) error {
+	out := rule.frontMatter.BootstrapOutput
 
-			return fmt.Errorf("failed to write bootstrap script from %s: %w", path, err)
-		}
+	// Expand parameters only if expand is not explicitly set to false
+	processedContent := rule.content
+	if shouldExpandParams(rule.frontMatter.ExpandParams) {
+		params := make(taskparser.Params, len(cc.capturedParams)+1)
+		maps.Copy(params, cc.capturedParams)
 
-		_ = tmpFile.Close()
-
-		// Scripts must be executable to run; supports shebangs (e.g. #!/usr/bin/python3)
-		// #nosec G302 G703 -- bootstrap scripts require 0755; tmpFilePath from CreateTemp is system-generated
-		if err := os.Chmod(tmpFilePath, executablePerm); err != nil {
-			return fmt.Errorf("failed to chmod bootstrap script from %s: %w", path, err)
+		if out != nil && out.Param != "" {
+			// A rule's own captured output takes precedence over another rule's output of the same name.
+			params[out.Param] = []string{rule.output}
 		}
 
-		// #nosec G204 G702 -- intentionally executing user-defined bootstrap scripts; path from CreateTemp
-		cmd := exec.CommandContext(ctx, tmpFilePath)
-		cmd.Stdout = os.Stderr
-		cmd.Stderr = os.Stderr
+		var err error
 
-		if err := cc.cmdRunner(cmd); err != nil {
-			return fmt.Errorf("frontmatter bootstrap script failed for %s: %w", path, err)
+		processedContent, err = cc.expandParams(rule.path, rule.content, params)
+		if err != nil {
+			return fmt.Errorf("failed to expand parameters in file %s: %w", rule.path, err)
 		}
-
-		return nil
 	}
 
-	// Fall back to file-based bootstrap
-	baseNameWithoutExt := strings.TrimSuffix(path, filepath.Ext(path))
-	bootstrapFilePath := baseNameWithoutExt + "-bootstrap"
+	processedContent = appendBootstrapOutput(processedContent, out, rule.output)
 
-	if _, err := os.Stat(bootstrapFilePath); os.IsNotExist(err) {
-		return nil
-	} else if err != nil {
-		return fmt.Errorf("failed to stat bootstrap file %s: %w", bootstrapFilePath, err)
+	included := markdown.FromContent(rule.frontMatter, processedContent)
+
+	err := cc.runHooks("rule included", rule.path, func(h Hook) error {
+		return h.RuleIncluded(ctx, rule.path, &included)
+	})
+	if err != nil {
+		return err
 	}
 
-	cc.logger.Info("Running bootstrap script", "path", bootstrapFilePath)
+	// Hooks may have changed the content, so its structure and tokens are worked out again.
+	included = markdown.FromContent(included.FrontMatter, included.Content)
+	tokens := included.Tokens
 
-	// #nosec G302 -- bootstrap scripts require executablePerm for direct execution and shebang support
-	if err := os.Chmod(bootstrapFilePath, executablePerm); err != nil {
-		return fmt.Errorf("failed to chmod bootstrap file %s: %w", bootstrapFilePath, err)
-	}
+	cc.rules = append(cc.rules, included)
 
-	// #nosec G204 G702 -- intentionally executing user-defined bootstrap scripts
-	cmd := exec.CommandContext(ctx, bootstrapFilePath)
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
+	cc.totalTokens += tokens
 
-	if err := cc.cmdRunner(cmd); err != nil {
-		return fmt.Errorf("file-based bootstrap script failed for %s: %w", path, err)
-	}
+	cc.logger.Info("Including rule file", "path", rule.path, "reason", rule.reason, "tokens", tokens)
+	cc.explainDecision(LoadedFileKindRule, rule.path, true, rule.reason, tokens)
 
 	return nil
 }
 
 // discoverSkills searches for skill directories and loads only their metadata (name and description)
 // for progressive disclosure. Skills are folders containing a SKILL.md file.
-func (cc *Context) discoverSkills() error {
+func (cc *Context) discoverSkills(ctx context.Context) error {
 	// Skip skill discovery if bootstrap is disabled
 	if !cc.doBootstrap {
 		return nil
@@ -955,40 +1187,45 @@ func (cc *Context) discoverSkills() error {
 		lenient bool
 	}
 
-	// Determine the agent's skill path prefix for lenient agent handling.
-	var agentSkillSuffix string
-	if cc.lenientAgent && cc.agent.IsSet() {
-		agentPaths := getAgentsPaths()
-		if cfg, ok := agentPaths[cc.agent]; ok {
-			agentSkillSuffix = cfg.skillsPath
-		}
+	// The agent loads skills from its own skills path natively, so they are excluded
+	// unless requested; when included, they are lenient for a lenient agent.
+	var agentSkillsPath string
+	if cc.agent.IsSet() {
+		agentSkillsPath = getAgentsPaths()[cc.agent].skillsPath
 	}
 
 	var skillPaths []skillDir
 
 	for _, sp := range cc.downloadedPaths {
 		for _, dir := range namespacedSkillSearchPaths(sp.Path, cc.namespace) {
-			lenient := sp.Lenient
-			if !lenient && agentSkillSuffix != "" && strings.HasSuffix(dir, agentSkillSuffix) {
-				lenient = true
+			isAgentPath := agentSkillsPath != "" && dir == filepath.Join(sp.Path, agentSkillsPath)
+			if isAgentPath && !cc.agentSkills {
+				cc.logger.Info("Skipping the agent's own skills", "agent", cc.agent, "path", dir)
+				cc.explainAgentSkills(dir)
+
+				continue
 			}
 
+			lenient := sp.Lenient || (isAgentPath && cc.lenientAgent)
+
 			skillPaths = append(skillPaths, skillDir{path: dir, lenient: lenient})
 		}
 	}
 
 	for _, dir := range skillPaths {
-		if err := cc.discoverSkillsInDir(dir.path, dir.lenient); err != nil {
+		if err := cc.discoverSkillsInDir(ctx, dir.path, dir.lenient); err != nil {
 			return err
 		}
 	}
 
+	cc.warnMissingInlineSkills()
+
 	return nil
 }
 
 // discoverSkillsInDir discovers skills within a single directory.
-func (cc *Context) discoverSkillsInDir(dir string, lenient bool) error {
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
+func (cc *Context) discoverSkillsInDir(ctx context.Context, dir string, lenient bool) error {
+	if _, err := cc.stat(dir); errors.Is(err, fs.ErrNotExist) {
 		return nil
 	} else if err != nil {
 		if lenient {
@@ -1000,7 +1237,7 @@ func (cc *Context) discoverSkillsInDir(dir string, lenient bool) error {
 		return fmt.Errorf("failed to stat skill directory %s: %w", dir, err)
 	}
 
-	entries, err := os.ReadDir(dir)
+	entries, err := cc.readDir(dir)
 	if err != nil {
 		if lenient {
 			cc.logger.Warn("skipping skill directory", "path", dir, "error", err)
@@ -1018,7 +1255,7 @@ func (cc *Context) discoverSkillsInDir(dir string, lenient bool) error {
 
 		skillFile := filepath.Join(dir, entry.Name(), "SKILL.md")
 
-		if err := cc.loadSkillEntry(skillFile, lenient); err != nil {
+		if err := cc.loadSkillEntry(ctx, skillFile, lenient); err != nil {
 			return err
 		}
 	}
@@ -1027,8 +1264,8 @@ func (cc *Context) discoverSkillsInDir(dir string, lenient bool) error {
 }
 
 // loadSkillEntry loads and validates a single skill from its SKILL.md file.
-func (cc *Context) loadSkillEntry(skillFile string, lenient bool) error {
-	if _, err := os.Stat(skillFile); os.IsNotExist(err) {
+func (cc *Context) loadSkillEntry(ctx context.Context, skillFile string, lenient bool) error {
+	if _, err := cc.stat(skillFile); errors.Is(err, fs.ErrNotExist) {
 		return nil
 	} else if err != nil {
 		if lenient {
@@ -1042,7 +1279,7 @@ func (cc *Context) loadSkillEntry(skillFile string, lenient bool) error {
 
 	var frontmatter markdown.SkillFrontMatter
 
-	if _, err := markdown.ParseMarkdownFile(skillFile, &frontmatter); err != nil {
+	if _, err := parseMarkdownFile(cc, skillFile, &frontmatter); err != nil {
 		if lenient {
 			cc.logger.Warn("skipping skill file: failed to parse YAML frontmatter", "path", skillFile, "error", err)
 
@@ -1063,22 +1300,33 @@ func (cc *Context) loadSkillEntry(skillFile string, lenient bool) error {
 			cc.logger.Info("Skipping skill", "name", frontmatter.Name, "path", skillFile, "reason", reason)
 		}
 
+		cc.explainDecision(LoadedFileKindSkill, skillFile, false, reason, 0)
+
 		return nil
 	}
 
-	return cc.validateAndAddSkill(frontmatter, skillFile, reason, lenient)
+	return cc.validateAndAddSkill(ctx, frontmatter, skillFile, reason, lenient)
 }
 
 // validateAndAddSkill validates skill metadata and adds it to the skill collection.
-func (cc *Context) validateAndAddSkill(frontmatter markdown.SkillFrontMatter, skillFile, reason string, lenient bool) error {
+func (cc *Context) validateAndAddSkill(
+	ctx context.Context, frontmatter markdown.SkillFrontMatter, skillFile, reason string, lenient bool,
+) error {
+	source, err := cc.readFile(skillFile)
+	if err != nil {
+		return fmt.Errorf("failed to read skill file %s: %w", skillFile, err)
+	}
+
+	lines, _ := frontMatterLines(source)
+
 	if frontmatter.Name == "" {
 		if lenient {
 			// Infer name from the skill's parent directory
 			frontmatter.Name = filepath.Base(filepath.Dir(skillFile))
 			cc.logger.Warn("using inferred skill name", "name", frontmatter.Name, "path", skillFile)
 		} else if cc.lintMode {
-			cc.lintCollector.recordError(skillFile, LintErrorKindSkillValidation,
-				fmt.Sprintf("%v: %s", ErrSkillMissingName, skillFile))
+			cc.lintCollector.recordErrorAt(skillFile, LintErrorKindSkillValidation,
+				fmt.Sprintf("%v: %s", ErrSkillMissingName, skillFile), lines["name"])
 
 			return nil
 		} else {
@@ -1086,13 +1334,12 @@ func (cc *Context) validateAndAddSkill(frontmatter markdown.SkillFrontMatter, sk
 		}
 	}
 
-	const maxSkillNameLen = 64
 	if len(frontmatter.Name) > maxSkillNameLen {
 		if lenient {
 			cc.logger.Warn("skill name exceeds maximum length", "path", skillFile, "length", len(frontmatter.Name))
 		} else if cc.lintMode {
-			cc.lintCollector.recordError(skillFile, LintErrorKindSkillValidation,
-				fmt.Sprintf("%v: %s (got %d)", ErrSkillNameLength, skillFile, len(frontmatter.Name)))
+			cc.lintCollector.recordErrorAt(skillFile, LintErrorKindSkillValidation,
+				fmt.Sprintf("%v: %s (got %d)", ErrSkillNameLength, skillFile, len(frontmatter.Name)), lines["name"])
 
 			return nil
 		} else {
@@ -1103,11 +1350,12 @@ func (cc *Context) validateAndAddSkill(frontmatter markdown.SkillFrontMatter, sk
 	if frontmatter.Description == "" {
 		if lenient {
 			cc.logger.Warn("skipping skill: missing 'description' field", "path", skillFile)
+			cc.explainDecision(LoadedFileKindSkill, skillFile, false, "missing 'description' field", 0)
 
 			return nil
 		} else if cc.lintMode {
-			cc.lintCollector.recordError(skillFile, LintErrorKindSkillValidation,
-				fmt.Sprintf("%v: %s", ErrSkillMissingDesc, skillFile))
+			cc.lintCollector.recordErrorAt(skillFile, LintErrorKindSkillValidation,
+				fmt.Sprintf("%v: %s", ErrSkillMissingDesc, skillFile), lines["description"])
 
 			return nil
 		}
@@ -1115,13 +1363,13 @@ func (cc *Context) validateAndAddSkill(frontmatter markdown.SkillFrontMatter, sk
 		return fmt.Errorf("%w: %s", ErrSkillMissingDesc, skillFile)
 	}
 
-	const maxSkillDescLen = 1024
 	if len(frontmatter.Description) > maxSkillDescLen {
 		if lenient {
 			cc.logger.Warn("skill description exceeds maximum length", "path", skillFile, "length", len(frontmatter.Description))
 		} else if cc.lintMode {
-			cc.lintCollector.recordError(skillFile, LintErrorKindSkillValidation,
-				fmt.Sprintf("%v: %s (got %d)", ErrSkillDescriptionLength, skillFile, len(frontmatter.Description)))
+			cc.lintCollector.recordErrorAt(skillFile, LintErrorKindSkillValidation,
+				fmt.Sprintf("%v: %s (got %d)", ErrSkillDescriptionLength, skillFile, len(frontmatter.Description)),
+				lines["description"])
 
 			return nil
 		} else {
@@ -1129,18 +1377,64 @@ func (cc *Context) validateAndAddSkill(frontmatter markdown.SkillFrontMatter, sk
 		}
 	}
 
-	absPath, err := filepath.Abs(skillFile)
+	cc.checkSkillSpec(skillFile, frontmatter, source)
+
+	// A skill from an fs.FS has no local path, so it is located by its name in the search path.
+	absPath := skillFile
+	if !cc.isFSPath(skillFile) {
+		absPath, err = filepath.Abs(skillFile)
+		if err != nil {
+			return fmt.Errorf("failed to get absolute path for skill %s: %w", skillFile, err)
+		}
+	}
+
+	// Run the skill's bootstrap (e.g. to install tools it needs) when it is discovered.
+	output, err := cc.runBootstrapScript(ctx, skillFile, frontmatter.BootstrapFrontMatter)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path for skill %s: %w", skillFile, err)
+		if lenient {
+			cc.logger.Warn("skipping skill: bootstrap failed", "path", skillFile, "error", err)
+			cc.explainDecision(LoadedFileKindSkill, skillFile, false, "bootstrap failed: "+err.Error(), 0)
+
+			return nil
+		}
+
+		return fmt.Errorf("failed to run bootstrap script for skill %s: %w", skillFile, err)
 	}
 
-	cc.skills.Skills = append(cc.skills.Skills, skills.Skill{
+	cc.captureBootstrapParam(frontmatter.BootstrapOutput, output)
+
+	skill := skills.Skill{
 		Name:        frontmatter.Name,
 		Description: frontmatter.Description,
 		Location:    absPath,
-	})
+	}
 
 	cc.logger.Info("Discovered skill", "name", frontmatter.Name, "reason", reason, "path", absPath)
 
+	if cc.shouldInlineSkill(frontmatter.Name) {
+		if err := cc.inlineSkill(&skill, skillFile); err != nil {
+			return err
+		}
+	}
+
+	inlined := skill.Content
+
+	err = cc.runHooks("skill discovered", skillFile, func(h Hook) error {
+		return h.SkillDiscovered(ctx, skillFile, frontmatter, &skill)
+	})
+	if err != nil {
+		return err
+	}
+
+	if skill.Content != inlined {
+		cc.totalTokens -= skill.Tokens
+		skill.Tokens = tokencount.EstimateTokens(skill.Content)
+		cc.totalTokens += skill.Tokens
+	}
+
+	cc.skills.Skills = append(cc.skills.Skills, skill)
+
+	cc.explainDecision(LoadedFileKindSkill, skillFile, true, reason, skill.Tokens)
+
 	return nil
 }
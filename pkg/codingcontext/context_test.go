@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -721,6 +722,23 @@ func TestContext_Run_Rules(t *testing.T) {
 			wantErr:  false,
 			check:    checkRulesCount(3),
 		},
+		{
+			name: "agent option excludes its own rules when requested",
+			setup: func(t *testing.T, dir string) {
+				t.Helper()
+				createTask(t, dir, "agent-task", "", "Task")
+				createRule(t, dir, ".agents/rules/generic.md", "", "Generic rule")
+				createRule(t, dir, ".cursor/rules/cursor-rule.md", "", "Cursor rule")
+				createRule(t, dir, ".github/agents/copilot-rule.md", "", "Copilot rule")
+			},
+			opts: []Option{
+				WithAgent(AgentCursor),
+				WithExcludeAgentRules(true),
+			},
+			taskName: "agent-task",
+			wantErr:  false,
+			check:    checkRulesCount(2), // generic + copilot; cursor excluded
+		},
 		{
 			name: "task frontmatter agent overrides option",
 			setup: func(t *testing.T, dir string) {
@@ -1891,6 +1909,26 @@ func TestUserPrompt(t *testing.T) {
 			wantErr:  false,
 			check:    checkUserPromptBothParsed,
 		},
+		{
+			name: "literal user_prompt is not expanded",
+			setup: func(t *testing.T, dir string) {
+				t.Helper()
+				createTask(t, dir, "literal", "", "Task ${issue_number}\n")
+				createCommand(t, dir, "greet", "", "Hello from command!")
+			},
+			opts: []Option{
+				WithUserPrompt("Issue ${issue_number}\n!`echo injected`\n@/etc/hostname\n/greet\n"),
+				WithLiteralUserPrompt(true),
+				WithParams(taskparser.Params{
+					"issue_number": []string{"123"},
+				}),
+			},
+			taskName: "literal",
+			wantErr:  false,
+			check: checkTaskContains(
+				"Task 123\n---\nIssue ${issue_number}\n!`echo injected`\n@/etc/hostname\n/greet\n",
+			),
+		},
 	}
 
 	for _, tt := range tests {
@@ -2971,6 +3009,7 @@ name: broken-skill
 		cc := New(
 			WithSearchPaths("file://"+tmpDir),
 			WithLenientAgent(AgentClaude),
+			WithAgentSkills(true),
 		)
 
 		result, err := cc.Run(context.Background(), "test-task")
@@ -3000,6 +3039,7 @@ description: Should infer name from directory
 		cc := New(
 			WithSearchPaths("file://"+tmpDir),
 			WithLenientAgent(AgentClaude),
+			WithAgentSkills(true),
 		)
 
 		result, err := cc.Run(context.Background(), "test-task")
@@ -3035,3 +3075,70 @@ func TestLenientAgentMutualExclusion(t *testing.T) {
 		t.Fatal("expected error when both WithAgent and WithLenientAgent are set, but got none")
 	}
 }
+
+// TestAgentSkillsExcluded tests that skills from the target agent's own skills path are excluded by default.
+func TestAgentSkillsExcluded(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		taskFM     string
+		opts       []Option
+		wantSkills []string
+	}{
+		{
+			name:       "no agent includes all skills",
+			wantSkills: []string{"claude-skill", "generic-skill"},
+		},
+		{
+			name:       "agent's own skills are excluded",
+			opts:       []Option{WithAgent(AgentClaude)},
+			wantSkills: []string{"generic-skill"},
+		},
+		{
+			name:       "agent from task frontmatter",
+			taskFM:     "agent: claude",
+			wantSkills: []string{"generic-skill"},
+		},
+		{
+			name:       "other agents' skills are kept",
+			opts:       []Option{WithAgent(AgentCursor)},
+			wantSkills: []string{"claude-skill", "generic-skill"},
+		},
+		{
+			name:       "opt in to the agent's own skills",
+			opts:       []Option{WithAgent(AgentClaude), WithAgentSkills(true)},
+			wantSkills: []string{"claude-skill", "generic-skill"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "test-task", tt.taskFM, "Test task content")
+			createSkill(t, dir, filepath.Join(".agents", "skills", "generic-skill"),
+				"---\nname: generic-skill\ndescription: Generic\n---\n")
+			createSkill(t, dir, filepath.Join(".claude", "skills", "claude-skill"),
+				"---\nname: claude-skill\ndescription: Claude\n---\n")
+
+			result, err := New(append([]Option{WithSearchPaths(dir)}, tt.opts...)...).Run(context.Background(), "test-task")
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			var names []string
+			for _, s := range result.Skills.Skills {
+				names = append(names, s.Name)
+			}
+
+			// Skill search paths are not ordered between agents.
+			slices.Sort(names)
+
+			if strings.Join(names, ",") != strings.Join(tt.wantSkills, ",") {
+				t.Errorf("skills = %v, want %v", names, tt.wantSkills)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package codingcontext
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// ResultDiff describes how the context assembled for a task differs between two
+// configurations, e.g. before and after a shared rule is edited.
+type ResultDiff struct {
+	RulesAdded   []string // Names of the rules only in the new context
+	RulesRemoved []string // Names of the rules only in the old context
+	RulesChanged []string // Names of the rules in both contexts whose content differs
+	OldTokens    int
+	NewTokens    int
+	// PromptDiff is a unified diff of the old and new prompts; empty when they are the same.
+	PromptDiff string
+}
+
+// DiffResults compares the context assembled for a task under two configurations. Either
+// result may be nil when the task is not found in that configuration. Rules are matched by
+// name; the unified diff of the prompts is labelled oldLabel and newLabel.
+func DiffResults(oldResult, newResult *Result, oldLabel, newLabel string) (ResultDiff, error) {
+	var diff ResultDiff
+
+	oldRules, oldPrompt := diffRules(oldResult)
+	newRules, newPrompt := diffRules(newResult)
+
+	for _, name := range slices.Sorted(maps.Keys(newRules)) {
+		oldContent, ok := oldRules[name]
+
+		switch {
+		case !ok:
+			diff.RulesAdded = append(diff.RulesAdded, name)
+		case oldContent != newRules[name]:
+			diff.RulesChanged = append(diff.RulesChanged, name)
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(oldRules)) {
+		if _, ok := newRules[name]; !ok {
+			diff.RulesRemoved = append(diff.RulesRemoved, name)
+		}
+	}
+
+	if oldResult != nil {
+		diff.OldTokens = oldResult.Tokens
+	}
+
+	if newResult != nil {
+		diff.NewTokens = newResult.Tokens
+	}
+
+	if oldPrompt == newPrompt {
+		return diff, nil
+	}
+
+	const contextLines = 3
+
+	promptDiff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        promptLines(oldPrompt),
+		B:        promptLines(newPrompt),
+		FromFile: oldLabel,
+		ToFile:   newLabel,
+		Context:  contextLines,
+	})
+	if err != nil {
+		return ResultDiff{}, fmt.Errorf("failed to diff prompts: %w", err)
+	}
+
+	diff.PromptDiff = promptDiff
+
+	return diff, nil
+}
+
+// Changed returns whether the prompt or any rule differs.
+func (d ResultDiff) Changed() bool {
+	return d.PromptDiff != "" || len(d.RulesAdded) > 0 || len(d.RulesRemoved) > 0 || len(d.RulesChanged) > 0
+}
+
+// TokenDelta returns how many more tokens the new context has than the old one.
+func (d ResultDiff) TokenDelta() int {
+	return d.NewTokens - d.OldTokens
+}
+
+// diffRules returns the content of the rules of result by name, with the content of rules
+// sharing a name joined, and its prompt.
+func diffRules(result *Result) (map[string]string, string) {
+	rules := make(map[string]string)
+	if result == nil {
+		return rules, ""
+	}
+
+	for _, rule := range result.Rules {
+		rules[rule.FrontMatter.Name] += rule.Content
+	}
+
+	return rules, result.Prompt
+}
+
+// promptLines splits a prompt into lines for diffing, each ending with a newline.
+func promptLines(prompt string) []string {
+	if prompt == "" {
+		return nil
+	}
+
+	return difflib.SplitLines(strings.TrimSuffix(prompt, "\n"))
+}
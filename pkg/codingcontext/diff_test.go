@@ -0,0 +1,117 @@
+package codingcontext
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiffResults(t *testing.T) {
+	t.Parallel()
+
+	oldDir := t.TempDir()
+	createTask(t, oldDir, "fix-bug", "", "Fix the bug.")
+	createRule(t, oldDir, ".agents/rules/style.md", "", "# Style\nUse tabs.")
+	createRule(t, oldDir, ".agents/rules/legacy.md", "", "# Legacy")
+	createRule(t, oldDir, ".agents/rules/testing.md", "", "# Testing")
+
+	newDir := t.TempDir()
+	createTask(t, newDir, "fix-bug", "", "Fix the bug.")
+	createRule(t, newDir, ".agents/rules/style.md", "", "# Style\nUse spaces.")
+	createRule(t, newDir, ".agents/rules/security.md", "", "# Security")
+	createRule(t, newDir, ".agents/rules/testing.md", "", "# Testing")
+
+	oldResult, err := New(WithSearchPaths(oldDir)).Run(context.Background(), "fix-bug")
+	if err != nil {
+		t.Fatalf("Run() old error: %v", err)
+	}
+
+	newResult, err := New(WithSearchPaths(newDir)).Run(context.Background(), "fix-bug")
+	if err != nil {
+		t.Fatalf("Run() new error: %v", err)
+	}
+
+	diff, err := DiffResults(oldResult, newResult, "old", "new")
+	if err != nil {
+		t.Fatalf("DiffResults() error: %v", err)
+	}
+
+	if !diff.Changed() {
+		t.Error("Changed() = false, want true")
+	}
+
+	if want := []string{"security"}; !slices.Equal(diff.RulesAdded, want) {
+		t.Errorf("RulesAdded = %v, want %v", diff.RulesAdded, want)
+	}
+
+	if want := []string{"legacy"}; !slices.Equal(diff.RulesRemoved, want) {
+		t.Errorf("RulesRemoved = %v, want %v", diff.RulesRemoved, want)
+	}
+
+	if want := []string{"style"}; !slices.Equal(diff.RulesChanged, want) {
+		t.Errorf("RulesChanged = %v, want %v", diff.RulesChanged, want)
+	}
+
+	if diff.OldTokens != oldResult.Tokens || diff.NewTokens != newResult.Tokens {
+		t.Errorf("tokens = %d -> %d, want %d -> %d", diff.OldTokens, diff.NewTokens, oldResult.Tokens, newResult.Tokens)
+	}
+
+	for _, want := range []string{"--- old\n", "+++ new\n", "-Use tabs.\n", "+Use spaces.\n", "+# Security\n"} {
+		if !strings.Contains(diff.PromptDiff, want) {
+			t.Errorf("PromptDiff does not contain %q:\n%s", want, diff.PromptDiff)
+		}
+	}
+}
+
+func TestDiffResults_Unchanged(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "fix-bug", "", "Fix the bug.")
+	createRule(t, dir, ".agents/rules/style.md", "", "# Style")
+
+	result, err := New(WithSearchPaths(dir)).Run(context.Background(), "fix-bug")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	diff, err := DiffResults(result, result, "old", "new")
+	if err != nil {
+		t.Fatalf("DiffResults() error: %v", err)
+	}
+
+	if diff.Changed() || diff.TokenDelta() != 0 {
+		t.Errorf("DiffResults() of the same result = %+v, want no change", diff)
+	}
+}
+
+func TestDiffResults_MissingTask(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "fix-bug", "", "Fix the bug.")
+	createRule(t, dir, ".agents/rules/style.md", "", "# Style")
+
+	result, err := New(WithSearchPaths(dir)).Run(context.Background(), "fix-bug")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	diff, err := DiffResults(nil, result, "old", "new")
+	if err != nil {
+		t.Fatalf("DiffResults() error: %v", err)
+	}
+
+	if want := []string{"style"}; !slices.Equal(diff.RulesAdded, want) {
+		t.Errorf("RulesAdded = %v, want %v", diff.RulesAdded, want)
+	}
+
+	if diff.TokenDelta() != result.Tokens {
+		t.Errorf("TokenDelta() = %d, want %d", diff.TokenDelta(), result.Tokens)
+	}
+
+	if !strings.Contains(diff.PromptDiff, "@@ -0,0 ") {
+		t.Errorf("PromptDiff does not add the whole prompt:\n%s", diff.PromptDiff)
+	}
+}
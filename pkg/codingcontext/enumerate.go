@@ -2,20 +2,27 @@ package codingcontext
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"strings"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
 )
 
 // DiscoveredTask represents a task found during enumeration of search paths.
 type DiscoveredTask struct {
 	// Name is the task name as passed to Lint or Run (e.g. "my-task" or "myteam/my-task").
 	Name string
-	// Path is the absolute path to the task markdown file.
+	// Path is the absolute path to the task markdown file; for a search path given by
+	// WithSearchFS, it is the path within the fs.FS joined to the search path's name.
 	Path string
 	// Namespace is the namespace prefix; empty for global tasks.
 	Namespace string
+	// Description is the description in the task's frontmatter; empty if it has none or
+	// its frontmatter cannot be parsed.
+	Description string
 }
 
 // ListTasks enumerates all available tasks from the configured search paths without
@@ -26,21 +33,18 @@ type DiscoveredTask struct {
 // If the same task name appears in multiple search paths the first occurrence wins
 // (consistent with how Run/Lint resolve tasks).
 func (cc *Context) ListTasks(ctx context.Context) ([]DiscoveredTask, error) {
-	manifestPaths, err := cc.parseManifestFile(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse manifest file: %w", err)
-	}
+	s := cc.session()
+	defer s.endRun()
 
-	for _, p := range manifestPaths {
-		cc.searchPaths = append(cc.searchPaths, SearchPath{Path: p})
-	}
+	return s.listTasks(ctx)
+}
 
-	if err := cc.downloadRemoteDirectories(ctx); err != nil {
-		return nil, fmt.Errorf("failed to download remote directories: %w", err)
+// listTasks lists the tasks on a session returned by session.
+func (cc *Context) listTasks(ctx context.Context) ([]DiscoveredTask, error) {
+	if err := cc.useSearchPaths(ctx); err != nil {
+		return nil, err
 	}
 
-	defer cc.cleanupDownloadedDirectories()
-
 	var tasks []DiscoveredTask
 
 	seen := make(map[string]bool)
@@ -49,7 +53,7 @@ func (cc *Context) ListTasks(ctx context.Context) ([]DiscoveredTask, error) {
 		// Global tasks.
 		dir := sp.Path
 		for _, taskDir := range taskSearchPaths(dir) {
-			found, err := listTasksInDir(taskDir, "")
+			found, err := cc.listTasksInDir(taskDir, "")
 			if err != nil {
 				return nil, fmt.Errorf("failed to list tasks in %s: %w", taskDir, err)
 			}
@@ -65,8 +69,8 @@ func (cc *Context) ListTasks(ctx context.Context) ([]DiscoveredTask, error) {
 		// Namespace tasks: walk .agents/namespaces/<ns>/tasks/.
 		nsRootDir := filepath.Join(dir, ".agents/namespaces")
 
-		entries, err := os.ReadDir(nsRootDir)
-		if err != nil && !os.IsNotExist(err) {
+		entries, err := cc.readDir(nsRootDir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return nil, fmt.Errorf("failed to read namespace directory %s: %w", nsRootDir, err)
 		}
 
@@ -78,7 +82,7 @@ func (cc *Context) ListTasks(ctx context.Context) ([]DiscoveredTask, error) {
 			ns := entry.Name()
 			nsTaskDir := filepath.Join(nsRootDir, ns, "tasks")
 
-			found, err := listTasksInDir(nsTaskDir, ns)
+			found, err := cc.listTasksInDir(nsTaskDir, ns)
 			if err != nil {
 				return nil, fmt.Errorf("failed to list namespace tasks in %s: %w", nsTaskDir, err)
 			}
@@ -97,8 +101,8 @@ func (cc *Context) ListTasks(ctx context.Context) ([]DiscoveredTask, error) {
 
 // listTasksInDir scans dir for .md/.mdc task files and returns a DiscoveredTask for each.
 // namespace is empty for global tasks; for namespaced tasks it is prepended to the name.
-func listTasksInDir(dir, namespace string) ([]DiscoveredTask, error) {
-	if _, err := os.Stat(dir); os.IsNotExist(err) {
+func (cc *Context) listTasksInDir(dir, namespace string) ([]DiscoveredTask, error) {
+	if _, err := cc.stat(dir); errors.Is(err, fs.ErrNotExist) {
 		return nil, nil
 	} else if err != nil {
 		return nil, fmt.Errorf("failed to stat directory %s: %w", dir, err)
@@ -106,12 +110,12 @@ func listTasksInDir(dir, namespace string) ([]DiscoveredTask, error) {
 
 	var tasks []DiscoveredTask
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+	err := cc.walkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if info.IsDir() {
+		if d.IsDir() {
 			return nil
 		}
 
@@ -127,10 +131,15 @@ func listTasksInDir(dir, namespace string) ([]DiscoveredTask, error) {
 			name = namespace + "/" + baseName
 		}
 
+		// A task that cannot be parsed is still listed; running or linting it reports the error.
+		var frontMatter markdown.TaskFrontMatter
+		_, _ = parseMarkdownFile(cc, path, &frontMatter)
+
 		tasks = append(tasks, DiscoveredTask{
-			Name:      name,
-			Path:      path,
-			Namespace: namespace,
+			Name:        name,
+			Path:        path,
+			Namespace:   namespace,
+			Description: frontMatter.Description,
 		})
 
 		return nil
@@ -2,6 +2,7 @@ package codingcontext
 
 import (
 	"context"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
@@ -141,7 +142,7 @@ func TestListTasksInDir(t *testing.T) {
 				taskDir = "/nonexistent/path"
 			}
 
-			got, err := listTasksInDir(taskDir, tt.namespace)
+			got, err := New().listTasksInDir(taskDir, tt.namespace)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("listTasksInDir() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -196,7 +197,7 @@ func TestListTasksInDir_Subdirectories(t *testing.T) {
 		t.Fatalf("failed to write nested task: %v", err)
 	}
 
-	got, err := listTasksInDir(taskDir, "")
+	got, err := New().listTasksInDir(taskDir, "")
 	if err != nil {
 		t.Fatalf("listTasksInDir() error: %v", err)
 	}
@@ -385,6 +386,30 @@ func TestContext_ListTasks(t *testing.T) {
 	}
 }
 
+func TestContext_ListTasks_Description(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	createTask(t, tmpDir, "deploy", "description: Deploy to an environment", "Deploy")
+	createTask(t, tmpDir, "plain", "", "Plain")
+	createTask(t, tmpDir, "broken", "description: [", "Broken")
+
+	got, err := New(WithSearchPaths(tmpDir)).ListTasks(context.Background())
+	if err != nil {
+		t.Fatalf("ListTasks() error: %v", err)
+	}
+
+	descriptions := make(map[string]string)
+	for _, task := range got {
+		descriptions[task.Name] = task.Description
+	}
+
+	want := map[string]string{"deploy": "Deploy to an environment", "plain": "", "broken": ""}
+	if !maps.Equal(descriptions, want) {
+		t.Errorf("ListTasks() descriptions = %v, want %v", descriptions, want)
+	}
+}
+
 func TestContext_ListTasks_FileProtocol(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,259 @@
+package codingcontext
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Decision records why a candidate file was included in or left out of the context.
+type Decision struct {
+	Kind LoadedFileKind `json:"kind"`
+	Path string         `json:"path"`
+	// SearchPath is the search path the file was found in.
+	SearchPath string `json:"search_path,omitempty"`
+	// AgentPath is the directory or file, relative to SearchPath, that the file was found
+	// through, e.g. ".cursor/rules" or ".agents/namespaces/myteam/tasks".
+	AgentPath string `json:"agent_path,omitempty"`
+	// Agent is the agent that AgentPath belongs to; empty for the generic .agents directories.
+	Agent    string `json:"agent,omitempty"`
+	Included bool   `json:"included"`
+	// Reason explains the decision, e.g. the selectors that matched or did not match.
+	Reason string `json:"reason"`
+	Tokens int    `json:"tokens,omitempty"`
+}
+
+// Explanation is returned by Explain. It reports every candidate file considered while
+// assembling the context and why each was included or left out.
+type Explanation struct {
+	Task      string              `json:"task"`
+	Namespace string              `json:"namespace,omitempty"`
+	Agent     string              `json:"agent,omitempty"`
+	Selectors map[string][]string `json:"selectors"`
+	Decisions []Decision          `json:"decisions"`
+	Tokens    int                 `json:"tokens"`
+	Result    *Result             `json:"-"` // The assembled context
+}
+
+// explainCollector is internal state attached to Context by Explain.
+type explainCollector struct {
+	decisions []Decision
+}
+
+// Explain runs context assembly like Run, and reports every candidate task, command, rule,
+// and skill file that was considered: where it was found, whether it was included, why, and
+// its tokens. Files of the task's name that are shadowed by an earlier one (e.g. by a
+// namespace task) are reported as left out.
+// Explain is safe for concurrent use.
+func (cc *Context) Explain(ctx context.Context, taskName string) (*Explanation, error) {
+	s := cc.session()
+	defer s.endRun()
+
+	return s.explain(ctx, taskName)
+}
+
+// explain explains taskName on a session returned by session.
+func (cc *Context) explain(ctx context.Context, taskName string) (*Explanation, error) {
+	cc.explainCollector = &explainCollector{}
+
+	result, err := cc.run(ctx, taskName)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &Explanation{
+		Task:      taskName,
+		Namespace: result.Namespace,
+		Selectors: make(map[string][]string, len(cc.includes)),
+		Decisions: cc.explainCollector.decisions,
+		Tokens:    result.Tokens,
+		Result:    result,
+	}
+
+	if result.Agent.IsSet() {
+		explanation.Agent = result.Agent.String()
+	}
+
+	for key, values := range cc.includes {
+		explanation.Selectors[key] = slices.Sorted(maps.Keys(values))
+	}
+
+	return explanation, nil
+}
+
+// explainDecision records a decision about a candidate file when explaining.
+func (cc *Context) explainDecision(kind LoadedFileKind, path string, included bool, reason string, tokens int) {
+	if cc.explainCollector == nil {
+		return
+	}
+
+	decision := Decision{Kind: kind, Path: path, Included: included, Reason: reason, Tokens: tokens}
+	decision.SearchPath, decision.AgentPath, decision.Agent = cc.fileOrigin(path)
+
+	cc.explainCollector.decisions = append(cc.explainCollector.decisions, decision)
+}
+
+// explainTokens sets the tokens of the last decision to include the file at path, once
+// they are known.
+func (cc *Context) explainTokens(kind LoadedFileKind, path string, tokens int) {
+	if cc.explainCollector == nil {
+		return
+	}
+
+	decisions := cc.explainCollector.decisions
+	for i := len(decisions) - 1; i >= 0; i-- {
+		if decisions[i].Kind == kind && decisions[i].Path == path && decisions[i].Included {
+			decisions[i].Tokens = tokens
+
+			return
+		}
+	}
+}
+
+// explainAgentSkills records the skills in the agent's own skills directory dir as left
+// out when explaining.
+func (cc *Context) explainAgentSkills(dir string) {
+	if cc.explainCollector == nil {
+		return
+	}
+
+	entries, err := cc.readDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		skillFile := filepath.Join(dir, entry.Name(), "SKILL.md")
+		if _, err := cc.stat(skillFile); entry.IsDir() && err == nil {
+			cc.explainDecision(LoadedFileKindSkill, skillFile, false,
+				fmt.Sprintf("the agent's own skill (%s), which it loads itself", cc.agent), 0)
+		}
+	}
+}
+
+// fileOrigin returns the search path holding path, the agent directory (or file) relative
+// to it that path was found through, and the agent that directory belongs to. When several
+// agents share a directory, the generic .agents directory or else the first agent by name wins.
+func (cc *Context) fileOrigin(path string) (string, string, string) {
+	agentsPaths := getAgentsPaths()
+	agents := slices.Sorted(maps.Keys(agentsPaths))
+
+	for _, sp := range cc.downloadedPaths {
+		rel, err := filepath.Rel(sp.Path, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		var agentPath, agent string
+
+		matches := func(dir string) bool {
+			dir = filepath.Clean(dir)
+
+			return len(dir) > len(agentPath) &&
+				(rel == dir || strings.HasPrefix(rel, dir+string(filepath.Separator)))
+		}
+
+		if cc.namespace != "" {
+			for _, kind := range []string{"tasks", "rules", "commands", "skills"} {
+				if dir := filepath.Join(".agents/namespaces", cc.namespace, kind); matches(dir) {
+					agentPath, agent = dir, ""
+				}
+			}
+		}
+
+		for _, a := range agents {
+			config := agentsPaths[a]
+			for _, dir := range append(slices.Clone(config.rulesPaths),
+				config.skillsPath, config.commandsPath, config.tasksPath) {
+				if dir != "" && matches(dir) {
+					agentPath, agent = filepath.Clean(dir), a.String()
+				}
+			}
+		}
+
+		if agentPath != "" {
+			return sp.Path, agentPath, agent
+		}
+	}
+
+	return "", "", ""
+}
+
+// WriteText writes the explanation as text: the task, its selectors and total tokens,
+// and then each decision grouped by kind, marked + when included and - when left out.
+func (e *Explanation) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Task: %s\n", e.Task)
+
+	if e.Namespace != "" {
+		fmt.Fprintf(&b, "Namespace: %s\n", e.Namespace)
+	}
+
+	if e.Agent != "" {
+		fmt.Fprintf(&b, "Agent: %s\n", e.Agent)
+	}
+
+	selectors := make([]string, 0, len(e.Selectors))
+	for _, key := range slices.Sorted(maps.Keys(e.Selectors)) {
+		selectors = append(selectors, key+"="+strings.Join(e.Selectors[key], ","))
+	}
+
+	fmt.Fprintf(&b, "Selectors: %s\n", strings.Join(selectors, " "))
+	fmt.Fprintf(&b, "Tokens: %d\n", e.Tokens)
+
+	for _, kind := range []LoadedFileKind{
+		LoadedFileKindTask, LoadedFileKindCommand, LoadedFileKindRule, LoadedFileKindSkill,
+	} {
+		heading := false
+
+		for _, d := range e.Decisions {
+			if d.Kind != kind {
+				continue
+			}
+
+			if !heading {
+				fmt.Fprintf(&b, "\n%ss:\n", kind)
+
+				heading = true
+			}
+
+			mark := "-"
+			if d.Included {
+				mark = "+"
+			}
+
+			fmt.Fprintf(&b, "  %s %s", mark, d.Path)
+
+			if d.Included {
+				fmt.Fprintf(&b, " (%d tokens)", d.Tokens)
+			}
+
+			b.WriteString("\n")
+
+			if d.AgentPath != "" {
+				fmt.Fprintf(&b, "      from %s in %s", d.AgentPath, d.SearchPath)
+
+				if d.Agent != "" {
+					fmt.Fprintf(&b, " (%s)", d.Agent)
+				}
+
+				b.WriteString("\n")
+			}
+
+			if d.Reason != "" {
+				fmt.Fprintf(&b, "      %s\n", d.Reason)
+			}
+		}
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write explanation: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+package codingcontext
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/selectors"
+)
+
+// createExplainFixture creates a namespace task shadowing a global one, a command, rules
+// that match and do not match the selectors, and skills, including the cursor agent's own.
+func createExplainFixture(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	createNamespaceTask(t, dir, "myteam", "fix-bug", "Fix the bug.\n/greet\n")
+	createTask(t, dir, "fix-bug", "", "Global fix the bug.")
+	createCommand(t, dir, "greet", "", "Hello.")
+	createRule(t, dir, ".agents/rules/platform.md", "team: platform", "# Platform Rule")
+	createRule(t, dir, ".agents/rules/data.md", "team: data", "# Data Rule")
+	createRule(t, dir, ".cursor/rules/own.md", "", "# Cursor's Own Rule")
+	createSkill(t, dir, ".agents/skills/db",
+		"---\nname: db\ndescription: Work with the database.\n---\n# DB\n")
+	createSkill(t, dir, ".agents/skills/data",
+		"---\nname: data\ndescription: Work with the data warehouse.\nteam: data\n---\n# Data\n")
+	createSkill(t, dir, ".cursor/skills/own",
+		"---\nname: own\ndescription: Cursor's own skill.\n---\n# Own\n")
+
+	return dir
+}
+
+func TestExplain(t *testing.T) {
+	t.Parallel()
+
+	dir := createExplainFixture(t)
+
+	cc := New(
+		WithSearchPaths(dir),
+		WithSelectors(selectors.Selectors{"team": {"platform": true}}),
+		WithAgent(AgentCursor),
+		WithExcludeAgentRules(true),
+	)
+
+	explanation, err := cc.Explain(context.Background(), "myteam/fix-bug")
+	if err != nil {
+		t.Fatalf("Explain() error: %v", err)
+	}
+
+	nsTask := filepath.Join(dir, ".agents", "namespaces", "myteam", "tasks", "fix-bug.md")
+
+	tests := []struct {
+		kind      LoadedFileKind
+		path      string
+		included  bool
+		reason    string
+		agentPath string
+		agent     string
+	}{
+		{LoadedFileKindTask, nsTask, true, "task name matches", ".agents/namespaces/myteam/tasks", ""},
+		{LoadedFileKindTask, ".agents/tasks/fix-bug.md", false, "shadowed by " + nsTask, ".agents/tasks", ""},
+		{LoadedFileKindCommand, ".agents/commands/greet.md", true, "referenced by slash command", ".agents/commands", ""},
+		{LoadedFileKindRule, ".agents/rules/platform.md", true, "matched selectors: team=platform", ".agents/rules", ""},
+		{LoadedFileKindRule, ".agents/rules/data.md", false, "selectors did not match", ".agents/rules", ""},
+		{LoadedFileKindRule, ".cursor/rules/own.md", false, "the agent's own rule file", ".cursor/rules", "cursor"},
+		{LoadedFileKindSkill, ".agents/skills/db/SKILL.md", true, "", ".agents/skills", ""},
+		{LoadedFileKindSkill, ".agents/skills/data/SKILL.md", false, "selectors did not match", ".agents/skills", ""},
+		{LoadedFileKindSkill, ".cursor/skills/own/SKILL.md", false, "the agent's own skill", ".cursor/skills", "cursor"},
+	}
+
+	for _, tt := range tests {
+		path := tt.path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		i := slices.IndexFunc(explanation.Decisions, func(d Decision) bool { return d.Path == path })
+		if i < 0 {
+			t.Errorf("expected a decision for %s, got %+v", path, explanation.Decisions)
+
+			continue
+		}
+
+		d := explanation.Decisions[i]
+		if d.Kind != tt.kind || d.Included != tt.included || !strings.Contains(d.Reason, tt.reason) ||
+			d.SearchPath != dir || d.AgentPath != filepath.FromSlash(tt.agentPath) || d.Agent != tt.agent {
+			t.Errorf("unexpected decision for %s: %+v", tt.path, d)
+		}
+
+		if d.Included && d.Kind != LoadedFileKindSkill && d.Tokens == 0 {
+			t.Errorf("expected tokens for %s", tt.path)
+		}
+	}
+
+	if explanation.Namespace != "myteam" || explanation.Agent != "cursor" ||
+		!slices.Equal(explanation.Selectors["team"], []string{"platform"}) {
+		t.Errorf("unexpected explanation: %+v", explanation)
+	}
+
+	if explanation.Tokens != explanation.Result.Tokens {
+		t.Errorf("expected %d tokens, got %d", explanation.Result.Tokens, explanation.Tokens)
+	}
+}
+
+func TestExplain_WriteText(t *testing.T) {
+	t.Parallel()
+
+	dir := createExplainFixture(t)
+	cc := New(WithSearchPaths(dir), WithSelectors(selectors.Selectors{"team": {"platform": true}}))
+
+	explanation, err := cc.Explain(context.Background(), "fix-bug")
+	if err != nil {
+		t.Fatalf("Explain() error: %v", err)
+	}
+
+	var b bytes.Buffer
+	if err := explanation.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error: %v", err)
+	}
+
+	for _, want := range []string{
+		"Task: fix-bug\n",
+		"team=platform",
+		"\ntasks:\n  + " + filepath.Join(dir, ".agents", "tasks", "fix-bug.md") + " (",
+		"\nrules:\n",
+		"  - " + filepath.Join(dir, ".agents", "rules", "data.md") + "\n",
+		"      from .agents/rules in " + dir + "\n",
+		"\nskills:\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestExplain_LeavesContextUnchanged(t *testing.T) {
+	t.Parallel()
+
+	dir := createExplainFixture(t)
+	cc := New(WithSearchPaths(dir))
+
+	if _, err := cc.Explain(context.Background(), "fix-bug"); err != nil {
+		t.Fatalf("Explain() error: %v", err)
+	}
+
+	if cc.explainCollector != nil {
+		t.Error("expected Explain to leave the Context unchanged")
+	}
+}
@@ -0,0 +1,107 @@
+package codingcontext
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+)
+
+// maxFileCacheEntries limits the parsed files a Context keeps. A long-running Context, such
+// as a server's, would otherwise keep every file it has ever parsed, including files since
+// deleted.
+const maxFileCacheEntries = 4096
+
+// fileCache holds parsed markdown files, so that files are not parsed again by later or
+// concurrent runs of a Context. An entry is reused while its file's size and modification
+// time are unchanged. When the cache is full, arbitrary entries are evicted; they are only
+// parsed again.
+type fileCache struct {
+	mu      sync.Mutex
+	entries map[fileCacheKey]fileCacheEntry
+}
+
+// fileCacheKey identifies a file parsed with a frontmatter type; the same file may be
+// parsed as both BaseFrontMatter and, e.g., RuleFrontMatter.
+type fileCacheKey struct {
+	path        string
+	frontMatter reflect.Type
+}
+
+type fileCacheEntry struct {
+	size    int64
+	modTime time.Time
+	parsed  any // markdown.Markdown[T] for the key's frontmatter type T
+}
+
+// parseMarkdownFile is markdown.ParseMarkdownFile, cached in the Context's file cache.
+// Parsed files are shared between runs and must not be modified.
+func parseMarkdownFile[T any](cc *Context, path string, frontMatter *T) (markdown.Markdown[T], error) {
+	info, statErr := cc.stat(path)
+
+	cache := &cc.shared.files
+	key := fileCacheKey{path: path, frontMatter: reflect.TypeFor[T]()}
+
+	cache.mu.Lock()
+	entry, ok := cache.entries[key]
+	cache.mu.Unlock()
+
+	if statErr == nil && ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		if md, ok := entry.parsed.(markdown.Markdown[T]); ok {
+			*frontMatter = md.FrontMatter
+
+			return md, nil
+		}
+	}
+
+	md, err := readMarkdownFile(cc, path, frontMatter)
+	if err != nil {
+		return md, err
+	}
+
+	// A file that cannot be stat'ed cannot be checked for changes, so it is not cached.
+	if statErr != nil {
+		return md, nil
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	for evict := range cache.entries {
+		if len(cache.entries) < maxFileCacheEntries {
+			break
+		}
+
+		delete(cache.entries, evict)
+	}
+
+	cache.entries[key] = fileCacheEntry{size: info.Size(), modTime: info.ModTime(), parsed: md}
+
+	return md, nil
+}
+
+// readMarkdownFile parses the markdown file at path from the search path it belongs to.
+func readMarkdownFile[T any](cc *Context, path string, frontMatter *T) (markdown.Markdown[T], error) {
+	if !cc.isFSPath(path) {
+		md, err := markdown.ParseMarkdownFile(path, frontMatter)
+		if err != nil {
+			return md, fmt.Errorf("%w", err)
+		}
+
+		return md, nil
+	}
+
+	source, err := cc.readFile(path)
+	if err != nil {
+		return markdown.Markdown[T]{}, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+
+	md, err := markdown.ParseMarkdown(path, source, frontMatter)
+	if err != nil {
+		return md, fmt.Errorf("%w", err)
+	}
+
+	return md, nil
+}
@@ -0,0 +1,103 @@
+package codingcontext
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/skills"
+)
+
+// Hook is called at each stage of assembling a context, to change what is assembled without
+// forking, e.g. to redact rule content or to add a generated rule. Hooks are registered with
+// WithHooks and called in the order registered. An error returned by a hook stops the run.
+//
+// Embed BaseHook to implement only some of the stages. Hooks of a Context run concurrently
+// (see Context) must be safe for concurrent use. Parsed files are shared between runs, so a
+// hook changing a frontmatter map (e.g. Selectors) must replace the map rather than modify it.
+type Hook interface {
+	// SearchPathsResolved is called with the local directories of the search paths, including
+	// those listed in the manifest, once remote ones are downloaded and before the task is
+	// looked up. The search paths it returns are searched instead, e.g. to add or leave out
+	// a directory.
+	SearchPathsResolved(ctx context.Context, paths []SearchPath) ([]SearchPath, error)
+
+	// TaskFound is called with the task file once it is parsed. Changes to the task's
+	// frontmatter (e.g. its selectors) and content apply to the rest of the run.
+	TaskFound(ctx context.Context, path string, task *markdown.Markdown[markdown.TaskFrontMatter]) error
+
+	// RuleCandidate is called with each rule file that matches the selectors, before its
+	// bootstrap runs. Returning false leaves the rule out.
+	RuleCandidate(ctx context.Context, path string, rule markdown.Markdown[markdown.RuleFrontMatter]) (bool, error)
+
+	// RuleIncluded is called with each rule as it is included, after its parameters are
+	// expanded. Changes to the rule's content are what is included.
+	RuleIncluded(ctx context.Context, path string, rule *markdown.Markdown[markdown.RuleFrontMatter]) error
+
+	// CommandExpanded is called with each slash command of the task once its parameters are
+	// expanded. Changes to the command's content are what replaces the slash command.
+	CommandExpanded(ctx context.Context, path string, command *markdown.Markdown[markdown.CommandFrontMatter]) error
+
+	// SkillDiscovered is called with each skill that is listed. Changes to the skill (e.g.
+	// its description, or its content when inlined) are what is listed.
+	SkillDiscovered(ctx context.Context, path string, frontMatter markdown.SkillFrontMatter, skill *skills.Skill) error
+
+	// PromptBuilt is called with the result once the prompt is built. Changes to the result
+	// are what Run returns; a hook adding a rule should add it to both Rules and Prompt.
+	// If the hooks change Prompt, Tokens is then adjusted by the tokens they added or removed.
+	PromptBuilt(ctx context.Context, result *Result) error
+}
+
+// BaseHook implements every stage of Hook as a no-op. Embed it in a hook to implement
+// only the stages it needs.
+type BaseHook struct{}
+
+// SearchPathsResolved implements Hook, keeping the search paths.
+func (BaseHook) SearchPathsResolved(_ context.Context, paths []SearchPath) ([]SearchPath, error) {
+	return paths, nil
+}
+
+// TaskFound implements Hook.
+func (BaseHook) TaskFound(context.Context, string, *markdown.Markdown[markdown.TaskFrontMatter]) error {
+	return nil
+}
+
+// RuleCandidate implements Hook, keeping every rule.
+func (BaseHook) RuleCandidate(context.Context, string, markdown.Markdown[markdown.RuleFrontMatter]) (bool, error) {
+	return true, nil
+}
+
+// RuleIncluded implements Hook.
+func (BaseHook) RuleIncluded(context.Context, string, *markdown.Markdown[markdown.RuleFrontMatter]) error {
+	return nil
+}
+
+// CommandExpanded implements Hook.
+func (BaseHook) CommandExpanded(context.Context, string, *markdown.Markdown[markdown.CommandFrontMatter]) error {
+	return nil
+}
+
+// SkillDiscovered implements Hook.
+func (BaseHook) SkillDiscovered(context.Context, string, markdown.SkillFrontMatter, *skills.Skill) error {
+	return nil
+}
+
+// PromptBuilt implements Hook.
+func (BaseHook) PromptBuilt(context.Context, *Result) error {
+	return nil
+}
+
+// runHooks calls stage for each hook, stopping at the first error.
+func (cc *Context) runHooks(stage, path string, call func(Hook) error) error {
+	for _, hook := range cc.hooks {
+		if err := call(hook); err != nil {
+			if path == "" {
+				return fmt.Errorf("%s hook failed: %w", stage, err)
+			}
+
+			return fmt.Errorf("%s hook failed for %s: %w", stage, path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package codingcontext
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/skills"
+)
+
+var errHookTest = errors.New("hook test error")
+
+// recordingHook records the stages it is called at and changes what is assembled.
+type recordingHook struct {
+	BaseHook
+
+	mu     sync.Mutex
+	stages []string
+}
+
+func (h *recordingHook) record(stage, path string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stages = append(h.stages, stage+" "+filepath.Base(path))
+}
+
+func (h *recordingHook) SearchPathsResolved(_ context.Context, paths []SearchPath) ([]SearchPath, error) {
+	h.record("paths", "")
+
+	return paths, nil
+}
+
+func (h *recordingHook) TaskFound(_ context.Context, path string, task *markdown.TaskMarkdown) error {
+	h.record("task", path)
+
+	// Replace, rather than modify, the selectors map.
+	task.FrontMatter.Selectors = map[string]any{"team": "platform"}
+	task.Content += "\n/greet\n"
+
+	return nil
+}
+
+func (h *recordingHook) RuleCandidate(_ context.Context, path string, rule markdown.RuleMarkdown) (bool, error) {
+	h.record("candidate", path)
+
+	return rule.FrontMatter.Name != "vetoed", nil
+}
+
+func (h *recordingHook) RuleIncluded(_ context.Context, path string, rule *markdown.RuleMarkdown) error {
+	h.record("rule", path)
+
+	rule.Content = strings.ReplaceAll(rule.Content, "SECRET", "[redacted]")
+
+	return nil
+}
+
+func (h *recordingHook) CommandExpanded(
+	_ context.Context, path string, command *markdown.Markdown[markdown.CommandFrontMatter],
+) error {
+	h.record("command", path)
+
+	command.Content = strings.ToUpper(command.Content)
+
+	return nil
+}
+
+func (h *recordingHook) SkillDiscovered(
+	_ context.Context, path string, _ markdown.SkillFrontMatter, skill *skills.Skill,
+) error {
+	h.record("skill", path)
+
+	skill.Description = "Changed by a hook."
+
+	return nil
+}
+
+func (h *recordingHook) PromptBuilt(_ context.Context, result *Result) error {
+	h.record("prompt", "")
+
+	generated := markdown.FromContent(markdown.RuleFrontMatter{}, "# Generated Rule\n")
+	result.Rules = append(result.Rules, generated)
+	result.Prompt = generated.Content + result.Prompt
+
+	return nil
+}
+
+func createHookFixture(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "Do the task.")
+	createCommand(t, dir, "greet", "", "hello from a command")
+	createRule(t, dir, ".agents/rules/team.md", "team: platform", "# Team Rule with SECRET")
+	createRule(t, dir, ".agents/rules/other.md", "team: other", "# Other Team Rule")
+	createRule(t, dir, ".agents/rules/vetoed.md", "", "# Vetoed Rule")
+	createSkill(t, dir, ".agents/skills/my-skill",
+		"---\nname: my-skill\ndescription: A skill.\n---\n# My Skill\n")
+
+	return dir
+}
+
+func TestWithHooks(t *testing.T) {
+	t.Parallel()
+
+	dir := createHookFixture(t)
+	hook := &recordingHook{}
+
+	result, err := New(WithSearchPaths(dir), WithHooks(hook)).Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	for _, want := range []string{"# Generated Rule", "# Team Rule with [redacted]", "HELLO FROM A COMMAND"} {
+		if !strings.Contains(result.Prompt, want) {
+			t.Errorf("expected prompt to contain %q, got:\n%s", want, result.Prompt)
+		}
+	}
+
+	for _, unwanted := range []string{"SECRET", "# Other Team Rule", "# Vetoed Rule"} {
+		if strings.Contains(result.Prompt, unwanted) {
+			t.Errorf("expected prompt not to contain %q, got:\n%s", unwanted, result.Prompt)
+		}
+	}
+
+	if len(result.Rules) != 2 {
+		t.Errorf("expected the team rule and the generated rule, got %d rules", len(result.Rules))
+	}
+
+	if len(result.Skills.Skills) != 1 || result.Skills.Skills[0].Description != "Changed by a hook." {
+		t.Errorf("expected the skill description changed by the hook, got %+v", result.Skills.Skills)
+	}
+
+	// Commands are expanded when the task is built, after rules and skills.
+	want := []string{
+		"paths .", "task task.md", "candidate team.md", "candidate vetoed.md", "rule team.md",
+		"skill SKILL.md", "command greet.md", "prompt .",
+	}
+	if strings.Join(hook.stages, ", ") != strings.Join(want, ", ") {
+		t.Errorf("hook stages = %v, want %v", hook.stages, want)
+	}
+}
+
+// failingHook fails at the rule-included stage.
+type failingHook struct {
+	BaseHook
+}
+
+func (failingHook) RuleIncluded(context.Context, string, *markdown.RuleMarkdown) error {
+	return errHookTest
+}
+
+func TestWithHooks_ErrorStopsRun(t *testing.T) {
+	t.Parallel()
+
+	dir := createHookFixture(t)
+
+	_, err := New(WithSearchPaths(dir), WithHooks(failingHook{})).Run(context.Background(), "task")
+	if !errors.Is(err, errHookTest) {
+		t.Fatalf("expected the hook's error, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "rule included hook failed") {
+		t.Errorf("expected the error to name the stage, got %v", err)
+	}
+}
+
+// searchPathHook searches another directory, and adds text to the prompt.
+type searchPathHook struct {
+	BaseHook
+
+	dir, text string
+}
+
+func (h searchPathHook) SearchPathsResolved(_ context.Context, paths []SearchPath) ([]SearchPath, error) {
+	return append(paths, SearchPath{Path: h.dir}), nil
+}
+
+func (h searchPathHook) PromptBuilt(_ context.Context, result *Result) error {
+	result.Prompt += h.text
+
+	return nil
+}
+
+func TestWithHooks_SearchPathsAndTokens(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "Do the task.")
+
+	extra := t.TempDir()
+	createRule(t, extra, ".agents/rules/extra.md", "", "# Extra Rule")
+
+	cc := New(WithSearchPaths(dir))
+
+	base, err := cc.Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	hook := searchPathHook{dir: extra, text: strings.Repeat("word ", 80)}
+
+	result, err := cc.With(WithHooks(hook)).Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(result.Rules) != 1 || !strings.Contains(result.Prompt, "# Extra Rule") {
+		t.Errorf("the search path added by the hook was not searched:\n%s", result.Prompt)
+	}
+
+	// The hook's text is 400 characters, about 100 tokens, on top of the extra rule.
+	if result.Tokens < base.Tokens+100 {
+		t.Errorf("Tokens = %d, want at least %d for the text the hook added", result.Tokens, base.Tokens+100)
+	}
+}
@@ -3,7 +3,6 @@ package codingcontext
 import (
 	"context"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -45,7 +44,7 @@ type LintError struct {
 	Path    string // May be empty
 	Kind    LintErrorKind
 	Message string
-	Line    int // 1-indexed; 0 means unknown (only set for parse errors)
+	Line    int // 1-indexed; 0 means unknown (set for parse and skill validation errors)
 	Column  int // 1-indexed; 0 means unknown (only set for parse errors)
 }
 
@@ -75,6 +74,10 @@ func (lc *lintCollector) recordError(path string, kind LintErrorKind, message st
 	lc.errors = append(lc.errors, LintError{Path: path, Kind: kind, Message: message})
 }
 
+func (lc *lintCollector) recordErrorAt(path string, kind LintErrorKind, message string, line int) {
+	lc.errors = append(lc.errors, LintError{Path: path, Kind: kind, Message: message, Line: line})
+}
+
 func (lc *lintCollector) recordParseError(pe *markdown.ParseError) {
 	lc.errors = append(lc.errors, LintError{
 		Path:    pe.File,
@@ -111,12 +114,21 @@ func (lc *lintCollector) recordFrontmatterValues(fm markdown.BaseFrontMatter) {
 // otherwise performs the same file loading, parsing, and selector matching as Run().
 // Fatal errors (e.g. task not found) are returned as errors; structural problems
 // are collected in LintResult.Errors.
+// Lint is safe for concurrent use.
 func (cc *Context) Lint(ctx context.Context, taskName string) (*LintResult, error) {
+	s := cc.session()
+	defer s.endRun()
+
+	return s.lint(ctx, taskName)
+}
+
+// lint lints taskName on a session returned by session.
+func (cc *Context) lint(ctx context.Context, taskName string) (*LintResult, error) {
 	cc.lintMode = true
 	cc.lintCollector = &lintCollector{}
 	cc.doBootstrap = true // ensure rule + skill discovery runs
 
-	result, err := cc.Run(ctx, taskName)
+	result, err := cc.run(ctx, taskName)
 	if err != nil {
 		return nil, err
 	}
@@ -141,7 +153,7 @@ func (cc *Context) recordLintBootstrap(rulePath, frontmatterBootstrap string) {
 	}
 
 	bootstrapFilePath := strings.TrimSuffix(rulePath, filepath.Ext(rulePath)) + "-bootstrap"
-	if _, err := os.Stat(bootstrapFilePath); err == nil && cc.lintCollector != nil {
+	if _, err := cc.stat(bootstrapFilePath); err == nil && cc.lintCollector != nil {
 		cc.lintCollector.recordFile(bootstrapFilePath, LoadedFileKindBootstrap)
 	}
 }
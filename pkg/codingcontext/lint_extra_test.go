@@ -19,9 +19,10 @@ func TestLint_FrontmatterListValue(t *testing.T) {
 	// Rule with a list-valued frontmatter field
 	createRule(t, dir, ".agents/rules/multi-lang.md", "languages:\n  - go\n  - python\n", "Multi-language rule.")
 
-	cc := newLintContext(dir)
+	// Lint on a session, which holds the lint state.
+	cc := newLintContext(dir).session()
 
-	result, err := cc.Lint(context.Background(), "task")
+	result, err := cc.lint(context.Background(), "task")
 	if err != nil {
 		t.Fatalf("Lint() error: %v", err)
 	}
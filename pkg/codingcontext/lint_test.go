@@ -106,6 +106,29 @@ func TestLint_BootstrapFileSkipped(t *testing.T) {
 	}
 }
 
+func TestLint_TaskAndSkillBootstrapFilesSkipped(t *testing.T) {
+	t.Parallel()
+	dir := lintTestDir(t)
+
+	createTask(t, dir, "mytask", "", "Do something.")
+	createBootstrapScript(t, dir, ".agents/tasks/mytask.md", "#!/bin/sh\nexit 1\n")
+	createSkill(t, dir, ".agents/skills/myskill", "---\nname: myskill\ndescription: A skill\n---\nBody")
+	createBootstrapScript(t, dir, ".agents/skills/myskill/SKILL.md", "#!/bin/sh\nexit 1\n")
+
+	cc := newLintContext(dir)
+
+	result, err := cc.Lint(context.Background(), "mytask")
+	if err != nil {
+		t.Fatalf("Lint() returned error (bootstrap should be skipped): %v", err)
+	}
+
+	for _, name := range []string{"mytask-bootstrap", "SKILL-bootstrap"} {
+		if !hasLoadedFile(result, name, LoadedFileKindBootstrap) {
+			t.Errorf("expected %s in LoadedFiles, got %+v", name, result.LoadedFiles)
+		}
+	}
+}
+
 func TestLint_CommandExpansionSkipped(t *testing.T) {
 	t.Parallel()
 	dir := lintTestDir(t)
@@ -0,0 +1,36 @@
+package codingcontext
+
+import "strings"
+
+// Markers around the rules that the CLI's -w flag writes into an agent's rules file. The
+// file is also a rule file, e.g. AGENTS.md, so the section between them is left out when
+// the file is read; otherwise each -w would read back, and write again, what it wrote before.
+const (
+	ManagedSectionBegin = "<!-- coding-context:begin -->"
+	ManagedSectionEnd   = "<!-- coding-context:end -->"
+)
+
+// stripManagedSections returns content without its managed sections, each including its
+// markers and the newline after the end marker. A begin marker without an end marker is kept.
+func stripManagedSections(content string) string {
+	var kept strings.Builder
+
+	for {
+		begin := strings.Index(content, ManagedSectionBegin)
+		if begin < 0 {
+			break
+		}
+
+		end := strings.Index(content[begin:], ManagedSectionEnd)
+		if end < 0 {
+			break
+		}
+
+		kept.WriteString(content[:begin])
+		content = strings.TrimPrefix(content[begin+end+len(ManagedSectionEnd):], "\n")
+	}
+
+	kept.WriteString(content)
+
+	return kept.String()
+}
@@ -0,0 +1,59 @@
+package codingcontext
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestStripManagedSections(t *testing.T) {
+	t.Parallel()
+
+	section := ManagedSectionBegin + "\n# Generated\n" + ManagedSectionEnd + "\n"
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "no section", content: "# Notes\n", want: "# Notes\n"},
+		{name: "only a section", content: section, want: ""},
+		{name: "section between notes", content: "# Notes\n\n" + section + "\n# More\n", want: "# Notes\n\n\n# More\n"},
+		{name: "two sections", content: section + "# Notes\n" + section, want: "# Notes\n"},
+		{name: "no end marker", content: ManagedSectionBegin + "\n# Notes\n", want: ManagedSectionBegin + "\n# Notes\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := stripManagedSections(tt.content); got != tt.want {
+				t.Errorf("stripManagedSections() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_LeavesOutManagedSections(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "Task")
+	createRule(t, dir, ".agents/rules/shared.md", "", "# Shared Rule")
+	createRule(t, dir, "AGENTS.md", "",
+		"# Project Notes\n\n"+ManagedSectionBegin+"\n# Shared Rule\n"+ManagedSectionEnd+"\n")
+	createRule(t, dir, "CLAUDE.local.md", "", ManagedSectionBegin+"\n# Shared Rule\n"+ManagedSectionEnd+"\n")
+
+	result, err := New(WithSearchPaths(dir)).Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(result.Rules) != 2 {
+		t.Errorf("Run() included %d rules, want 2", len(result.Rules))
+	}
+
+	if n := strings.Count(result.Prompt, "# Shared Rule"); n != 1 || !strings.Contains(result.Prompt, "# Project Notes") {
+		t.Errorf("Run() did not leave out the managed sections:\n%s", result.Prompt)
+	}
+}
@@ -63,6 +63,13 @@ type TaskFrontMatter struct {
 	// any active selector are included by default. Defaults to true (current behaviour).
 	// Set to false to require an explicit selector match (strict/opt-in mode).
 	IncludeUnmatched *bool `json:"include_unmatched,omitempty" yaml:"include_unmatched,omitempty"`
+
+	// Skills lists skills whose full content is inlined into the prompt
+	// Other discovered skills are only advertised by name, description, and location
+	Skills []string `json:"skills,omitempty" yaml:"skills,omitempty"`
+
+	// BootstrapFrontMatter configures the task's bootstrap script, which runs before rules are discovered
+	BootstrapFrontMatter `yaml:",inline"`
 }
 
 // populateContent unmarshals raw JSON into the inline Content map.
@@ -130,9 +137,65 @@ type RuleFrontMatter struct {
 	// Defaults to true if not specified
 	ExpandParams *bool `json:"expand,omitempty" yaml:"expand,omitempty"`
 
+	// BootstrapFrontMatter configures the rule's bootstrap script
+	BootstrapFrontMatter `yaml:",inline"`
+}
+
+// BootstrapFrontMatter holds the frontmatter fields that control bootstrap scripts.
+type BootstrapFrontMatter struct {
 	// Bootstrap contains a shell script to execute before including the rule
 	// This is preferred over file-based bootstrap scripts
 	Bootstrap string `json:"bootstrap,omitempty" yaml:"bootstrap,omitempty"`
+
+	// RunOnce skips the bootstrap when neither the script nor its declared inputs
+	// have changed since the last successful run
+	RunOnce bool `json:"run_once,omitempty" yaml:"run_once,omitempty"`
+
+	// BootstrapCache sets where a successful run_once bootstrap counts as done: "project"
+	// (the default) for the working directory and rule file it ran for, or "machine" for
+	// any project on the machine, e.g. for a script that installs a tool globally
+	// Setting it implies run_once
+	BootstrapCache BootstrapCacheScope `json:"bootstrap_cache,omitempty" yaml:"bootstrap_cache,omitempty"`
+
+	// BootstrapInputs lists files or directories whose content is hashed together
+	// with the script to decide whether a run_once bootstrap must run again
+	// Relative paths are resolved against the directory the script runs in
+	BootstrapInputs []string `json:"bootstrap_inputs,omitempty" yaml:"bootstrap_inputs,omitempty"`
+
+	// Creates names a path the bootstrap produces; the bootstrap is skipped if it exists
+	// Relative paths are resolved against the directory the script runs in
+	Creates string `json:"creates,omitempty" yaml:"creates,omitempty"`
+
+	// BootstrapOutput captures the bootstrap's stdout into the assembled context
+	// By default stdout is sent to stderr and discarded
+	BootstrapOutput *BootstrapOutput `json:"bootstrap_output,omitempty" yaml:"bootstrap_output,omitempty"`
+
+	// DependsOn lists the names of rules whose bootstraps must succeed before this one runs
+	// Bootstraps without dependencies between them run in parallel
+	DependsOn []string `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+}
+
+// BootstrapCacheScope is where a successful run_once bootstrap counts as done.
+type BootstrapCacheScope string
+
+// Bootstrap cache scopes.
+const (
+	BootstrapCacheProject BootstrapCacheScope = "project"
+	BootstrapCacheMachine BootstrapCacheScope = "machine"
+)
+
+// BootstrapOutput describes how captured bootstrap stdout is used.
+type BootstrapOutput struct {
+	// Param names a parameter set to the captured output (trailing newlines trimmed)
+	// It can be referenced as ${param} in every rule, the task, and commands
+	Param string `json:"param,omitempty" yaml:"param,omitempty"`
+
+	// Append adds the captured output to the end of the rule content
+	Append bool `json:"append,omitempty" yaml:"append,omitempty"`
+
+	// MaxBytes limits how much output is captured; longer output is truncated
+	// Defaults to 16384
+	MaxBytes int `json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`
 }
 
 // UnmarshalJSON custom unmarshaler that populates both typed fields and Content map.
@@ -161,6 +224,9 @@ type SkillFrontMatter struct {
 
 	// AllowedTools is a space-delimited list of pre-approved tools (optional, experimental)
 	AllowedTools string `json:"allowed_tools,omitempty" yaml:"allowed_tools,omitempty"`
+
+	// BootstrapFrontMatter configures the skill's bootstrap script, which runs when the skill is discovered
+	BootstrapFrontMatter `yaml:",inline"`
 }
 
 // UnmarshalJSON custom unmarshaler that populates both typed fields and Content map.
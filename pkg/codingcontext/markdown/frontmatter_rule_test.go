@@ -54,6 +54,22 @@ func validateRuleExtraFields(t *testing.T, fm RuleFrontMatter) {
 	}
 }
 
+func validateRuleBootstrapCacheFields(t *testing.T, fm RuleFrontMatter) {
+	t.Helper()
+
+	if !fm.RunOnce {
+		t.Error("RunOnce should be true")
+	}
+
+	if len(fm.BootstrapInputs) != 1 || fm.BootstrapInputs[0] != "go.mod" {
+		t.Errorf("BootstrapInputs = %v, want [go.mod]", fm.BootstrapInputs)
+	}
+
+	if fm.Creates != "bin/tool" {
+		t.Errorf("Creates = %q, want bin/tool", fm.Creates)
+	}
+}
+
 func TestRuleFrontMatter_Marshal(t *testing.T) {
 	t.Parallel()
 
@@ -248,6 +264,11 @@ func TestRuleFrontMatter_UnmarshalJSON(t *testing.T) {
 			input:    `{"agent": "copilot", "custom-key": "custom-val"}`,
 			validate: validateRuleExtraFields,
 		},
+		{
+			name:     "bootstrap cache fields",
+			input:    `{"bootstrap": "echo hi", "run_once": true, "bootstrap_inputs": ["go.mod"], "creates": "bin/tool"}`,
+			validate: validateRuleBootstrapCacheFields,
+		},
 		{name: "invalid JSON returns error", input: `{bad json`, wantErr: true},
 	}
 
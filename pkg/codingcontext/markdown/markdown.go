@@ -3,6 +3,7 @@ package markdown
 import (
 	"bytes"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
@@ -76,6 +77,21 @@ func ParseMarkdownFile[T any](path string, frontMatter *T) (Markdown[T], error)
 		return Markdown[T]{}, fmt.Errorf("failed to open file %s: %w", path, err)
 	}
 
+	return ParseMarkdown(path, source, frontMatter)
+}
+
+// ParseMarkdownFS is ParseMarkdownFile for the file named path in fsys, such as an embed.FS.
+func ParseMarkdownFS[T any](fsys fs.FS, path string, frontMatter *T) (Markdown[T], error) {
+	source, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return Markdown[T]{}, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+
+	return ParseMarkdown(path, source, frontMatter)
+}
+
+// ParseMarkdown parses the markdown source of the file at path; path is only used in errors.
+func ParseMarkdown[T any](path string, source []byte, frontMatter *T) (Markdown[T], error) {
 	// Parse with goldmark+meta+taskparser in a single pass: meta extracts frontmatter,
 	// taskparser.Extension captures task structure (slash commands) from the body.
 	pctx := parser.NewContext()
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
 )
@@ -250,6 +251,34 @@ func TestParseMarkdownFile_FileNotFound(t *testing.T) {
 	}
 }
 
+func TestParseMarkdownFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"rules/rule.md": {Data: []byte("---\nname: embedded\n---\n# Embedded Rule\n")},
+	}
+
+	var frontmatter BaseFrontMatter
+
+	md, err := ParseMarkdownFS(fsys, "rules/rule.md", &frontmatter)
+	if err != nil {
+		t.Fatalf("ParseMarkdownFS() error = %v", err)
+	}
+
+	if frontmatter.Name != "embedded" {
+		t.Errorf("ParseMarkdownFS() name = %q, want %q", frontmatter.Name, "embedded")
+	}
+
+	if md.Content != "# Embedded Rule\n" {
+		t.Errorf("ParseMarkdownFS() content = %q, want %q", md.Content, "# Embedded Rule\n")
+	}
+
+	if _, err := ParseMarkdownFS(fsys, "rules/missing.md", &frontmatter); err == nil ||
+		!strings.Contains(err.Error(), "rules/missing.md") {
+		t.Errorf("ParseMarkdownFS() error should contain file path, got: %v", err)
+	}
+}
+
 func TestParseMarkdownFile_ErrorsIncludeFilePath(t *testing.T) {
 	t.Parallel()
 
@@ -635,9 +635,10 @@ func TestRun_NamespacedTask_SetsNamespaceSelector(t *testing.T) {
 	dir := t.TempDir()
 	createNamespaceTask(t, dir, "myteam", "work", "Do work.")
 
-	cc := newRunContext(dir)
+	// Run on a session, which holds the selectors adjusted for the task.
+	cc := newRunContext(dir).session()
 
-	_, err := cc.Run(context.Background(), "myteam/work")
+	_, err := cc.run(context.Background(), "myteam/work")
 	if err != nil {
 		t.Fatalf("Run() error: %v", err)
 	}
@@ -653,9 +654,10 @@ func TestRun_NamespacedTask_SetsBothTaskNameForms(t *testing.T) {
 	dir := t.TempDir()
 	createNamespaceTask(t, dir, "myteam", "fix-bug", "Fix the bug.")
 
-	cc := newRunContext(dir)
+	// Run on a session, which holds the selectors adjusted for the task.
+	cc := newRunContext(dir).session()
 
-	_, err := cc.Run(context.Background(), "myteam/fix-bug")
+	_, err := cc.run(context.Background(), "myteam/fix-bug")
 	if err != nil {
 		t.Fatalf("Run() error: %v", err)
 	}
@@ -676,9 +678,10 @@ func TestRun_NonNamespacedTask_NamespaceSelectorIsEmpty(t *testing.T) {
 	dir := t.TempDir()
 	createTask(t, dir, "plain", "", "Plain task.")
 
-	cc := newRunContext(dir)
+	// Run on a session, which holds the selectors adjusted for the task.
+	cc := newRunContext(dir).session()
 
-	_, err := cc.Run(context.Background(), "plain")
+	_, err := cc.run(context.Background(), "plain")
 	if err != nil {
 		t.Fatalf("Run() error: %v", err)
 	}
@@ -1,7 +1,9 @@
 package codingcontext
 
 import (
+	"io/fs"
 	"log/slog"
+	"time"
 
 	"github.com/kitproj/coding-context-cli/pkg/codingcontext/selectors"
 	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
@@ -53,6 +55,16 @@ func WithLenientSearchPaths(paths ...string) Option {
 	}
 }
 
+// WithSearchFS adds a strict search path whose files are read from fsys, such as an embed.FS
+// holding default rules, rather than from the local file system. The search path's directories
+// (e.g. .agents/rules) are looked up from the root of fsys; use fs.Sub for a subdirectory.
+// The name identifies fsys in logs, results, and lint output, and should not be a local path.
+func WithSearchFS(name string, fsys fs.FS) Option {
+	return func(c *Context) {
+		c.searchPaths = append(c.searchPaths, SearchPath{Path: name, FS: fsys})
+	}
+}
+
 // WithLogger sets the logger.
 func WithLogger(logger *slog.Logger) Option {
 	return func(c *Context) {
@@ -76,6 +88,58 @@ func WithBootstrap(doBootstrap bool) Option {
 	}
 }
 
+// WithBootstrapSandbox configures isolation for bootstrap scripts.
+// See BootstrapSandbox for the restrictions applied when sandboxing is enabled.
+func WithBootstrapSandbox(sandbox BootstrapSandbox) Option {
+	return func(c *Context) {
+		c.sandbox = sandbox
+	}
+}
+
+// WithSession makes the Context a session: remote search paths are downloaded by its first
+// run and reused by later and concurrent runs, until Close. Use it for a Context that is run
+// many times, e.g. by a server. Without it, each run downloads the remote search paths and
+// removes them when it finishes, so runs with remote search paths must not be concurrent.
+func WithSession(session bool) Option {
+	return func(c *Context) {
+		c.keepSession = session
+	}
+}
+
+// WithRefresh makes a session download its remote search paths again when they are older
+// than interval, so that a long-running server sees changes to them. The downloads are
+// replaced once the runs using them have finished. Zero, the default, keeps them until Close.
+func WithRefresh(interval time.Duration) Option {
+	return func(c *Context) {
+		c.refresh = interval
+	}
+}
+
+// WithBootstrapCacheDir sets the directory used to record successful run_once bootstraps.
+// Defaults to coding-context/bootstrap under the user cache directory.
+func WithBootstrapCacheDir(dir string) Option {
+	return func(c *Context) {
+		c.bootstrapCache = dir
+	}
+}
+
+// WithForceBootstrap runs bootstrap scripts even when their run_once cache entry or
+// creates path says they can be skipped.
+func WithForceBootstrap(force bool) Option {
+	return func(c *Context) {
+		c.forceBootstrap = force
+	}
+}
+
+// WithBootstrapConcurrency sets how many bootstrap scripts run at once. Zero or a
+// negative value runs them one at a time, unless a rule uses depends_on, and then
+// up to the number of CPUs at once.
+func WithBootstrapConcurrency(n int) Option {
+	return func(c *Context) {
+		c.bootstrapJobs = n
+	}
+}
+
 // WithAgent sets the target agent, which excludes that agent's own rules.
 // Agent-specific paths are treated as strict (errors are fatal).
 // Mutually exclusive with WithLenientAgent.
@@ -107,6 +171,39 @@ func WithLenientAgent(agent Agent) Option {
 	}
 }
 
+// WithAgentSkills includes skills from the target agent's own skills path (e.g. .claude/skills
+// for claude). By default they are excluded because the agent loads them itself.
+func WithAgentSkills(include bool) Option {
+	return func(c *Context) {
+		c.agentSkills = include
+	}
+}
+
+// WithExcludeAgentRules leaves out rules from the target agent's own paths (e.g. CLAUDE.md
+// for claude), which the agent reads itself. By default all rules are included.
+func WithExcludeAgentRules(exclude bool) Option {
+	return func(c *Context) {
+		c.excludeOwnRules = exclude
+	}
+}
+
+// WithInlineSkills inlines the full content of the named skills, including their references/
+// and scripts/ files, into the prompt instead of only advertising their location.
+// Skills listed in a task's skills frontmatter field are inlined as well.
+func WithInlineSkills(names ...string) Option {
+	return func(c *Context) {
+		c.inlineSkills = append(c.inlineSkills, names...)
+	}
+}
+
+// WithSkillsListing configures how available skills are listed in the prompt.
+// See SkillsListing for the formats and per-agent omission.
+func WithSkillsListing(listing SkillsListing) Option {
+	return func(c *Context) {
+		c.skillsListing = listing
+	}
+}
+
 // WithUserPrompt sets the user prompt to append to the task.
 func WithUserPrompt(userPrompt string) Option {
 	return func(c *Context) {
@@ -114,6 +211,15 @@ func WithUserPrompt(userPrompt string) Option {
 	}
 }
 
+// WithLiteralUserPrompt appends the user prompt as plain text: it is not scanned for
+// slash commands and neither parameters, !`cmd` nor @path references in it are expanded.
+// Use it when the prompt comes from an untrusted caller.
+func WithLiteralUserPrompt(literal bool) Option {
+	return func(c *Context) {
+		c.literalPrompt = literal
+	}
+}
+
 // WithLint enables lint mode: skips bootstrap script execution and shell command
 // expansion (!`cmd`). File access is tracked and non-fatal structural errors are
 // collected in LintResult. Use Lint() instead of Run() to retrieve results.
@@ -122,3 +228,18 @@ func WithLint(lint bool) Option {
 		c.lintMode = lint
 	}
 }
+
+// WithHooks adds hooks, called in order at each stage of assembling a context. See Hook.
+func WithHooks(hooks ...Hook) Option {
+	return func(c *Context) {
+		c.hooks = append(c.hooks, hooks...)
+	}
+}
+
+// WithSources adds sources, run in order once the task is found. The rules and skills they
+// provide are selected like rule and skill files. See Source.
+func WithSources(sources ...Source) Option {
+	return func(c *Context) {
+		c.sources = append(c.sources, sources...)
+	}
+}
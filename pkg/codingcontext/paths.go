@@ -1,6 +1,9 @@
 package codingcontext
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"slices"
+)
 
 // namespacedTaskSearchPaths returns task search paths for the given namespace.
 // Namespace task dir is searched first; global task dirs follow as fallback.
@@ -61,7 +64,7 @@ func rulePaths(dir string) []string {
 	var paths []string
 
 	// Iterate through all configured agents
-	for _, config := range getAgentsPaths() {
+	for _, config := range sortedAgentsPaths() {
 		// Add each rule path for this agent
 		for _, rulePath := range config.rulesPaths {
 			paths = append(paths, filepath.Join(dir, rulePath))
@@ -77,7 +80,7 @@ func taskSearchPaths(dir string) []string {
 	var paths []string
 
 	// Iterate through all configured agents
-	for _, config := range getAgentsPaths() {
+	for _, config := range sortedAgentsPaths() {
 		if config.tasksPath != "" {
 			paths = append(paths, filepath.Join(dir, config.tasksPath))
 		}
@@ -92,7 +95,7 @@ func commandSearchPaths(dir string) []string {
 	var paths []string
 
 	// Iterate through all configured agents
-	for _, config := range getAgentsPaths() {
+	for _, config := range sortedAgentsPaths() {
 		if config.commandsPath != "" {
 			paths = append(paths, filepath.Join(dir, config.commandsPath))
 		}
@@ -107,7 +110,7 @@ func skillSearchPaths(dir string) []string {
 	var paths []string
 
 	// Iterate through all configured agents
-	for _, config := range getAgentsPaths() {
+	for _, config := range sortedAgentsPaths() {
 		if config.skillsPath != "" {
 			paths = append(paths, filepath.Join(dir, config.skillsPath))
 		}
@@ -115,3 +118,33 @@ func skillSearchPaths(dir string) []string {
 
 	return paths
 }
+
+// WatchPaths returns the local files and directories that runs of cc read tasks, rules,
+// commands, and skills from, and the manifest file if it is local, so that a caller can
+// watch them for changes. Remote and fs.FS search paths are left out. Paths that do not
+// exist are included, because creating them changes what a run reads.
+func (cc *Context) WatchPaths() []string {
+	var paths []string
+
+	for _, sp := range cc.searchPaths {
+		if sp.FS != nil || !isLocalPath(sp.Path) {
+			continue
+		}
+
+		dir := normalizeLocalPath(sp.Path)
+
+		paths = append(paths, filepath.Join(dir, ".agents/namespaces"))
+		paths = append(paths, taskSearchPaths(dir)...)
+		paths = append(paths, rulePaths(dir)...)
+		paths = append(paths, commandSearchPaths(dir)...)
+		paths = append(paths, skillSearchPaths(dir)...)
+	}
+
+	if cc.manifestURL != "" && isLocalPath(cc.manifestURL) {
+		paths = append(paths, normalizeLocalPath(cc.manifestURL))
+	}
+
+	slices.Sort(paths)
+
+	return slices.Compact(paths)
+}
@@ -3,6 +3,7 @@ package codingcontext
 import (
 	"path/filepath"
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -126,3 +127,35 @@ func TestPathsUseAgentsPaths(t *testing.T) {
 		t.Error("skillSearchPaths should return at least one path")
 	}
 }
+
+func TestWatchPaths(t *testing.T) {
+	t.Parallel()
+
+	cc := New(
+		WithSearchPaths("file://"+testProjectDir, "git::https://github.com/org/rules.git"),
+		WithLenientSearchPaths("/home/user"),
+		WithManifestURL("/project/manifest.txt"),
+	)
+
+	paths := cc.WatchPaths()
+
+	for _, want := range []string{
+		filepath.Join(testProjectDir, ".agents", "rules"),
+		filepath.Join(testProjectDir, ".agents", "tasks"),
+		filepath.Join(testProjectDir, ".agents", "commands"),
+		filepath.Join(testProjectDir, ".agents", "skills"),
+		filepath.Join(testProjectDir, ".agents", "namespaces"),
+		filepath.Join("/home/user", ".agents", "rules"),
+		"/project/manifest.txt",
+	} {
+		if !slices.Contains(paths, want) {
+			t.Errorf("WatchPaths() does not contain %s: %v", want, paths)
+		}
+	}
+
+	for _, path := range paths {
+		if strings.Contains(path, "github.com") {
+			t.Errorf("WatchPaths() contains remote path %s", path)
+		}
+	}
+}
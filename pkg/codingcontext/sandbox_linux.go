@@ -0,0 +1,267 @@
+//go:build linux
+
+package codingcontext
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Landlock syscall numbers are shared by all Linux architectures.
+const (
+	sysLandlockCreateRuleset = 444
+	sysLandlockAddRule       = 445
+	sysLandlockRestrictSelf  = 446
+
+	landlockCreateRulesetVersion = 1 << 0
+	landlockRulePathBeneath      = 1
+
+	prSetNoNewPrivs = 38
+	oPath           = 0o10000000 // O_PATH; not exported by the syscall package
+)
+
+// Landlock filesystem access rights (see linux/landlock.h).
+const (
+	accessFSExecute    = 1 << 0
+	accessFSWriteFile  = 1 << 1
+	accessFSReadFile   = 1 << 2
+	accessFSReadDir    = 1 << 3
+	accessFSMakeSym    = 1 << 12 // Last right of ABI v1
+	accessFSRefer      = 1 << 13 // ABI v2
+	accessFSTruncate   = 1 << 14 // ABI v3
+	accessFSIoctlDev   = 1 << 15 // ABI v5
+	accessFSReadAccess = accessFSExecute | accessFSReadFile | accessFSReadDir
+	accessFSDevAccess  = accessFSReadFile | accessFSWriteFile | accessFSTruncate | accessFSIoctlDev
+)
+
+// systemDirs are the directories sandboxed scripts may read and execute from, for the shell,
+// tools, and shared libraries. Those that do not exist are skipped.
+var systemDirs = []string{"/usr", "/lib", "/lib64", "/bin", "/sbin", "/etc"}
+
+// runConfined runs cmd with Landlock restricting file access to c.writableDirs, reads of
+// systemDirs and c.readableFiles, and /dev, with a seccomp filter denying the system calls
+// in seccompDeniedSyscalls and, unless network is allowed, in new user and network
+// namespaces.
+//
+// Landlock restricts the calling thread and is inherited by processes it forks, so
+// the command is started from a dedicated, locked OS thread. That thread is never
+// unlocked: once restricted it must not be reused by the Go scheduler, so the runtime
+// terminates it when the goroutine exits.
+func runConfined(cmd *exec.Cmd, c confinement, run func(*exec.Cmd) error) error {
+	// No uid/gid mappings are written: the parent is already restricted by Landlock and
+	// could not write /proc/<pid>/uid_map. The script keeps its host credentials for
+	// file access and merely appears as the overflow user inside the namespace.
+	if !c.allowNetwork {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET}
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		runtime.LockOSThread()
+
+		if err := restrictThread(c.writableDirs, c.readableFiles); err != nil {
+			errCh <- err
+
+			return
+		}
+
+		errCh <- run(cmd)
+	}()
+
+	return <-errCh
+}
+
+// restrictThread applies a Landlock ruleset and a seccomp filter to the current thread. The
+// ruleset allows reading and executing beneath systemDirs and readableFiles, device access
+// under /dev, and full access beneath writableDirs.
+func restrictThread(writableDirs, readableFiles []string) error {
+	abi, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset, 0, 0, landlockCreateRulesetVersion)
+	if errno != 0 {
+		return fmt.Errorf("%w: landlock not supported: %w", ErrSandboxUnavailable, errno)
+	}
+
+	handled := handledAccessFS(int(abi))
+
+	rulesetAttr := struct{ handledAccessFS uint64 }{handledAccessFS: handled}
+
+	fd, _, errno := syscall.RawSyscall(sysLandlockCreateRuleset,
+		uintptr(unsafe.Pointer(&rulesetAttr)), unsafe.Sizeof(rulesetAttr), 0)
+	if errno != 0 {
+		return fmt.Errorf("%w: failed to create landlock ruleset: %w", ErrSandboxUnavailable, errno)
+	}
+
+	rulesetFd := int(fd)
+
+	defer func() { _ = syscall.Close(rulesetFd) }()
+
+	for _, dir := range systemDirs {
+		if err := addPathRule(rulesetFd, dir, accessFSReadAccess&handled); err != nil && !errors.Is(err, syscall.ENOENT) {
+			return err
+		}
+	}
+
+	for _, file := range readableFiles {
+		if err := addPathRule(rulesetFd, file, (accessFSExecute|accessFSReadFile)&handled); err != nil {
+			return err
+		}
+	}
+
+	if err := addPathRule(rulesetFd, "/dev", accessFSDevAccess&handled); err != nil {
+		return err
+	}
+
+	for _, dir := range writableDirs {
+		if err := addPathRule(rulesetFd, dir, handled); err != nil {
+			return err
+		}
+	}
+
+	if _, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0, 0, 0, 0); errno != 0 {
+		return fmt.Errorf("%w: failed to set no_new_privs: %w", ErrSandboxUnavailable, errno)
+	}
+
+	if err := installSeccompFilter(); err != nil {
+		return err
+	}
+
+	if _, _, errno := syscall.RawSyscall(sysLandlockRestrictSelf, uintptr(rulesetFd), 0, 0); errno != 0 {
+		return fmt.Errorf("%w: failed to enforce landlock ruleset: %w", ErrSandboxUnavailable, errno)
+	}
+
+	return nil
+}
+
+// handledAccessFS returns every filesystem access right supported by the given Landlock ABI version.
+func handledAccessFS(abi int) uint64 {
+	const (
+		abiRefer    = 2
+		abiTruncate = 3
+		abiIoctlDev = 5
+	)
+
+	handled := uint64(accessFSMakeSym<<1 - 1)
+	if abi >= abiRefer {
+		handled |= accessFSRefer
+	}
+
+	if abi >= abiTruncate {
+		handled |= accessFSTruncate
+	}
+
+	if abi >= abiIoctlDev {
+		handled |= accessFSIoctlDev
+	}
+
+	return handled
+}
+
+// addPathRule grants access beneath path in the ruleset.
+func addPathRule(rulesetFd int, path string, access uint64) error {
+	pathFd, err := syscall.Open(path, oPath|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for sandbox rule: %w", path, err)
+	}
+
+	defer func() { _ = syscall.Close(pathFd) }()
+
+	// struct landlock_path_beneath_attr is packed: a u64 followed by an s32.
+	var attr [12]byte
+
+	*(*uint64)(unsafe.Pointer(&attr[0])) = access
+	*(*int32)(unsafe.Pointer(&attr[8])) = int32(pathFd) // #nosec G115 -- file descriptors fit in int32
+
+	if _, _, errno := syscall.RawSyscall6(sysLandlockAddRule, uintptr(rulesetFd), landlockRulePathBeneath,
+		uintptr(unsafe.Pointer(&attr[0])), 0, 0, 0); errno != 0 {
+		return fmt.Errorf("%w: failed to add landlock rule for %s: %w", ErrSandboxUnavailable, path, errno)
+	}
+
+	return nil
+}
+
+// Seccomp filter constants (see linux/seccomp.h and linux/filter.h).
+const (
+	prSetSeccomp      = 22
+	seccompModeFilter = 2
+
+	seccompRetAllow = 0x7fff0000
+	seccompRetErrno = 0x00050000
+
+	bpfLoadAbsWord = 0x20 // BPF_LD | BPF_W | BPF_ABS
+	bpfJumpEq      = 0x15 // BPF_JMP | BPF_JEQ | BPF_K
+	bpfJumpGE      = 0x35 // BPF_JMP | BPF_JGE | BPF_K
+	bpfReturn      = 0x06 // BPF_RET | BPF_K
+
+	seccompDataNr   = 0 // Offset of nr in struct seccomp_data
+	seccompDataArch = 4 // Offset of arch in struct seccomp_data
+
+	// seccompX32Bit marks the x32 ABI's system calls on amd64, which are denied so that
+	// they cannot be used to get around the filter. No other ABI numbers calls this high.
+	seccompX32Bit = 0x40000000
+)
+
+// sockFilter is struct sock_filter, one BPF instruction.
+type sockFilter struct {
+	code uint16
+	jt   uint8
+	jf   uint8
+	k    uint32
+}
+
+// sockFprog is struct sock_fprog.
+type sockFprog struct {
+	len    uint16
+	filter *sockFilter
+}
+
+// installSeccompFilter makes the system calls in seccompDeniedSyscalls, and those of
+// another architecture, fail with EPERM on the current thread and the processes it starts.
+// no_new_privs must be set.
+func installSeccompFilter() error {
+	if seccompAuditArch == 0 {
+		return fmt.Errorf("%w: no seccomp filter for %s", ErrSandboxUnavailable, runtime.GOARCH)
+	}
+
+	filter := seccompFilter(seccompAuditArch, seccompDeniedSyscalls)
+	prog := sockFprog{len: uint16(len(filter)), filter: &filter[0]} // #nosec G115 -- the filter is short
+
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRCTL, prSetSeccomp, seccompModeFilter,
+		uintptr(unsafe.Pointer(&prog)), 0, 0, 0)
+
+	runtime.KeepAlive(filter)
+
+	if errno != 0 {
+		return fmt.Errorf("%w: failed to install seccomp filter: %w", ErrSandboxUnavailable, errno)
+	}
+
+	return nil
+}
+
+// seccompFilter returns a BPF program that allows every system call of arch except denied.
+func seccompFilter(arch uint32, denied []uint32) []sockFilter {
+	deny := sockFilter{code: bpfReturn, k: seccompRetErrno | uint32(syscall.EPERM)}
+
+	filter := []sockFilter{
+		{code: bpfLoadAbsWord, k: seccompDataArch},
+		{code: bpfJumpEq, jt: 1, k: arch},
+		deny,
+		{code: bpfLoadAbsWord, k: seccompDataNr},
+	}
+
+	checks := append([]uint32{seccompX32Bit}, denied...)
+	for i, nr := range checks {
+		// Jump past the remaining checks and the allow to the deny at the end.
+		jump := sockFilter{code: bpfJumpEq, jt: uint8(len(checks) - i), k: nr} // #nosec G115 -- few checks
+		if nr == seccompX32Bit {
+			jump.code = bpfJumpGE
+		}
+
+		filter = append(filter, jump)
+	}
+
+	return append(filter, sockFilter{code: bpfReturn, k: seccompRetAllow}, deny)
+}
@@ -0,0 +1,42 @@
+package codingcontext
+
+// seccompAuditArch is AUDIT_ARCH_X86_64, the arch of system calls made through the amd64 ABI.
+const seccompAuditArch = 0xc000003e
+
+// seccompDeniedSyscalls are the system calls sandboxed scripts may not make: those that
+// trace or read other processes, load kernel code, change mounts or namespaces, or reach
+// the kernel keyring.
+var seccompDeniedSyscalls = []uint32{
+	101, // ptrace
+	155, // pivot_root
+	161, // chroot
+	163, // acct
+	165, // mount
+	166, // umount2
+	167, // swapon
+	168, // swapoff
+	169, // reboot
+	175, // init_module
+	176, // delete_module
+	246, // kexec_load
+	248, // add_key
+	249, // request_key
+	250, // keyctl
+	272, // unshare
+	298, // perf_event_open
+	303, // name_to_handle_at
+	304, // open_by_handle_at
+	308, // setns
+	310, // process_vm_readv
+	311, // process_vm_writev
+	313, // finit_module
+	320, // kexec_file_load
+	321, // bpf
+	323, // userfaultfd
+	425, // io_uring_setup
+	428, // open_tree
+	429, // move_mount
+	430, // fsopen
+	432, // fsmount
+	442, // mount_setattr
+}
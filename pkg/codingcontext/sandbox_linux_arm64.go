@@ -0,0 +1,42 @@
+package codingcontext
+
+// seccompAuditArch is AUDIT_ARCH_AARCH64, the arch of system calls made through the arm64 ABI.
+const seccompAuditArch = 0xc00000b7
+
+// seccompDeniedSyscalls are the system calls sandboxed scripts may not make: those that
+// trace or read other processes, load kernel code, change mounts or namespaces, or reach
+// the kernel keyring.
+var seccompDeniedSyscalls = []uint32{
+	39,  // umount2
+	40,  // mount
+	41,  // pivot_root
+	51,  // chroot
+	89,  // acct
+	97,  // unshare
+	104, // kexec_load
+	105, // init_module
+	106, // delete_module
+	117, // ptrace
+	142, // reboot
+	217, // add_key
+	218, // request_key
+	219, // keyctl
+	224, // swapon
+	225, // swapoff
+	241, // perf_event_open
+	264, // name_to_handle_at
+	265, // open_by_handle_at
+	268, // setns
+	270, // process_vm_readv
+	271, // process_vm_writev
+	273, // finit_module
+	280, // bpf
+	282, // userfaultfd
+	294, // kexec_file_load
+	425, // io_uring_setup
+	428, // open_tree
+	429, // move_mount
+	430, // fsopen
+	432, // fsmount
+	442, // mount_setattr
+}
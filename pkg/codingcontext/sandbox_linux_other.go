@@ -0,0 +1,10 @@
+//go:build linux && !amd64 && !arm64
+
+package codingcontext
+
+// seccompAuditArch is zero where there is no seccomp filter for the architecture, so the
+// sandbox is unavailable.
+const seccompAuditArch = 0
+
+// seccompDeniedSyscalls is empty where there is no seccomp filter for the architecture.
+var seccompDeniedSyscalls []uint32
@@ -0,0 +1,260 @@
+//go:build linux
+
+package codingcontext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// requireSandbox skips the test when the kernel cannot provide the bootstrap sandbox.
+func requireSandbox(t *testing.T) {
+	t.Helper()
+
+	cmd := exec.CommandContext(context.Background(), "/bin/true")
+
+	err := runConfined(cmd, confinement{writableDirs: []string{t.TempDir()}}, func(cmd *exec.Cmd) error {
+		return cmd.Run()
+	})
+	if err != nil {
+		t.Skipf("bootstrap sandbox unavailable: %v", err)
+	}
+}
+
+// runSandboxedRule runs a task whose single rule has the given frontmatter bootstrap
+// under sandbox and returns the script's combined output.
+func runSandboxedRule(t *testing.T, sandbox BootstrapSandbox, script string) (string, error) {
+	t.Helper()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "Task")
+	createRule(t, dir, ".agents/rules/rule.md", "bootstrap: |\n"+indent(script), "Rule content")
+
+	if sandbox.WorkDir == "" {
+		sandbox.WorkDir = dir
+	}
+
+	sandbox.Enabled = true
+
+	var (
+		mu  sync.Mutex
+		out bytes.Buffer
+	)
+
+	c := New(WithSearchPaths(dir), WithBootstrapSandbox(sandbox))
+	c.cmdRunner = func(cmd *exec.Cmd) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+
+		return cmd.Run()
+	}
+
+	_, err := c.Run(context.Background(), "task")
+
+	return out.String(), err
+}
+
+func indent(script string) string {
+	lines := strings.Split(strings.TrimSpace(script), "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestSandbox_ScrubsEnvironment(t *testing.T) {
+	requireSandbox(t)
+	t.Setenv("SANDBOX_SECRET", "hunter2")
+	t.Setenv("SANDBOX_ALLOWED", "visible")
+
+	out, err := runSandboxedRule(t, BootstrapSandbox{EnvAllowlist: []string{"PATH", "SANDBOX_ALLOWED"}},
+		"#!/bin/sh\necho \"secret=$SANDBOX_SECRET allowed=$SANDBOX_ALLOWED\"\necho \"home=$HOME\"")
+	if err != nil {
+		t.Fatalf("Run() error: %v\n%s", err, out)
+	}
+
+	if !strings.Contains(out, "secret= allowed=visible") {
+		t.Errorf("expected only allowlisted variables, got: %s", out)
+	}
+
+	if !strings.Contains(out, "home="+os.TempDir()) {
+		t.Errorf("expected HOME to point at the scratch directory, got: %s", out)
+	}
+}
+
+func TestSandbox_RestrictsWrites(t *testing.T) {
+	requireSandbox(t)
+
+	outside := t.TempDir()
+	workDir := t.TempDir()
+
+	script := "#!/bin/sh\n" +
+		"echo ok > " + filepath.Join(workDir, "inside.txt") + "\n" +
+		"echo ok > \"$TMPDIR/scratch.txt\"\n" +
+		"echo ok > /dev/null\n" +
+		"if echo bad > " + filepath.Join(outside, "outside.txt") + "; then echo WROTE_OUTSIDE; fi"
+
+	out, err := runSandboxedRule(t, BootstrapSandbox{WorkDir: workDir}, script)
+	if err != nil {
+		t.Fatalf("Run() error: %v\n%s", err, out)
+	}
+
+	if _, err := os.Stat(filepath.Join(workDir, "inside.txt")); err != nil {
+		t.Errorf("expected write inside workdir to succeed: %v", err)
+	}
+
+	if strings.Contains(out, "WROTE_OUTSIDE") {
+		t.Errorf("expected write outside workdir to be denied, got: %s", out)
+	}
+
+	if _, err := os.Stat(filepath.Join(outside, "outside.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected no file outside workdir, stat error: %v", err)
+	}
+}
+
+func TestSandbox_RestrictsReads(t *testing.T) {
+	requireSandbox(t)
+
+	outside := t.TempDir()
+	workDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(workDir, "inside.txt"), []byte("inside"), 0o600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	script := "#!/bin/sh\n" +
+		"cat " + filepath.Join(workDir, "inside.txt") + "\n" +
+		"cat /etc/passwd >/dev/null && echo READ_ETC\n" +
+		"if cat " + filepath.Join(outside, "secret.txt") + "; then echo READ_OUTSIDE; fi\n" +
+		"if cat /proc/1/environ; then echo READ_PROC; fi"
+
+	out, err := runSandboxedRule(t, BootstrapSandbox{WorkDir: workDir}, script)
+	if err != nil {
+		t.Fatalf("Run() error: %v\n%s", err, out)
+	}
+
+	if !strings.Contains(out, "inside") || !strings.Contains(out, "READ_ETC") {
+		t.Errorf("expected reads of the workdir and /etc to succeed, got: %s", out)
+	}
+
+	for _, denied := range []string{"READ_OUTSIDE", "READ_PROC"} {
+		if strings.Contains(out, denied) {
+			t.Errorf("expected %s to be denied, got: %s", denied, out)
+		}
+	}
+}
+
+func TestSandbox_DeniesSyscalls(t *testing.T) {
+	requireSandbox(t)
+
+	unsharePath, err := exec.LookPath("unshare")
+	if err != nil {
+		t.Skip("unshare is not installed")
+	}
+
+	// With the network allowed, the script is not in a user namespace that would deny it anyway.
+	out, err := runSandboxedRule(t, BootstrapSandbox{AllowNetwork: true},
+		"#!/bin/sh\nif "+unsharePath+" --user true; then echo UNSHARED; fi")
+	if err != nil {
+		t.Fatalf("Run() error: %v\n%s", err, out)
+	}
+
+	if strings.Contains(out, "UNSHARED") {
+		t.Errorf("expected unshare to be denied, got: %s", out)
+	}
+}
+
+func TestSandbox_DisablesNetwork(t *testing.T) {
+	requireSandbox(t)
+
+	// /proc cannot be read in the sandbox, so interfaces are listed over netlink.
+	ipPath, err := exec.LookPath("ip")
+	if err != nil {
+		t.Skip("ip is not installed")
+	}
+
+	tests := []struct {
+		name         string
+		allowNetwork bool
+		wantOnlyLo   bool
+	}{
+		{name: "network disabled by default", allowNetwork: false, wantOnlyLo: true},
+		{name: "network allowed", allowNetwork: true, wantOnlyLo: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := runSandboxedRule(t, BootstrapSandbox{AllowNetwork: tt.allowNetwork},
+				"#!/bin/sh\n"+ipPath+" -o link show | awk '{print \"iface\", $2}'")
+			if err != nil {
+				t.Fatalf("Run() error: %v\n%s", err, out)
+			}
+
+			onlyLo := true
+
+			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+				if strings.HasPrefix(line, "iface ") && line != "iface lo:" {
+					onlyLo = false
+				}
+			}
+
+			if onlyLo != tt.wantOnlyLo && (tt.wantOnlyLo || hostHasInterfaces(t)) {
+				t.Errorf("only loopback = %v, want %v; interfaces:\n%s", onlyLo, tt.wantOnlyLo, out)
+			}
+		})
+	}
+}
+
+// hostHasInterfaces reports whether the test host has any non-loopback interface.
+func hostHasInterfaces(t *testing.T) bool {
+	t.Helper()
+
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(string(data), "\n")[2:] {
+		name, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+		if name != "" && name != "lo" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestSandbox_Timeout(t *testing.T) {
+	requireSandbox(t)
+
+	start := time.Now()
+
+	out, err := runSandboxedRule(t, BootstrapSandbox{Timeout: 200 * time.Millisecond}, "#!/bin/sh\nexec sleep 10")
+	if err == nil {
+		t.Fatalf("expected timeout error, got output: %s", out)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected script to be killed promptly, took %v", elapsed)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package codingcontext
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// runConfined is only implemented on Linux.
+func runConfined(_ *exec.Cmd, _ confinement, _ func(*exec.Cmd) error) error {
+	return fmt.Errorf("%w: not supported on %s", ErrSandboxUnavailable, runtime.GOOS)
+}
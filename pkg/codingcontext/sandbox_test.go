@@ -0,0 +1,47 @@
+package codingcontext
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSandboxEnv(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		environ   []string
+		allowlist []string
+		want      []string
+	}{
+		{
+			name:      "keeps only allowlisted variables",
+			environ:   []string{"PATH=/bin", "SECRET=x", "LANG=C"},
+			allowlist: []string{"PATH", "LANG"},
+			want:      []string{"PATH=/bin", "LANG=C", "HOME=/scratch", "TMPDIR=/scratch"},
+		},
+		{
+			name:      "HOME and TMPDIR always point at scratch",
+			environ:   []string{"HOME=/root", "TMPDIR=/var/tmp"},
+			allowlist: []string{"HOME", "TMPDIR"},
+			want:      []string{"HOME=/scratch", "TMPDIR=/scratch"},
+		},
+		{
+			name:      "values containing equals signs are preserved",
+			environ:   []string{"OPTS=a=b"},
+			allowlist: []string{"OPTS"},
+			want:      []string{"OPTS=a=b", "HOME=/scratch", "TMPDIR=/scratch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := sandboxEnv(tt.environ, tt.allowlist, "/scratch")
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("sandboxEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package codingcontext
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files of a search path given by WithSearchFS are named by joining the search path's name
+// and their path within its fs.FS, so that they can be handled like local files. The helpers
+// below read a named file from the fs.FS it belongs to, or else from the local file system.
+
+// searchFS returns the fs.FS search path that path belongs to and path's name within it.
+func (cc *Context) searchFS(path string) (SearchPath, string, bool) {
+	for _, sp := range cc.downloadedPaths {
+		if sp.FS == nil {
+			continue
+		}
+
+		if path == sp.Path {
+			return sp, ".", true
+		}
+
+		if rel, ok := strings.CutPrefix(path, sp.Path+string(filepath.Separator)); ok {
+			return sp, filepath.ToSlash(rel), true
+		}
+	}
+
+	return SearchPath{}, "", false
+}
+
+// isFSPath reports whether path is read from an fs.FS rather than the local file system.
+func (cc *Context) isFSPath(path string) bool {
+	_, _, ok := cc.searchFS(path)
+
+	return ok
+}
+
+// stat is os.Stat for a file of any search path.
+func (cc *Context) stat(path string) (fs.FileInfo, error) {
+	if sp, name, ok := cc.searchFS(path); ok {
+		info, err := fs.Stat(sp.FS, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+
+		return info, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	return info, nil
+}
+
+// readFile is os.ReadFile for a file of any search path.
+func (cc *Context) readFile(path string) ([]byte, error) {
+	if sp, name, ok := cc.searchFS(path); ok {
+		data, err := fs.ReadFile(sp.FS, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		return data, nil
+	}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return data, nil
+}
+
+// readDir is os.ReadDir for a directory of any search path.
+func (cc *Context) readDir(path string) ([]fs.DirEntry, error) {
+	if sp, name, ok := cc.searchFS(path); ok {
+		entries, err := fs.ReadDir(sp.FS, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory %s: %w", path, err)
+		}
+
+		return entries, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+// walkDir is filepath.WalkDir for a directory of any search path. The paths passed to fn
+// are named like local files.
+func (cc *Context) walkDir(root string, fn fs.WalkDirFunc) error {
+	sp, name, ok := cc.searchFS(root)
+	if !ok {
+		if err := filepath.WalkDir(root, fn); err != nil {
+			return fmt.Errorf("%w", err)
+		}
+
+		return nil
+	}
+
+	err := fs.WalkDir(sp.FS, name, func(p string, d fs.DirEntry, err error) error {
+		return fn(filepath.Join(sp.Path, filepath.FromSlash(p)), d, err)
+	})
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
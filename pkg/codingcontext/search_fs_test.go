@@ -0,0 +1,113 @@
+package codingcontext
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// embeddedFS returns an in-memory file system laid out like a search path.
+func embeddedFS() fstest.MapFS {
+	return fstest.MapFS{
+		".agents/tasks/deploy.md":                  {Data: []byte("/greet\nDeploy with @docs/notes.md\n")},
+		".agents/namespaces/ops/tasks/rollback.md": {Data: []byte("Roll back.\n")},
+		".agents/commands/greet.md":                {Data: []byte("Hello from a command.\n")},
+		".agents/rules/style.md": {
+			Data: []byte("---\nbootstrap_output:\n  append: true\n---\n# Embedded Style\n"),
+		},
+		".agents/rules/style-bootstrap": {Data: []byte("#!/bin/sh\necho booted\n"), Mode: 0o755},
+		".agents/skills/embedded-skill/SKILL.md": {
+			Data: []byte("---\nname: embedded-skill\ndescription: A skill shipped in the binary.\n---\n# Skill\n"),
+		},
+		"docs/notes.md": {Data: []byte("embedded notes")},
+	}
+}
+
+func TestWithSearchFS_Run(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createRule(t, dir, ".agents/rules/local.md", "", "# Local Rule")
+
+	cc := New(WithSearchFS("embedded", embeddedFS()), WithSearchPaths(dir))
+
+	result, err := cc.Run(context.Background(), "deploy")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	wants := []string{"# Embedded Style", "booted", "# Local Rule", "Hello from a command.", "embedded notes"}
+	for _, want := range wants {
+		if !strings.Contains(result.Prompt, want) {
+			t.Errorf("expected prompt to contain %q, got:\n%s", want, result.Prompt)
+		}
+	}
+
+	wantLocation := filepath.Join("embedded", ".agents", "skills", "embedded-skill", "SKILL.md")
+	if len(result.Skills.Skills) != 1 || result.Skills.Skills[0].Location != wantLocation {
+		t.Errorf("expected skill at %s, got %+v", wantLocation, result.Skills.Skills)
+	}
+}
+
+func TestWithSearchFS_InlineSkill(t *testing.T) {
+	t.Parallel()
+
+	cc := New(WithSearchFS("embedded", embeddedFS()), WithInlineSkills("embedded-skill"))
+
+	result, err := cc.Run(context.Background(), "deploy")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(result.Skills.Skills) != 1 || !strings.Contains(result.Skills.Skills[0].Content, "# Skill") {
+		t.Errorf("expected the inlined skill content, got %+v", result.Skills.Skills)
+	}
+}
+
+func TestWithSearchFS_ListTasks(t *testing.T) {
+	t.Parallel()
+
+	cc := New(WithSearchFS("embedded", embeddedFS()))
+
+	tasks, err := cc.ListTasks(context.Background())
+	if err != nil {
+		t.Fatalf("ListTasks() error: %v", err)
+	}
+
+	want := []DiscoveredTask{
+		{Name: "deploy", Path: filepath.Join("embedded", ".agents", "tasks", "deploy.md")},
+		{Name: "ops/rollback", Path: filepath.Join("embedded", ".agents", "namespaces", "ops", "tasks", "rollback.md"),
+			Namespace: "ops"},
+	}
+
+	if !slices.Equal(tasks, want) {
+		t.Errorf("ListTasks() = %+v, want %+v", tasks, want)
+	}
+}
+
+func TestWithSearchFS_Lint(t *testing.T) {
+	t.Parallel()
+
+	cc := New(WithSearchFS("embedded", embeddedFS()))
+
+	result, err := cc.Lint(context.Background(), "deploy")
+	if err != nil {
+		t.Fatalf("Lint() error: %v", err)
+	}
+
+	for _, want := range []struct {
+		path string
+		kind LoadedFileKind
+	}{
+		{filepath.Join("embedded", ".agents", "tasks", "deploy.md"), LoadedFileKindTask},
+		{filepath.Join("embedded", ".agents", "rules", "style-bootstrap"), LoadedFileKindBootstrap},
+		{filepath.Join("embedded", "docs", "notes.md"), LoadedFileKindPathRef},
+	} {
+		if !hasLoadedFile(result, want.path, want.kind) {
+			t.Errorf("expected %s to be loaded as %v, got %+v", want.path, want.kind, result.LoadedFiles)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package selectors
 import (
 	"errors"
 	"fmt"
+	"maps"
 	"strings"
 
 	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
@@ -86,6 +87,20 @@ func (s *Selectors) SetValue(key, value string) {
 	(*s)[key][value] = true
 }
 
+// Clone returns a deep copy of the selectors, so that values set on the copy do not affect s.
+func (s *Selectors) Clone() Selectors {
+	if *s == nil {
+		return nil
+	}
+
+	clone := make(Selectors, len(*s))
+	for key, values := range *s {
+		clone[key] = maps.Clone(values)
+	}
+
+	return clone
+}
+
 // GetValue returns true if the given value exists in the inner map for the given key.
 // Returns false if the key doesn't exist or the value is not present.
 func (s *Selectors) GetValue(key, value string) bool {
@@ -221,6 +221,25 @@ func runMatchesIncludes(t *testing.T, tt matchesIncludesCase) {
 	}
 }
 
+func TestSelectorMap_Clone(t *testing.T) {
+	t.Parallel()
+
+	s := make(Selectors)
+	s.SetValue("env", "prod")
+
+	clone := s.Clone()
+	clone.SetValue("env", "dev")
+	clone.SetValue("language", "go")
+
+	if s.GetValue("env", "dev") || s.GetValue("language", "go") {
+		t.Errorf("setting values on the clone changed the original: %v", s.String())
+	}
+
+	if !clone.GetValue("env", "prod") {
+		t.Errorf("clone is missing the original value: %v", clone.String())
+	}
+}
+
 func TestSelectorMap_MatchesIncludes(t *testing.T) {
 	t.Parallel()
 
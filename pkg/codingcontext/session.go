@@ -0,0 +1,304 @@
+package codingcontext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/skills"
+)
+
+// sharedState is shared by every run of a Context: in a session, the search paths,
+// resolved and downloaded once, and the markdown files parsed so far.
+type sharedState struct {
+	mu              sync.Mutex
+	resolved        bool
+	resolvedAt      time.Time    // When searchPaths were downloaded, for WithRefresh
+	searchPaths     []SearchPath // Configured search paths followed by those listed in the manifest
+	downloadedPaths []SearchPath // Local directories of searchPaths
+	files           fileCache
+
+	// inUse is read-locked by each session run using downloadedPaths, and write-locked to
+	// download them again, so that a refresh never changes the files of a run.
+	inUse sync.RWMutex
+
+	// sourceDirs hold what sources provided, one per run. They have their own lock, because
+	// runs create them while holding inUse, which Close waits for while holding mu.
+	sourceMu   sync.Mutex
+	sourceDirs []sourceDir
+
+	bootstrapMu    sync.Mutex
+	bootstrapLocks map[string]chan struct{} // By file path; held while its bootstrap runs
+}
+
+// sourceDir is the directory of what the sources of a run provided.
+type sourceDir struct {
+	path    string
+	running bool // The run has not ended, so the directory must not be removed
+}
+
+func newSharedState() *sharedState {
+	return &sharedState{
+		files:          fileCache{entries: make(map[fileCacheKey]fileCacheEntry)},
+		bootstrapLocks: make(map[string]chan struct{}),
+	}
+}
+
+// session returns a copy of cc for a single run. Run, Lint, and ListTasks work on a session,
+// so that what a run collects (rules, skills, tokens) and the configuration it adjusts for
+// its task (selectors, agent, inclusion policy) do not leak into other runs, including
+// concurrent ones.
+func (cc *Context) session() *Context {
+	s := *cc
+
+	s.params = maps.Clone(cc.params)
+	s.includes = cc.includes.Clone()
+	s.searchPaths = slices.Clone(cc.searchPaths)
+	s.inlineSkills = slices.Clone(cc.inlineSkills)
+	s.hooks = slices.Clone(cc.hooks)
+	s.sources = slices.Clone(cc.sources)
+	s.rules = make([]markdown.Markdown[markdown.RuleFrontMatter], 0)
+	s.skills = skills.AvailableSkills{Skills: make([]skills.Skill, 0)}
+
+	return &s
+}
+
+// With returns a copy of cc with opts applied, for runs that differ from those of cc in,
+// e.g., their parameters, selectors, agent, or user prompt. The copy shares the parsed
+// files of cc and, in a session, its downloaded search paths, so a server can assemble each
+// request on its own copy of one Context; Close of either removes the downloads of both.
+// Options that add search paths or set the manifest must not be given, because the search
+// paths are resolved once for every copy.
+func (cc *Context) With(opts ...Option) *Context {
+	c := cc.session()
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// resolveSearchPaths returns the local directories of the search paths, including those
+// listed in the manifest. In a session (see WithSession), remote search paths are downloaded
+// by the first run and reused by later and concurrent runs until Close, or until the
+// WithRefresh interval has passed and the runs using them have finished. Otherwise they are
+// downloaded for this run, and endRun removes them.
+func (cc *Context) resolveSearchPaths(ctx context.Context) ([]SearchPath, error) {
+	if !cc.keepSession {
+		searchPaths, downloadedPaths, err := cc.downloadSearchPaths(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		cc.runSearchPaths = searchPaths
+
+		return downloadedPaths, nil
+	}
+
+	shared := cc.shared
+
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+
+	if shared.resolved && cc.refresh > 0 && time.Since(shared.resolvedAt) >= cc.refresh {
+		cc.logger.Info("Refreshing remote search paths", "age", time.Since(shared.resolvedAt))
+
+		shared.inUse.Lock()
+		err := removeDownloadedDirectories(shared.searchPaths)
+		shared.inUse.Unlock()
+
+		if err != nil {
+			cc.logger.Warn("Failed to remove downloaded directories", "error", err)
+		}
+
+		shared.resolved = false
+	}
+
+	if !shared.resolved {
+		searchPaths, downloadedPaths, err := cc.downloadSearchPaths(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		shared.searchPaths = searchPaths
+		shared.downloadedPaths = downloadedPaths
+		shared.resolvedAt = time.Now()
+		shared.resolved = true
+	}
+
+	shared.inUse.RLock()
+	cc.usingDownloads = true
+
+	return slices.Clone(shared.downloadedPaths), nil
+}
+
+// downloadSearchPaths returns the search paths, including those listed in the manifest,
+// and their local directories, downloading remote ones.
+func (cc *Context) downloadSearchPaths(ctx context.Context) ([]SearchPath, []SearchPath, error) {
+	manifestPaths, err := cc.parseManifestFile(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse manifest file: %w", err)
+	}
+
+	searchPaths := slices.Clone(cc.searchPaths)
+	for _, p := range manifestPaths {
+		searchPaths = append(searchPaths, SearchPath{Path: p})
+	}
+
+	downloadedPaths, err := cc.downloadRemoteDirectories(ctx, searchPaths)
+	if err != nil {
+		_ = removeDownloadedDirectories(searchPaths)
+
+		return nil, nil, fmt.Errorf("failed to download remote directories: %w", err)
+	}
+
+	return searchPaths, downloadedPaths, nil
+}
+
+// endRun removes the remote search paths that a run outside a session downloaded, or lets
+// a session refresh the ones the run used.
+func (cc *Context) endRun() {
+	if cc.usingDownloads {
+		cc.shared.inUse.RUnlock()
+	}
+
+	if err := removeDownloadedDirectories(cc.runSearchPaths); err != nil {
+		cc.logger.Warn("Failed to remove downloaded directories", "error", err)
+	}
+
+	if cc.runSourceDir != "" {
+		cc.shared.endSourceDir(cc.runSourceDir)
+	}
+}
+
+// lockBootstrap waits until no other run of the Context is running the bootstrap of the
+// file at path, so that concurrent runs, such as a server's requests, do not run the same
+// bootstrap in the same directory at once. It returns the function that releases the lock.
+func (shared *sharedState) lockBootstrap(ctx context.Context, path string) (func(), error) {
+	shared.bootstrapMu.Lock()
+
+	lock, ok := shared.bootstrapLocks[path]
+	if !ok {
+		lock = make(chan struct{}, 1)
+		shared.bootstrapLocks[path] = lock
+	}
+
+	shared.bootstrapMu.Unlock()
+
+	select {
+	case lock <- struct{}{}:
+		return func() { <-lock }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("waiting for the bootstrap of %s: %w", path, ctx.Err())
+	}
+}
+
+// maxSourceDirs is how many runs' source directories are kept. The oldest of those whose
+// runs have ended are removed first, so that a long-running session, such as a server, does
+// not fill the temporary directory.
+const maxSourceDirs = 32
+
+// newSourceDir creates a directory for what the sources of a run provide, removed by Close
+// or, once the run has ended, when maxSourceDirs newer ones have been created.
+func (shared *sharedState) newSourceDir() (string, error) {
+	dir, err := os.MkdirTemp("", SourcePrefix+"*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory for sources: %w", err)
+	}
+
+	shared.sourceMu.Lock()
+	defer shared.sourceMu.Unlock()
+
+	shared.sourceDirs = append(shared.sourceDirs, sourceDir{path: dir, running: true})
+	shared.removeOldSourceDirs()
+
+	return dir, nil
+}
+
+// endSourceDir records that the run of the source directory dir has ended, so that it may
+// be removed.
+func (shared *sharedState) endSourceDir(dir string) {
+	shared.sourceMu.Lock()
+	defer shared.sourceMu.Unlock()
+
+	for i := range shared.sourceDirs {
+		if shared.sourceDirs[i].path == dir {
+			shared.sourceDirs[i].running = false
+		}
+	}
+
+	shared.removeOldSourceDirs()
+}
+
+// removeOldSourceDirs removes the oldest source directories of ended runs while there are
+// more than maxSourceDirs. It must be called with sourceMu held.
+func (shared *sharedState) removeOldSourceDirs() {
+	excess := len(shared.sourceDirs) - maxSourceDirs
+
+	shared.sourceDirs = slices.DeleteFunc(shared.sourceDirs, func(dir sourceDir) bool {
+		if excess <= 0 || dir.running {
+			return false
+		}
+
+		excess--
+		_ = os.RemoveAll(dir.path)
+
+		return true
+	})
+}
+
+// Close removes the remote search paths downloaded by runs of cc, and what their sources
+// provided, once the runs using the downloads have finished. A run after Close downloads
+// them again.
+func (cc *Context) Close() error {
+	shared := cc.shared
+
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+
+	shared.inUse.Lock()
+	defer shared.inUse.Unlock()
+
+	shared.sourceMu.Lock()
+	defer shared.sourceMu.Unlock()
+
+	errs := []error{removeDownloadedDirectories(shared.searchPaths)}
+
+	for _, dir := range shared.sourceDirs {
+		if err := os.RemoveAll(dir.path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove source directory %s: %w", dir.path, err))
+		}
+	}
+
+	shared.searchPaths = nil
+	shared.downloadedPaths = nil
+	shared.sourceDirs = nil
+	shared.resolved = false
+
+	return errors.Join(errs...)
+}
+
+// removeDownloadedDirectories removes the download directories of the remote search paths.
+// Local and fs.FS search paths are never removed.
+func removeDownloadedDirectories(searchPaths []SearchPath) error {
+	var errs []error
+
+	for _, sp := range searchPaths {
+		if sp.FS != nil || isLocalPath(sp.Path) {
+			continue
+		}
+
+		if err := os.RemoveAll(downloadDir(sp.Path)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove downloaded directory for %s: %w", sp.Path, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
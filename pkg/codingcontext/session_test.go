@@ -0,0 +1,485 @@
+package codingcontext
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/selectors"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
+)
+
+func TestContext_RunTwice(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "Do the task.")
+	createRule(t, dir, ".agents/rules/a.md", "", "# Rule A")
+	createRule(t, dir, ".agents/rules/b.md", "", "# Rule B")
+
+	cc := newFullContext(dir)
+
+	first, err := cc.Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("first Run() error: %v", err)
+	}
+
+	second, err := cc.Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("second Run() error: %v", err)
+	}
+
+	if len(first.Rules) != 2 || len(second.Rules) != 2 {
+		t.Errorf("expected 2 rules from each run, got %d and %d", len(first.Rules), len(second.Rules))
+	}
+
+	if first.Tokens != second.Tokens {
+		t.Errorf("expected the same token count from each run, got %d and %d", first.Tokens, second.Tokens)
+	}
+}
+
+func TestContext_ConcurrentRuns(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "alpha", "", "Do alpha.")
+	createTask(t, dir, "beta", "", "Do beta.")
+	createRule(t, dir, ".agents/rules/shared.md", "", "# Shared Rule")
+	createRule(t, dir, ".agents/rules/alpha.md", "task_name: alpha", "# Alpha Rule")
+	createRule(t, dir, ".agents/rules/beta.md", "task_name: beta", "# Beta Rule")
+
+	cc := newFullContext(dir)
+
+	const runs = 8
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, runs)
+	contents := make([]string, runs)
+
+	for i := range runs {
+		taskName := "alpha"
+		if i%2 == 1 {
+			taskName = "beta"
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			result, err := cc.Run(context.Background(), taskName)
+			if err != nil {
+				errs[i] = err
+
+				return
+			}
+
+			var sb strings.Builder
+			for _, rule := range result.Rules {
+				sb.WriteString(rule.Content)
+			}
+
+			contents[i] = sb.String()
+		}()
+	}
+
+	wg.Wait()
+
+	for i := range runs {
+		if errs[i] != nil {
+			t.Fatalf("Run() %d error: %v", i, errs[i])
+		}
+
+		want, unwanted := "# Alpha Rule", "# Beta Rule"
+		if i%2 == 1 {
+			want, unwanted = unwanted, want
+		}
+
+		if !strings.Contains(contents[i], want) || !strings.Contains(contents[i], "# Shared Rule") {
+			t.Errorf("run %d: expected %q and the shared rule, got:\n%s", i, want, contents[i])
+		}
+
+		if strings.Contains(contents[i], unwanted) {
+			t.Errorf("run %d: unexpected %q, got:\n%s", i, unwanted, contents[i])
+		}
+	}
+}
+
+func TestContext_RunRereadsModifiedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "Do the task.")
+
+	rulePath := filepath.Join(dir, ".agents", "rules", "rule.md")
+	writeFile(t, rulePath, "# Before")
+
+	cc := newFullContext(dir)
+
+	if _, err := cc.Run(context.Background(), "task"); err != nil {
+		t.Fatalf("first Run() error: %v", err)
+	}
+
+	writeFile(t, rulePath, "# After, with more content")
+
+	// Make sure the modification time differs on file systems with coarse timestamps.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(rulePath, later, later); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+
+	result, err := cc.Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("second Run() error: %v", err)
+	}
+
+	if len(result.Rules) != 1 || !strings.Contains(result.Rules[0].Content, "# After") {
+		t.Errorf("expected the modified rule, got %+v", result.Rules)
+	}
+}
+
+func TestContext_Close(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "Do the task.")
+
+	cc := newFullContext(dir)
+
+	if _, err := cc.Run(context.Background(), "task"); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if err := cc.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	// Local search paths are never removed.
+	if _, err := os.Stat(filepath.Join(dir, ".agents", "tasks", "task.md")); err != nil {
+		t.Errorf("expected the task file to remain after Close(): %v", err)
+	}
+
+	// A Context can still be run after Close.
+	if _, err := cc.Run(context.Background(), "task"); err != nil {
+		t.Errorf("Run() after Close() error: %v", err)
+	}
+}
+
+func TestContext_RunRemovesDownloads(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		session       bool
+		wantKeptAfter bool
+	}{
+		{name: "each run removes its downloads", session: false, wantKeptAfter: false},
+		{name: "a session keeps them until Close", session: true, wantKeptAfter: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "task", "", "Do the task.")
+
+			// A forced getter makes the directory a remote search path, which is downloaded.
+			remote := "file::" + dir
+			cc := New(WithSearchPaths(remote), WithSession(tt.session))
+
+			if _, err := cc.Run(context.Background(), "task"); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			_, err := os.Lstat(downloadDir(remote))
+			if kept := err == nil; kept != tt.wantKeptAfter {
+				t.Errorf("download kept after Run() = %v, want %v", kept, tt.wantKeptAfter)
+			}
+
+			if err := cc.Close(); err != nil {
+				t.Fatalf("Close() error: %v", err)
+			}
+
+			if _, err := os.Lstat(downloadDir(remote)); !os.IsNotExist(err) {
+				t.Errorf("expected Close() to remove the download, stat error: %v", err)
+			}
+		})
+	}
+}
+
+func TestContext_With(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "task", "", "Deploy to ${env}.")
+	createRule(t, dir, ".agents/rules/go.md", "team: go", "# Go Rule")
+	createRule(t, dir, ".agents/rules/python.md", "team: python", "# Python Rule")
+
+	cc := New(
+		WithSearchPaths(dir),
+		WithParams(taskparser.Params{"env": {"staging"}}),
+		WithSelectors(selectors.Selectors{"team": {"go": true}}),
+	)
+
+	derived := cc.With(
+		WithParams(taskparser.Params{"env": {"production"}}),
+		WithSelectors(selectors.Selectors{"team": {"python": true}}),
+	)
+
+	if derived.shared != cc.shared {
+		t.Error("With() does not share the downloaded search paths and parsed files")
+	}
+
+	result, err := derived.Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if !strings.Contains(result.Prompt, "Deploy to production.") || !strings.Contains(result.Prompt, "# Python Rule") ||
+		strings.Contains(result.Prompt, "# Go Rule") {
+		t.Errorf("Run() of With() copy did not use its options:\n%s", result.Prompt)
+	}
+
+	// The original Context is not changed.
+	result, err = cc.Run(context.Background(), "task")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if !strings.Contains(result.Prompt, "Deploy to staging.") || !strings.Contains(result.Prompt, "# Go Rule") {
+		t.Errorf("Run() of the original Context used the options of the copy:\n%s", result.Prompt)
+	}
+}
+
+func TestContext_WithConcurrentHooks(t *testing.T) {
+	t.Parallel()
+
+	// Each WithHooks grows the slice, so it is likely to have room for another hook.
+	cc := New(
+		WithHooks(&BaseHook{}), WithHooks(&BaseHook{}), WithHooks(&BaseHook{}),
+		WithSources(Source{Name: "a"}), WithSources(Source{Name: "b"}), WithSources(Source{Name: "c"}),
+	)
+
+	hooks := []Hook{&BaseHook{}, &BaseHook{}}
+	derived := make([]*Context, len(hooks))
+
+	var wg sync.WaitGroup
+
+	for i, hook := range hooks {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			derived[i] = cc.With(WithHooks(hook), WithSources(Source{Name: strconv.Itoa(i)}))
+		}()
+	}
+
+	wg.Wait()
+
+	for i, c := range derived {
+		if len(c.hooks) != 4 || c.hooks[3] != hooks[i] || len(c.sources) != 4 || c.sources[3].Name != strconv.Itoa(i) {
+			t.Errorf("With() copy %d does not have its own hook and source last", i)
+		}
+	}
+
+	if len(cc.hooks) != 3 || len(cc.sources) != 3 {
+		t.Error("With() changed the hooks or sources of the original Context")
+	}
+}
+
+func TestSharedState_KeepsSourceDirsOfRunningRuns(t *testing.T) {
+	t.Parallel()
+
+	shared := newSharedState()
+	t.Cleanup(func() { _ = (&Context{shared: shared}).Close() })
+
+	newDir := func() string {
+		t.Helper()
+
+		dir, err := shared.newSourceDir()
+		if err != nil {
+			t.Fatalf("newSourceDir() error: %v", err)
+		}
+
+		return dir
+	}
+
+	exists := func(dir string) bool {
+		_, err := os.Stat(dir)
+
+		return err == nil
+	}
+
+	running := newDir()
+
+	ended := make([]string, 0, maxSourceDirs+1)
+	for range maxSourceDirs + 1 {
+		dir := newDir()
+		shared.endSourceDir(dir)
+		ended = append(ended, dir)
+	}
+
+	if !exists(running) {
+		t.Error("the source directory of a running run was removed")
+	}
+
+	if exists(ended[0]) || exists(ended[1]) || !exists(ended[2]) {
+		t.Error("the oldest source directories of ended runs were not the ones removed")
+	}
+
+	// Once its run ends, the directory is the oldest and is removed first.
+	shared.endSourceDir(running)
+	shared.endSourceDir(newDir())
+
+	if exists(running) || !exists(ended[2]) {
+		t.Error("the source directory of an ended run was not removed first")
+	}
+}
+
+func TestContext_ConcurrentRunsShareBootstrap(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "bootstrap.log")
+
+	createTask(t, dir, "task", "", "Do the task.")
+	createRule(t, dir, ".agents/rules/setup.md", "", "# Setup")
+	createBootstrapScript(t, dir, ".agents/rules/setup.md",
+		"#!/bin/sh\necho start >> "+logPath+"\nsleep 0.05\necho end >> "+logPath+"\n")
+
+	cc := New(WithSearchPaths(dir), WithSession(true))
+
+	const runs = 4
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, runs)
+
+	for i := range runs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, errs[i] = cc.Run(context.Background(), "task")
+		}()
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Run() %d error: %v", i, err)
+		}
+	}
+
+	got, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read bootstrap log: %v", err)
+	}
+
+	if want := strings.Repeat("start\nend\n", runs); string(got) != want {
+		t.Errorf("bootstrap runs overlapped, log =\n%s", got)
+	}
+}
+
+func TestContext_WithRefresh(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		refresh time.Duration
+		wantErr bool
+	}{
+		{name: "downloads are reused", refresh: 0, wantErr: true},
+		{name: "downloads are refreshed", refresh: time.Nanosecond, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "task", "", "Do the task.")
+
+			remote := "file::" + dir
+			cc := New(WithSearchPaths(remote), WithSession(true), WithRefresh(tt.refresh))
+
+			t.Cleanup(func() { _ = cc.Close() })
+
+			if _, err := cc.Run(context.Background(), "task"); err != nil {
+				t.Fatalf("first Run() error: %v", err)
+			}
+
+			// Only a run that downloads the search path again finds the task.
+			if err := os.RemoveAll(downloadDir(remote)); err != nil {
+				t.Fatalf("failed to remove download: %v", err)
+			}
+
+			_, err := cc.Run(context.Background(), "task")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("second Run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContext_WithRefreshConcurrentSources(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "fix-bug", "", "Fix the bug.")
+
+	command := createSource(t, t.TempDir(), "tickets", sourceScript(sourceResponseJSON))
+	remote := "file::" + dir
+	cc := New(WithSearchPaths(remote), WithSources(Source{Name: "tickets", Command: command}),
+		WithSession(true), WithRefresh(time.Nanosecond))
+
+	const runs = 8
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, runs)
+
+	for i := range runs {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			// Every run refreshes the downloads while others are providing sources.
+			_, errs[i] = cc.Run(context.Background(), "fix-bug")
+		}()
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Fatal("concurrent runs did not finish")
+	}
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Run() %d error: %v", i, err)
+		}
+	}
+
+	if err := cc.Close(); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+}
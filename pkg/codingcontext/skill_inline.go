@@ -0,0 +1,138 @@
+package codingcontext
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/skills"
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/tokencount"
+)
+
+// skillResourceDirs are the skill subdirectories whose files are inlined with the skill.
+var skillResourceDirs = []string{"references", "scripts"}
+
+// shouldInlineSkill reports whether the named skill was requested with WithInlineSkills
+// or in the task's skills frontmatter field.
+func (cc *Context) shouldInlineSkill(name string) bool {
+	return slices.Contains(cc.inlineSkills, name) || slices.Contains(cc.task.FrontMatter.Skills, name)
+}
+
+// inlineSkill reads the full content of the skill at skillFile into skill.Content
+// and adds its tokens to the total.
+func (cc *Context) inlineSkill(skill *skills.Skill, skillFile string) error {
+	content, err := cc.readSkillContent(skill.Name, skillFile)
+	if err != nil {
+		return err
+	}
+
+	skill.Content = content
+	skill.Tokens = tokencount.EstimateTokens(content)
+	cc.totalTokens += skill.Tokens
+
+	cc.logger.Info("Inlining skill", "name", skill.Name, "tokens", skill.Tokens)
+
+	return nil
+}
+
+// warnMissingInlineSkills logs each requested inline skill that was not discovered.
+func (cc *Context) warnMissingInlineSkills() {
+	for _, name := range slices.Concat(cc.inlineSkills, cc.task.FrontMatter.Skills) {
+		found := slices.ContainsFunc(cc.skills.Skills, func(s skills.Skill) bool { return s.Name == name })
+		if !found {
+			cc.logger.Warn("Skill to inline not found", "name", name)
+		}
+	}
+}
+
+// readSkillContent returns the SKILL.md body followed by every file in the skill's
+// resource directories, each under a heading with its path relative to the skill.
+func (cc *Context) readSkillContent(name, skillFile string) (string, error) {
+	var frontmatter markdown.SkillFrontMatter
+
+	md, err := parseMarkdownFile(cc, skillFile, &frontmatter)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse skill file %s: %w", skillFile, err)
+	}
+
+	var b strings.Builder
+
+	b.WriteString("## Skill: " + name + "\n\n")
+	b.WriteString(strings.TrimSpace(md.Content))
+	b.WriteString("\n")
+
+	skillDir := filepath.Dir(skillFile)
+
+	for _, resourceDir := range skillResourceDirs {
+		if err := cc.writeSkillResources(&b, skillDir, resourceDir); err != nil {
+			return "", err
+		}
+	}
+
+	return b.String(), nil
+}
+
+// writeSkillResources writes each text file under skillDir/resourceDir as a fenced block.
+// Binary files are skipped because they cannot be represented in the prompt.
+func (cc *Context) writeSkillResources(b *strings.Builder, skillDir, resourceDir string) error {
+	root := filepath.Join(skillDir, resourceDir)
+
+	err := cc.walkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return nil
+		}
+
+		content, err := cc.readFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read skill resource %s: %w", path, err)
+		}
+
+		if !utf8.Valid(content) {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(skillDir, path)
+		fence := codeFence(string(content))
+
+		b.WriteString("\n### " + filepath.ToSlash(rel) + "\n\n")
+		b.WriteString(fence + strings.TrimPrefix(filepath.Ext(path), ".") + "\n")
+		b.WriteString(strings.TrimRight(string(content), "\n"))
+		b.WriteString("\n" + fence + "\n")
+
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to inline skill resources from %s: %w", root, err)
+	}
+
+	return nil
+}
+
+// codeFence returns a backtick fence longer than any backtick run in content.
+func codeFence(content string) string {
+	const minFence = 3
+
+	longest, run := 0, 0
+
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+
+	return strings.Repeat("`", max(minFence, longest+1))
+}
@@ -0,0 +1,130 @@
+package codingcontext
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInlineSkills(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		taskFM       string
+		opts         []Option
+		wantInlined  []string
+		wantListed   []string
+		wantContains []string
+		wantMissing  []string
+	}{
+		{
+			name:        "skills are listed by default",
+			wantListed:  []string{"pdf", "xlsx"},
+			wantMissing: []string{"Extract text with pdfplumber."},
+		},
+		{
+			name:        "inlined by option",
+			opts:        []Option{WithInlineSkills("pdf")},
+			wantInlined: []string{"pdf"},
+			wantListed:  []string{"xlsx"},
+			wantContains: []string{
+				"## Skill: pdf\n\nExtract text with pdfplumber.",
+				"### references/forms.md\n\n```md\n# Forms\n```",
+				"### scripts/extract.py\n\n```py\nprint('hi')\n```",
+			},
+			wantMissing: []string{"<name>pdf</name>", "assets/logo.txt"},
+		},
+		{
+			name:        "inlined by task frontmatter",
+			taskFM:      "skills: [xlsx]",
+			wantInlined: []string{"xlsx"},
+			wantListed:  []string{"pdf"},
+			wantContains: []string{
+				"## Skill: xlsx\n\nWork with spreadsheets.",
+			},
+		},
+		{
+			name:        "unknown skill is ignored",
+			opts:        []Option{WithInlineSkills("missing")},
+			wantListed:  []string{"pdf", "xlsx"},
+			wantMissing: []string{"loaded in full"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "task", tt.taskFM, "Task")
+			createSkill(t, dir, ".agents/skills/pdf",
+				"---\nname: pdf\ndescription: PDF processing\n---\nExtract text with pdfplumber.\n")
+			writeFile(t, filepath.Join(dir, ".agents/skills/pdf/references/forms.md"), "# Forms\n")
+			writeFile(t, filepath.Join(dir, ".agents/skills/pdf/scripts/extract.py"), "print('hi')\n")
+			writeFile(t, filepath.Join(dir, ".agents/skills/pdf/scripts/tool.bin"), "\xff\xfe\x00")
+			writeFile(t, filepath.Join(dir, ".agents/skills/pdf/assets/logo.txt"), "logo")
+			createSkill(t, dir, ".agents/skills/xlsx",
+				"---\nname: xlsx\ndescription: Spreadsheets\n---\nWork with spreadsheets.\n")
+
+			result, err := New(append([]Option{WithSearchPaths(dir)}, tt.opts...)...).Run(context.Background(), "task")
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			var inlined, listed []string
+
+			for _, s := range result.Skills.Skills {
+				if s.Inlined() {
+					inlined = append(inlined, s.Name)
+
+					if s.Tokens == 0 {
+						t.Errorf("inlined skill %s has no tokens", s.Name)
+					}
+				} else {
+					listed = append(listed, s.Name)
+				}
+			}
+
+			if strings.Join(inlined, ",") != strings.Join(tt.wantInlined, ",") {
+				t.Errorf("inlined skills = %v, want %v", inlined, tt.wantInlined)
+			}
+
+			if strings.Join(listed, ",") != strings.Join(tt.wantListed, ",") {
+				t.Errorf("listed skills = %v, want %v", listed, tt.wantListed)
+			}
+
+			for _, want := range tt.wantContains {
+				if !strings.Contains(result.Prompt, want) {
+					t.Errorf("prompt does not contain %q:\n%s", want, result.Prompt)
+				}
+			}
+
+			for _, unwanted := range tt.wantMissing {
+				if strings.Contains(result.Prompt, unwanted) {
+					t.Errorf("prompt unexpectedly contains %q:\n%s", unwanted, result.Prompt)
+				}
+			}
+		})
+	}
+}
+
+func TestCodeFence(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "plain", want: "```"},
+		{content: "has ``` fence", want: "````"},
+		{content: "has ````` long fence", want: "``````"},
+	}
+
+	for _, tt := range tests {
+		if got := codeFence(tt.content); got != tt.want {
+			t.Errorf("codeFence(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package codingcontext
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-getter/v2"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+)
+
+// SkillLockFile is the path of the skills lock file, relative to the working directory.
+const SkillLockFile = ".agents/skills-lock.json"
+
+// installSkillsDir is the directory skills are installed into, relative to the working directory.
+const installSkillsDir = ".agents/skills"
+
+var (
+	// ErrNoSkillsFound is returned when a skill source contains no SKILL.md files.
+	ErrNoSkillsFound = errors.New("no skills found")
+	// ErrSkillInvalid is returned when a skill to install or pack does not pass validation.
+	ErrSkillInvalid = errors.New("skill is invalid")
+)
+
+// SkillLock records where each installed skill came from, keyed by skill name.
+type SkillLock struct {
+	Skills map[string]LockedSkill `json:"skills"`
+}
+
+// LockedSkill is the source and version of an installed skill.
+type LockedSkill struct {
+	// Source is the go-getter URL the skill was installed from.
+	Source string `json:"source"`
+	// Ref is the version requested with the source's ref query parameter, if any.
+	Ref string `json:"ref,omitempty"`
+	// Digest is the SHA-256 digest of the installed skill's files.
+	Digest string `json:"digest"`
+}
+
+// InstalledSkill is a skill installed by InstallSkills.
+type InstalledSkill struct {
+	Name string
+	Path string
+	LockedSkill
+}
+
+// ReadSkillLock reads the lock file at path. A missing file is an empty lock.
+func ReadSkillLock(path string) (*SkillLock, error) {
+	lock := &SkillLock{Skills: make(map[string]LockedSkill)}
+
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return lock, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read skills lock file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse skills lock file %s: %w", path, err)
+	}
+
+	if lock.Skills == nil {
+		lock.Skills = make(map[string]LockedSkill)
+	}
+
+	return lock, nil
+}
+
+// Write writes the lock file to path.
+func (l *SkillLock) Write(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode skills lock file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create directory for skills lock file: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write skills lock file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// InstallSkills fetches src with go-getter and installs every skill it contains into
+// workDir/.agents/skills/<name>, replacing any existing copy, and records them in the
+// lock file. Src may be a skill directory, a .zip or .tar.gz bundle (such as one made by
+// PackSkill), or a directory or repository containing skills. Skills are validated
+// before anything is installed; an invalid skill fails the whole install.
+func InstallSkills(ctx context.Context, src, workDir string) ([]InstalledSkill, error) {
+	tmpDir, err := os.MkdirTemp("", "coding-context-skills-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	downloaded := filepath.Join(tmpDir, "src")
+
+	absWorkDir, err := filepath.Abs(workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	req := &getter.Request{Src: src, Dst: downloaded, Pwd: absWorkDir, GetMode: getter.ModeAny}
+	if _, err := getter.DefaultClient.Get(ctx, req); err != nil {
+		return nil, fmt.Errorf("failed to download skill %s: %w", src, err)
+	}
+
+	skillFiles, err := downloadedSkillFiles(downloaded)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stage every skill under its own name first so that validation checks the
+	// directory name it will be installed with.
+	staging := filepath.Join(tmpDir, "staging")
+
+	installed := make([]InstalledSkill, 0, len(skillFiles))
+
+	for _, skillFile := range skillFiles {
+		skill, err := stageSkill(skillFile, staging)
+		if err != nil {
+			return nil, err
+		}
+
+		skill.Source = src
+		skill.Ref = sourceRef(src)
+		installed = append(installed, skill)
+	}
+
+	lockPath := filepath.Join(workDir, SkillLockFile)
+
+	lock, err := ReadSkillLock(lockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, skill := range installed {
+		dst := filepath.Join(workDir, installSkillsDir, skill.Name)
+		if err := replaceDir(skill.Path, dst); err != nil {
+			return nil, err
+		}
+
+		installed[i].Path = dst
+		lock.Skills[skill.Name] = skill.LockedSkill
+	}
+
+	if err := lock.Write(lockPath); err != nil {
+		return nil, err
+	}
+
+	return installed, nil
+}
+
+// downloadedSkillFiles returns the SKILL.md files in a downloaded skill source: the
+// source itself, skills at its top level (as in a bundle), or skills in its skill
+// search paths.
+func downloadedSkillFiles(dir string) ([]string, error) {
+	dir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve downloaded skill: %w", err)
+	}
+
+	files, err := SkillFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(files) == 0 {
+		files, _ = filepath.Glob(filepath.Join(dir, "*", "SKILL.md"))
+	}
+
+	if len(files) == 0 {
+		return nil, ErrNoSkillsFound
+	}
+
+	return files, nil
+}
+
+// stageSkill copies the skill at skillFile into staging/<name> and validates it there.
+func stageSkill(skillFile, staging string) (InstalledSkill, error) {
+	var frontmatter markdown.SkillFrontMatter
+
+	if _, err := markdown.ParseMarkdownFile(skillFile, &frontmatter); err != nil {
+		return InstalledSkill{}, fmt.Errorf("%w: %w", ErrSkillInvalid, err)
+	}
+
+	// The name becomes a directory name, so check it before using it.
+	if !skillNamePattern.MatchString(frontmatter.Name) || len(frontmatter.Name) > maxSkillNameLen {
+		return InstalledSkill{}, fmt.Errorf("%w: %s: %w: %q",
+			ErrSkillInvalid, skillFile, ErrSkillNameFormat, frontmatter.Name)
+	}
+
+	dst := filepath.Join(staging, frontmatter.Name)
+	if fileExists(dst) {
+		return InstalledSkill{}, fmt.Errorf("%w: more than one skill named %q", ErrSkillInvalid, frontmatter.Name)
+	}
+
+	if err := copySkillDir(filepath.Dir(skillFile), dst); err != nil {
+		return InstalledSkill{}, err
+	}
+
+	if err := validateForDistribution(filepath.Join(dst, "SKILL.md")); err != nil {
+		return InstalledSkill{}, err
+	}
+
+	digest, err := skillDigest(dst)
+	if err != nil {
+		return InstalledSkill{}, err
+	}
+
+	return InstalledSkill{Name: frontmatter.Name, Path: dst, LockedSkill: LockedSkill{Digest: digest}}, nil
+}
+
+// validateForDistribution returns ErrSkillInvalid listing every problem found by ValidateSkill.
+func validateForDistribution(skillFile string) error {
+	problems, err := ValidateSkill(skillFile)
+	if err != nil {
+		return err
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	errs := make([]error, 0, len(problems))
+	for _, problem := range problems {
+		errs = append(errs, problem)
+	}
+
+	return fmt.Errorf("%w: %w", ErrSkillInvalid, errors.Join(errs...))
+}
+
+// sourceRef returns the ref query parameter of a go-getter URL, such as v1.2.0 in
+// git::https://example.com/skills.git?ref=v1.2.0.
+func sourceRef(src string) string {
+	if i := strings.Index(src, "::"); i >= 0 {
+		src = src[i+2:]
+	}
+
+	u, err := url.Parse(src)
+	if err != nil {
+		return ""
+	}
+
+	return u.Query().Get("ref")
+}
+
+// skillFilesIn returns the regular files in dir as slash-separated relative paths, in
+// sorted order. Version control directories are skipped.
+func skillFilesIn(dir string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return fmt.Errorf("failed to get relative path: %w", err)
+		}
+
+		files = append(files, filepath.ToSlash(rel))
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list skill files in %s: %w", dir, err)
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// copySkillDir copies the files of the skill in src to dst, keeping their permissions.
+func copySkillDir(src, dst string) error {
+	files, err := skillFilesIn(src)
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range files {
+		if err := copySkillFile(filepath.Join(src, rel), filepath.Join(dst, rel)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copySkillFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", src, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o750); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", dst, err)
+	}
+
+	in, err := os.Open(filepath.Clean(src))
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+
+	defer func() { _ = in.Close() }()
+
+	// #nosec G302 -- keep the skill file's own permissions so scripts stay executable
+	out, err := os.OpenFile(filepath.Clean(dst), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+
+	return nil
+}
+
+// skillDigest returns the SHA-256 digest of the paths and contents of the skill's files.
+func skillDigest(dir string) (string, error) {
+	files, err := skillFilesIn(dir)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+
+	for _, rel := range files {
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", fmt.Errorf("failed to read skill file: %w", err)
+		}
+
+		_, _ = fmt.Fprintf(hash, "%s\x00%d\x00", rel, len(content))
+		_, _ = hash.Write(content)
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// replaceDir replaces dst with a copy of src.
+func replaceDir(src, dst string) error {
+	if err := os.RemoveAll(dst); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dst, err)
+	}
+
+	return copySkillDir(src, dst)
+}
@@ -0,0 +1,178 @@
+package codingcontext
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPackAndInstallSkill(t *testing.T) {
+	t.Parallel()
+
+	srcDir := filepath.Join(t.TempDir(), "pdf-processing")
+	writeFile(t, filepath.Join(srcDir, "SKILL.md"),
+		"---\nname: pdf-processing\ndescription: Work with PDFs\n---\nRun scripts/extract.py.\n")
+	writeFile(t, filepath.Join(srcDir, "scripts", "extract.py"), "print()\n")
+
+	if err := os.Chmod(filepath.Join(srcDir, "scripts", "extract.py"), 0o700); err != nil {
+		t.Fatalf("failed to chmod: %v", err)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "pdf-processing.tar.gz")
+
+	f, err := os.Create(bundle)
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+
+	name, err := PackSkill(srcDir, f)
+	if err != nil {
+		t.Fatalf("PackSkill() error: %v", err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatalf("failed to close bundle: %v", err)
+	}
+
+	if name != "pdf-processing" {
+		t.Errorf("PackSkill() name = %q, want pdf-processing", name)
+	}
+
+	workDir := t.TempDir()
+
+	installed, err := InstallSkills(context.Background(), bundle, workDir)
+	if err != nil {
+		t.Fatalf("InstallSkills() error: %v", err)
+	}
+
+	if len(installed) != 1 || installed[0].Name != "pdf-processing" {
+		t.Fatalf("InstallSkills() = %+v, want pdf-processing", installed)
+	}
+
+	script := filepath.Join(workDir, ".agents/skills/pdf-processing/scripts/extract.py")
+
+	info, err := os.Stat(script)
+	if err != nil {
+		t.Fatalf("installed script missing: %v", err)
+	}
+
+	if info.Mode().Perm()&0o100 == 0 {
+		t.Errorf("installed script mode = %v, want executable", info.Mode())
+	}
+
+	lock, err := ReadSkillLock(filepath.Join(workDir, SkillLockFile))
+	if err != nil {
+		t.Fatalf("ReadSkillLock() error: %v", err)
+	}
+
+	locked := lock.Skills["pdf-processing"]
+	if locked.Source != bundle {
+		t.Errorf("locked source = %q, want %q", locked.Source, bundle)
+	}
+
+	wantDigest, err := skillDigest(srcDir)
+	if err != nil {
+		t.Fatalf("skillDigest() error: %v", err)
+	}
+
+	if locked.Digest != wantDigest || !strings.HasPrefix(locked.Digest, "sha256:") {
+		t.Errorf("locked digest = %q, want %q", locked.Digest, wantDigest)
+	}
+}
+
+func TestInstallSkills_Directory(t *testing.T) {
+	t.Parallel()
+
+	// A directory named differently from the skill is installed under the skill's name.
+	srcDir := filepath.Join(t.TempDir(), "repo")
+	writeFile(t, filepath.Join(srcDir, ".agents/skills/a/SKILL.md"), "---\nname: a\ndescription: A\n---\n")
+	writeFile(t, filepath.Join(srcDir, ".agents/skills/b/SKILL.md"), "---\nname: b\ndescription: B\n---\n")
+
+	workDir := t.TempDir()
+	writeFile(t, filepath.Join(workDir, ".agents/skills/a/stale.md"), "stale")
+
+	if _, err := InstallSkills(context.Background(), srcDir, workDir); err != nil {
+		t.Fatalf("InstallSkills() error: %v", err)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if !fileExists(filepath.Join(workDir, ".agents/skills", name, "SKILL.md")) {
+			t.Errorf("skill %s was not installed", name)
+		}
+	}
+
+	if fileExists(filepath.Join(workDir, ".agents/skills/a/stale.md")) {
+		t.Error("existing skill was not replaced")
+	}
+}
+
+func TestInstallSkills_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr error
+	}{
+		{
+			name:    "no skills",
+			files:   map[string]string{"README.md": "nothing here"},
+			wantErr: ErrNoSkillsFound,
+		},
+		{
+			name:    "unsafe name",
+			files:   map[string]string{"SKILL.md": "---\nname: ../escape\ndescription: d\n---\n"},
+			wantErr: ErrSkillNameFormat,
+		},
+		{
+			name:    "missing referenced file",
+			files:   map[string]string{"SKILL.md": "---\nname: s\ndescription: d\n---\nSee references/missing.md.\n"},
+			wantErr: ErrSkillMissingResource,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			srcDir := t.TempDir()
+			for rel, content := range tt.files {
+				writeFile(t, filepath.Join(srcDir, rel), content)
+			}
+
+			workDir := t.TempDir()
+
+			_, err := InstallSkills(context.Background(), srcDir, workDir)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("InstallSkills() error = %v, want %v", err, tt.wantErr)
+			}
+
+			if fileExists(filepath.Join(workDir, ".agents")) {
+				t.Error("invalid skill was installed")
+			}
+		})
+	}
+}
+
+func TestSourceRef(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "git::https://example.com/skills.git?ref=v1.2.0", want: "v1.2.0"},
+		{src: "github.com/org/skills//pdf?ref=main", want: "main"},
+		{src: "https://example.com/pdf.tar.gz", want: ""},
+		{src: "./skills/pdf", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := sourceRef(tt.src); got != tt.want {
+			t.Errorf("sourceRef(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package codingcontext
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/skills"
+)
+
+// DefaultSkillsPreamble introduces the list of available skills in the prompt.
+const DefaultSkillsPreamble = "You have access to the following skills. Skills are specialized capabilities " +
+	"that provide domain expertise, workflows, and procedural knowledge. When a task matches a skill's " +
+	"description, you can load the full skill content by reading the SKILL.md file at the location provided."
+
+// SkillsListing configures how available skills are listed in the prompt.
+type SkillsListing struct {
+	// Format is the format of the list. Defaults to skills.FormatXML.
+	Format skills.Format
+	// Template, if set, is a text/template that renders the list instead of Format.
+	// It is executed with the skills.AvailableSkills being listed.
+	Template string
+	// Preamble, if set, replaces DefaultSkillsPreamble. An empty string removes it.
+	Preamble *string
+	// OmitForAgents lists agents for which the list is left out of the prompt,
+	// for example because the agent discovers skills itself. Inlined skills are still included.
+	OmitForAgents []Agent
+}
+
+// listSkills returns the list of available skills in the configured format.
+func (l SkillsListing) listSkills(available skills.AvailableSkills) (string, error) {
+	var (
+		list string
+		err  error
+	)
+
+	if l.Template != "" {
+		list, err = available.AsTemplate(l.Template)
+	} else {
+		list, err = available.As(l.Format)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to list skills: %w", err)
+	}
+
+	return list, nil
+}
+
+// preamble returns the text written before the list of available skills.
+func (l SkillsListing) preamble() string {
+	if l.Preamble != nil {
+		return *l.Preamble
+	}
+
+	return DefaultSkillsPreamble
+}
+
+// writeSkills writes the skills section of the prompt: inlined skills in full, and the
+// remaining skills listed for the agent to load on demand.
+func (cc *Context) writeSkills(b *strings.Builder) error {
+	listed := skills.AvailableSkills{}
+
+	var inlined []skills.Skill
+
+	for _, skill := range cc.skills.Skills {
+		if skill.Inlined() {
+			inlined = append(inlined, skill)
+		} else {
+			listed.Skills = append(listed.Skills, skill)
+		}
+	}
+
+	if slices.Contains(cc.skillsListing.OmitForAgents, cc.agent) && cc.agent.IsSet() {
+		cc.logger.Info("Omitting skills list for agent", "agent", cc.agent, "skills", len(listed.Skills))
+		listed.Skills = nil
+	}
+
+	if len(listed.Skills) == 0 && len(inlined) == 0 {
+		return nil
+	}
+
+	b.WriteString("\n# Skills\n\n")
+
+	if len(listed.Skills) > 0 {
+		if preamble := cc.skillsListing.preamble(); preamble != "" {
+			b.WriteString(preamble)
+			b.WriteString("\n\n")
+		}
+
+		list, err := cc.skillsListing.listSkills(listed)
+		if err != nil {
+			return err
+		}
+
+		b.WriteString(strings.TrimRight(list, "\n"))
+		b.WriteString("\n\n")
+	}
+
+	if len(inlined) > 0 {
+		b.WriteString("The following skills have been loaded in full.\n\n")
+
+		for _, skill := range inlined {
+			b.WriteString(skill.Content)
+			b.WriteString("\n")
+		}
+	}
+
+	return nil
+}
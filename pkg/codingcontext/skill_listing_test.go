@@ -0,0 +1,118 @@
+package codingcontext
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/skills"
+)
+
+func TestSkillsListing(t *testing.T) {
+	t.Parallel()
+
+	empty := ""
+	custom := "Skills you can use:"
+
+	tests := []struct {
+		name         string
+		listing      SkillsListing
+		opts         []Option
+		wantContains []string
+		wantMissing  []string
+		wantErr      bool
+	}{
+		{
+			name:         "xml by default",
+			wantContains: []string{DefaultSkillsPreamble, "<name>pdf</name>"},
+		},
+		{
+			name:         "markdown",
+			listing:      SkillsListing{Format: skills.FormatMarkdown},
+			wantContains: []string{"| Name | Description | Location |", "| pdf | PDF processing |"},
+			wantMissing:  []string{"<available_skills>"},
+		},
+		{
+			name:         "json",
+			listing:      SkillsListing{Format: skills.FormatJSON},
+			wantContains: []string{`"name": "pdf"`},
+		},
+		{
+			name:         "template",
+			listing:      SkillsListing{Template: "{{range .Skills}}* {{.Name}}{{end}}"},
+			wantContains: []string{"# Skills\n\n" + DefaultSkillsPreamble + "\n\n* pdf\n\n"},
+		},
+		{
+			name:    "invalid template",
+			listing: SkillsListing{Template: "{{"},
+			wantErr: true,
+		},
+		{
+			name:         "custom preamble",
+			listing:      SkillsListing{Preamble: &custom},
+			wantContains: []string{"# Skills\n\nSkills you can use:\n\n<available_skills>"},
+			wantMissing:  []string{DefaultSkillsPreamble},
+		},
+		{
+			name:         "no preamble",
+			listing:      SkillsListing{Preamble: &empty},
+			wantContains: []string{"# Skills\n\n<available_skills>"},
+		},
+		{
+			name:        "omitted for agent",
+			listing:     SkillsListing{OmitForAgents: []Agent{AgentClaude}},
+			opts:        []Option{WithAgent(AgentClaude)},
+			wantMissing: []string{"# Skills", "pdf"},
+		},
+		{
+			name:         "not omitted for other agents",
+			listing:      SkillsListing{OmitForAgents: []Agent{AgentClaude}},
+			opts:         []Option{WithAgent(AgentCursor)},
+			wantContains: []string{"<name>pdf</name>"},
+		},
+		{
+			name:         "inlined skills are kept when the list is omitted",
+			listing:      SkillsListing{OmitForAgents: []Agent{AgentClaude}},
+			opts:         []Option{WithAgent(AgentClaude), WithInlineSkills("pdf")},
+			wantContains: []string{"## Skill: pdf"},
+			wantMissing:  []string{DefaultSkillsPreamble},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "task", "", "Task")
+			createSkill(t, dir, ".agents/skills/pdf", "---\nname: pdf\ndescription: PDF processing\n---\nBody\n")
+
+			opts := append([]Option{WithSearchPaths(dir), WithSkillsListing(tt.listing)}, tt.opts...)
+
+			result, err := New(opts...).Run(context.Background(), "task")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Run() expected error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			for _, want := range tt.wantContains {
+				if !strings.Contains(result.Prompt, want) {
+					t.Errorf("prompt does not contain %q:\n%s", want, result.Prompt)
+				}
+			}
+
+			for _, unwanted := range tt.wantMissing {
+				if strings.Contains(result.Prompt, unwanted) {
+					t.Errorf("prompt unexpectedly contains %q:\n%s", unwanted, result.Prompt)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package codingcontext
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+)
+
+// PackSkill validates the skill in skillDir and writes it to w as a .tar.gz bundle with
+// the skill's files under a top-level directory named after the skill, which InstallSkills
+// can install. It returns the skill's name.
+func PackSkill(skillDir string, w io.Writer) (string, error) {
+	skillFile := filepath.Join(skillDir, "SKILL.md")
+
+	var frontmatter markdown.SkillFrontMatter
+
+	if _, err := markdown.ParseMarkdownFile(skillFile, &frontmatter); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSkillInvalid, err)
+	}
+
+	if err := validateForDistribution(skillFile); err != nil {
+		return "", err
+	}
+
+	files, err := skillFilesIn(skillDir)
+	if err != nil {
+		return "", err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, rel := range files {
+		file := filepath.Join(skillDir, filepath.FromSlash(rel))
+		if err := writeTarFile(tw, file, path.Join(frontmatter.Name, rel)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("failed to write skill bundle: %w", err)
+	}
+
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to write skill bundle: %w", err)
+	}
+
+	return frontmatter.Name, nil
+}
+
+func writeTarFile(tw *tar.Writer, file, name string) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", file, err)
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return fmt.Errorf("failed to create tar header for %s: %w", file, err)
+	}
+
+	// Keep bundles reproducible: only the name, mode, and content matter.
+	header.Name = name
+	header.ModTime = time.Unix(0, 0)
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", file, err)
+	}
+
+	in, err := os.Open(filepath.Clean(file))
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file, err)
+	}
+
+	defer func() { _ = in.Close() }()
+
+	if _, err := io.Copy(tw, in); err != nil {
+		return fmt.Errorf("failed to write %s to bundle: %w", file, err)
+	}
+
+	return nil
+}
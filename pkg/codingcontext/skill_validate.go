@@ -0,0 +1,353 @@
+package codingcontext
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/markdown"
+)
+
+// Limits from the Agent Skills specification.
+const (
+	maxSkillNameLen          = 64
+	maxSkillDescLen          = 1024
+	maxSkillCompatibilityLen = 500
+)
+
+var (
+	// ErrSkillNameFormat is returned when a skill's name is not lowercase alphanumeric words joined by hyphens.
+	ErrSkillNameFormat = errors.New("skill 'name' field must contain only lowercase letters, digits, " +
+		"and single hyphens, and must not start or end with a hyphen")
+	// ErrSkillNameMismatch is returned when a skill's name differs from its directory name.
+	ErrSkillNameMismatch = errors.New("skill 'name' field must match the skill directory name")
+	// ErrSkillCompatibilityLength is returned when a skill's compatibility field exceeds the maximum length.
+	ErrSkillCompatibilityLength = errors.New("skill 'compatibility' field must be at most 500 characters")
+	// ErrSkillAllowedTools is returned when a skill's allowed tools are not a space-delimited list of tools.
+	ErrSkillAllowedTools = errors.New("skill 'allowed_tools' field must be a space-delimited list of tools " +
+		"such as Read or Bash(git:*)")
+	// ErrSkillResourceNotDir is returned when a skill's scripts, references, or assets entry is not a directory.
+	ErrSkillResourceNotDir = errors.New("skill resource must be a directory")
+	// ErrSkillMissingResource is returned when a skill's content references a resource file that does not exist.
+	ErrSkillMissingResource = errors.New("skill references a missing file")
+)
+
+var (
+	skillNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// A tool is a name with optional arguments in parentheses, e.g. Read or Bash(git status:*).
+	skillToolPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*(\([^()]*\))?$`)
+	// Relative references to files in a skill's resource directories.
+	skillResourceRefPattern = regexp.MustCompile("(?:^|[\\s(\\[\"'`])((?:scripts|references|assets)/[^\\s)\\]\"'`]+)")
+	frontMatterKeyPattern   = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*:`)
+)
+
+// skillStructureDirs are the optional directories of a skill defined by the Agent Skills specification.
+var skillStructureDirs = []string{"scripts", "references", "assets"}
+
+// SkillProblem is a violation of the Agent Skills specification found in a SKILL.md file.
+type SkillProblem struct {
+	Path string
+	Line int // 1-indexed; 0 means unknown
+	Err  error
+}
+
+func (p SkillProblem) Error() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", p.Path, p.Line, p.Err)
+	}
+
+	return fmt.Sprintf("%s: %v", p.Path, p.Err)
+}
+
+func (p SkillProblem) Unwrap() error {
+	return p.Err
+}
+
+// ValidateSkill checks the SKILL.md file at skillFile against the Agent Skills specification
+// and returns every problem found. The returned error is only set when the file cannot be read.
+func ValidateSkill(skillFile string) ([]SkillProblem, error) {
+	source, err := os.ReadFile(filepath.Clean(skillFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read skill file %s: %w", skillFile, err)
+	}
+
+	var frontmatter markdown.SkillFrontMatter
+
+	if _, err := markdown.ParseMarkdownFile(skillFile, &frontmatter); err != nil {
+		var pe *markdown.ParseError
+		if errors.As(err, &pe) {
+			return []SkillProblem{{Path: skillFile, Line: pe.Line, Err: errors.New(pe.Message)}}, nil
+		}
+
+		return []SkillProblem{{Path: skillFile, Err: err}}, nil
+	}
+
+	lines, _ := frontMatterLines(source)
+	problems := skillRequiredProblems(skillFile, frontmatter, lines)
+
+	return append(problems, skillSpecProblems(skillFile, frontmatter, source, os.Stat)...), nil
+}
+
+// ValidateSkillName checks name against the naming rules of the Agent Skills specification,
+// e.g. before creating a skill directory with that name.
+func ValidateSkillName(name string) error {
+	switch {
+	case name == "" || len(name) > maxSkillNameLen:
+		return fmt.Errorf("%w (got %d)", ErrSkillNameLength, len(name))
+	case !skillNamePattern.MatchString(name):
+		return fmt.Errorf("%w: %q", ErrSkillNameFormat, name)
+	default:
+		return nil
+	}
+}
+
+// SkillFiles returns the SKILL.md files for path. Path may be a SKILL.md file, a skill
+// directory, or a directory whose skill search paths (e.g. .agents/skills) are searched,
+// including those of every namespace.
+func SkillFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	if skillFile := filepath.Join(path, "SKILL.md"); fileExists(skillFile) {
+		return []string{skillFile}, nil
+	}
+
+	dirs := skillSearchPaths(path)
+
+	namespaces, _ := filepath.Glob(filepath.Join(path, ".agents/namespaces", "*", "skills"))
+	dirs = append(dirs, namespaces...)
+
+	var files []string
+
+	for _, dir := range dirs {
+		matches, err := filepath.Glob(filepath.Join(dir, "*", "SKILL.md"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to search %s for skills: %w", dir, err)
+		}
+
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
+
+// checkSkillSpec reports problems found by skillSpecProblems. They are lint errors in
+// lint mode and warnings otherwise; either way the skill is still used.
+func (cc *Context) checkSkillSpec(skillFile string, fm markdown.SkillFrontMatter, source []byte) {
+	for _, problem := range skillSpecProblems(skillFile, fm, source, cc.stat) {
+		if cc.lintMode {
+			cc.lintCollector.recordErrorAt(skillFile, LintErrorKindSkillValidation, problem.Err.Error(), problem.Line)
+
+			continue
+		}
+
+		cc.logger.Warn("skill does not follow the Agent Skills specification",
+			"path", skillFile, "line", problem.Line, "error", problem.Err)
+	}
+}
+
+// skillRequiredProblems checks the required name and description fields.
+func skillRequiredProblems(skillFile string, fm markdown.SkillFrontMatter, lines map[string]int) []SkillProblem {
+	var problems []SkillProblem
+
+	add := func(key string, err error) {
+		problems = append(problems, SkillProblem{Path: skillFile, Line: lines[key], Err: err})
+	}
+
+	switch {
+	case fm.Name == "":
+		add("name", ErrSkillMissingName)
+	case len(fm.Name) > maxSkillNameLen:
+		add("name", fmt.Errorf("%w (got %d)", ErrSkillNameLength, len(fm.Name)))
+	}
+
+	switch {
+	case fm.Description == "":
+		add("description", ErrSkillMissingDesc)
+	case len(fm.Description) > maxSkillDescLen:
+		add("description", fmt.Errorf("%w (got %d)", ErrSkillDescriptionLength, len(fm.Description)))
+	}
+
+	return problems
+}
+
+// skillSpecProblems checks the parts of the Agent Skills specification beyond the required
+// fields: the name format, that the name matches the directory, the compatibility length,
+// the allowed tools syntax, and the skill's directory structure, which is looked up with stat.
+func skillSpecProblems(
+	skillFile string, fm markdown.SkillFrontMatter, source []byte, stat func(string) (fs.FileInfo, error),
+) []SkillProblem {
+	lines, bodyStart := frontMatterLines(source)
+
+	var problems []SkillProblem
+
+	add := func(line int, err error) {
+		problems = append(problems, SkillProblem{Path: skillFile, Line: line, Err: err})
+	}
+
+	skillDir := filepath.Dir(skillFile)
+
+	if fm.Name != "" {
+		if !skillNamePattern.MatchString(fm.Name) {
+			add(lines["name"], fmt.Errorf("%w: %q", ErrSkillNameFormat, fm.Name))
+		}
+
+		if dirName := filepath.Base(skillDir); fm.Name != dirName {
+			add(lines["name"], fmt.Errorf("%w: %q is in directory %q", ErrSkillNameMismatch, fm.Name, dirName))
+		}
+	}
+
+	if len(fm.Compatibility) > maxSkillCompatibilityLen {
+		add(lines["compatibility"], fmt.Errorf("%w (got %d)", ErrSkillCompatibilityLength, len(fm.Compatibility)))
+	}
+
+	toolsKey, tools := "allowed_tools", fm.AllowedTools
+	if spec, ok := fm.Content["allowed-tools"].(string); ok && tools == "" {
+		toolsKey, tools = "allowed-tools", spec
+	}
+
+	for _, tool := range splitAllowedTools(tools) {
+		if !skillToolPattern.MatchString(tool) {
+			add(lines[toolsKey], fmt.Errorf("%w: %q", ErrSkillAllowedTools, tool))
+		}
+	}
+
+	for _, dir := range skillStructureDirs {
+		if info, err := stat(filepath.Join(skillDir, dir)); err == nil && !info.IsDir() {
+			add(0, fmt.Errorf("%w: %s", ErrSkillResourceNotDir, dir))
+		}
+	}
+
+	body := source[min(lineOffset(source, bodyStart), len(source)):]
+
+	for _, ref := range skillResourceRefs(body) {
+		if _, err := stat(filepath.Join(skillDir, filepath.FromSlash(ref.path))); err != nil {
+			add(bodyStart+ref.line-1, fmt.Errorf("%w: %s", ErrSkillMissingResource, ref.path))
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+
+	return problems
+}
+
+// splitAllowedTools splits a space-delimited tool list, keeping spaces inside parentheses.
+func splitAllowedTools(tools string) []string {
+	var (
+		fields  []string
+		current strings.Builder
+		depth   int
+	)
+
+	flush := func() {
+		if current.Len() > 0 {
+			fields = append(fields, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range tools {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case (r == ' ' || r == '\t' || r == '\n') && depth <= 0:
+			flush()
+
+			continue
+		}
+
+		current.WriteRune(r)
+	}
+
+	flush()
+
+	return fields
+}
+
+// skillResourceRef is a reference to a resource file found in a skill's content.
+type skillResourceRef struct {
+	path string
+	line int // 1-indexed line within the content
+}
+
+// skillResourceRefs returns the references to files in resource directories in content.
+func skillResourceRefs(content []byte) []skillResourceRef {
+	var refs []skillResourceRef
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for line := 1; scanner.Scan(); line++ {
+		for _, match := range skillResourceRefPattern.FindAllStringSubmatch(scanner.Text(), -1) {
+			path := strings.TrimRight(match[1], ".,;:!?")
+			if strings.HasSuffix(path, "/") {
+				continue // a directory, not a file
+			}
+
+			refs = append(refs, skillResourceRef{path: path, line: line})
+		}
+	}
+
+	return refs
+}
+
+// frontMatterLines returns the 1-indexed line of each top-level frontmatter key in source,
+// and the line on which the content after the frontmatter starts.
+func frontMatterLines(source []byte) (map[string]int, int) {
+	lines := make(map[string]int)
+
+	scanner := bufio.NewScanner(bytes.NewReader(source))
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "---" {
+		return lines, 1
+	}
+
+	for line := 2; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "---" {
+			return lines, line + 1
+		}
+
+		if match := frontMatterKeyPattern.FindStringSubmatch(text); match != nil {
+			if _, ok := lines[match[1]]; !ok {
+				lines[match[1]] = line
+			}
+		}
+	}
+
+	return lines, 1
+}
+
+// lineOffset returns the byte offset of the 1-indexed line in source.
+func lineOffset(source []byte, line int) int {
+	offset := 0
+
+	for range line - 1 {
+		i := bytes.IndexByte(source[offset:], '\n')
+		if i < 0 {
+			return len(source)
+		}
+
+		offset += i + 1
+	}
+
+	return offset
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+
+	return err == nil
+}
@@ -0,0 +1,227 @@
+package codingcontext
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidateSkill(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		dirName   string
+		content   string
+		setup     func(t *testing.T, skillDir string)
+		wantErrs  []error
+		wantLines []int
+	}{
+		{
+			name:    "valid skill",
+			dirName: "pdf-processing",
+			content: "---\nname: pdf-processing\ndescription: Work with PDFs\nallowed_tools: Read Bash(git status:*)\n---\n" +
+				"Run scripts/extract.py.\n",
+			setup: func(t *testing.T, skillDir string) {
+				t.Helper()
+				writeFile(t, filepath.Join(skillDir, "scripts", "extract.py"), "print()")
+			},
+		},
+		{
+			name:      "missing required fields",
+			dirName:   "empty",
+			content:   "---\nlicense: MIT\n---\nBody\n",
+			wantErrs:  []error{ErrSkillMissingName, ErrSkillMissingDesc},
+			wantLines: []int{0, 0},
+		},
+		{
+			name:      "name format",
+			dirName:   "Bad--Name",
+			content:   "---\nname: Bad--Name\ndescription: d\n---\n",
+			wantErrs:  []error{ErrSkillNameFormat},
+			wantLines: []int{2},
+		},
+		{
+			name:      "name does not match directory",
+			dirName:   "other",
+			content:   "---\ndescription: d\nname: pdf\n---\n",
+			wantErrs:  []error{ErrSkillNameMismatch},
+			wantLines: []int{3},
+		},
+		{
+			name:      "compatibility too long",
+			dirName:   "s",
+			content:   "---\nname: s\ndescription: d\ncompatibility: " + strings.Repeat("x", 501) + "\n---\n",
+			wantErrs:  []error{ErrSkillCompatibilityLength},
+			wantLines: []int{4},
+		},
+		{
+			name:      "invalid allowed tools",
+			dirName:   "s",
+			content:   "---\nname: s\ndescription: d\nallowed_tools: Read Bash(git\n---\n",
+			wantErrs:  []error{ErrSkillAllowedTools},
+			wantLines: []int{4},
+		},
+		{
+			name:      "spec allowed-tools key is checked",
+			dirName:   "s",
+			content:   "---\nname: s\ndescription: d\nallowed-tools: \"Read ,\"\n---\n",
+			wantErrs:  []error{ErrSkillAllowedTools},
+			wantLines: []int{4},
+		},
+		{
+			name:    "resource is not a directory",
+			dirName: "s",
+			content: "---\nname: s\ndescription: d\n---\n",
+			setup: func(t *testing.T, skillDir string) {
+				t.Helper()
+				writeFile(t, filepath.Join(skillDir, "assets"), "not a dir")
+			},
+			wantErrs:  []error{ErrSkillResourceNotDir},
+			wantLines: []int{0},
+		},
+		{
+			name:      "missing referenced file",
+			dirName:   "s",
+			content:   "---\nname: s\ndescription: d\n---\n# Usage\n\nSee [forms](references/forms.md).\n",
+			wantErrs:  []error{ErrSkillMissingResource},
+			wantLines: []int{7},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			skillDir := filepath.Join(t.TempDir(), tt.dirName)
+			skillFile := filepath.Join(skillDir, "SKILL.md")
+			writeFile(t, skillFile, tt.content)
+
+			if tt.setup != nil {
+				tt.setup(t, skillDir)
+			}
+
+			problems, err := ValidateSkill(skillFile)
+			if err != nil {
+				t.Fatalf("ValidateSkill() error: %v", err)
+			}
+
+			if len(problems) != len(tt.wantErrs) {
+				t.Fatalf("got %d problems, want %d: %v", len(problems), len(tt.wantErrs), problems)
+			}
+
+			for i, problem := range problems {
+				if !errors.Is(problem, tt.wantErrs[i]) {
+					t.Errorf("problem %d = %v, want %v", i, problem, tt.wantErrs[i])
+				}
+
+				if problem.Line != tt.wantLines[i] {
+					t.Errorf("problem %d line = %d, want %d", i, problem.Line, tt.wantLines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSplitAllowedTools(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tools string
+		want  []string
+	}{
+		{tools: "", want: nil},
+		{tools: "Read Write", want: []string{"Read", "Write"}},
+		{tools: "Bash(git status:*)  Read", want: []string{"Bash(git status:*)", "Read"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitAllowedTools(tt.tools); !slices.Equal(got, tt.want) {
+			t.Errorf("splitAllowedTools(%q) = %q, want %q", tt.tools, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSkillName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		want error
+	}{
+		{name: "pdf-processing", want: nil},
+		{name: "v2", want: nil},
+		{name: "", want: ErrSkillNameLength},
+		{name: strings.Repeat("a", 65), want: ErrSkillNameLength},
+		{name: "PDF", want: ErrSkillNameFormat},
+		{name: "pdf--processing", want: ErrSkillNameFormat},
+		{name: "-pdf", want: ErrSkillNameFormat},
+		{name: "pdf/processing", want: ErrSkillNameFormat},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateSkillName(tt.name); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateSkillName(%q) = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestSkillFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".agents/skills/a/SKILL.md"), "")
+	writeFile(t, filepath.Join(dir, ".claude/skills/b/SKILL.md"), "")
+	writeFile(t, filepath.Join(dir, ".agents/namespaces/team/skills/c/SKILL.md"), "")
+
+	files, err := SkillFiles(dir)
+	if err != nil {
+		t.Fatalf("SkillFiles() error: %v", err)
+	}
+
+	if len(files) != 3 {
+		t.Errorf("SkillFiles(dir) = %v, want 3 files", files)
+	}
+
+	files, err = SkillFiles(filepath.Join(dir, ".agents/skills/a"))
+	if err != nil {
+		t.Fatalf("SkillFiles() error: %v", err)
+	}
+
+	if len(files) != 1 || filepath.Base(filepath.Dir(files[0])) != "a" {
+		t.Errorf("SkillFiles(skillDir) = %v, want the skill's SKILL.md", files)
+	}
+}
+
+func TestLint_SkillSpecProblemsAreReported(t *testing.T) {
+	t.Parallel()
+	dir := lintTestDir(t)
+
+	createTask(t, dir, "task1", "", "Do stuff.")
+	createSkill(t, dir, ".agents/skills/myskill", "---\nname: my_skill\ndescription: A skill\n---\nBody")
+
+	result, err := newLintContext(dir).Lint(context.Background(), "task1")
+	if err != nil {
+		t.Fatalf("Lint() returned error: %v", err)
+	}
+
+	found := false
+
+	for _, e := range result.Errors {
+		if e.Kind == LintErrorKindSkillValidation && e.Line == 2 {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Errorf("expected skill-validation error on line 2, got %+v", result.Errors)
+	}
+
+	// Spec problems do not prevent the skill from being used.
+	if len(result.Skills.Skills) != 1 {
+		t.Errorf("expected the skill to be discovered, got %+v", result.Skills.Skills)
+	}
+}
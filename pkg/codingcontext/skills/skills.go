@@ -2,16 +2,71 @@
 package skills
 
 import (
+	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"strings"
+	"text/template"
 )
 
+// ErrUnknownFormat is returned when parsing an unknown skills listing format.
+var ErrUnknownFormat = errors.New("unknown skills format")
+
+// Format is a format for listing available skills in the prompt.
+type Format string
+
+// Supported formats.
+const (
+	FormatXML      Format = "xml"
+	FormatMarkdown Format = "markdown"
+	FormatJSON     Format = "json"
+)
+
+// ParseFormat parses a string into a Format.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatXML, FormatMarkdown, FormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("%w: %s (supported: xml, markdown, json)", ErrUnknownFormat, s)
+	}
+}
+
+// String returns the string representation of the format.
+func (f *Format) String() string {
+	if f == nil {
+		return ""
+	}
+
+	return string(*f)
+}
+
+// Set implements the flag.Value interface for Format.
+func (f *Format) Set(value string) error {
+	format, err := ParseFormat(value)
+	if err != nil {
+		return err
+	}
+
+	*f = format
+
+	return nil
+}
+
 // Skill represents a discovered skill with its metadata.
 type Skill struct {
-	XMLName     xml.Name `xml:"skill"`
-	Name        string   `xml:"name"`
-	Description string   `xml:"description"`
-	Location    string   `xml:"location"` // Absolute path to the SKILL.md file
+	XMLName     xml.Name `json:"-"                 xml:"skill"`
+	Name        string   `json:"name"              xml:"name"`
+	Description string   `json:"description"       xml:"description"`
+	Location    string   `json:"location"          xml:"location"` // Absolute path to the SKILL.md file
+	Content     string   `json:"content,omitempty" xml:"-"`        // Full skill content when inlined, otherwise empty
+	Tokens      int      `json:"tokens,omitempty"  xml:"-"`        // Estimated tokens of Content
+}
+
+// Inlined reports whether the skill's full content is included in the prompt.
+func (s Skill) Inlined() bool {
+	return s.Content != ""
 }
 
 // AvailableSkills represents a collection of discovered skills.
@@ -30,3 +85,65 @@ func (a AvailableSkills) AsXML() (string, error) {
 
 	return string(xmlBytes), nil
 }
+
+// AsMarkdown returns the available skills as a markdown table.
+func (a AvailableSkills) AsMarkdown() string {
+	cell := strings.NewReplacer("|", "\\|", "\r\n", " ", "\n", " ")
+
+	var b strings.Builder
+
+	b.WriteString("| Name | Description | Location |\n")
+	b.WriteString("| --- | --- | --- |")
+
+	for _, skill := range a.Skills {
+		b.WriteString("\n| " + cell.Replace(skill.Name) + " | " + cell.Replace(skill.Description) +
+			" | " + cell.Replace(skill.Location) + " |")
+	}
+
+	return b.String()
+}
+
+// AsJSON returns the available skills as a JSON array.
+func (a AvailableSkills) AsJSON() (string, error) {
+	list := a.Skills
+	if list == nil {
+		list = []Skill{}
+	}
+
+	jsonBytes, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal skills to JSON: %w", err)
+	}
+
+	return string(jsonBytes), nil
+}
+
+// AsTemplate returns the available skills rendered with the text/template text,
+// which is executed with the AvailableSkills as its data.
+func (a AvailableSkills) AsTemplate(text string) (string, error) {
+	tmpl, err := template.New("skills").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse skills template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, a); err != nil {
+		return "", fmt.Errorf("failed to execute skills template: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+// As returns the available skills in the given format.
+func (a AvailableSkills) As(format Format) (string, error) {
+	switch format {
+	case FormatXML, "":
+		return a.AsXML()
+	case FormatMarkdown:
+		return a.AsMarkdown(), nil
+	case FormatJSON:
+		return a.AsJSON()
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
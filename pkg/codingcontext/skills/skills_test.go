@@ -1,6 +1,7 @@
 package skills
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -90,3 +91,97 @@ func TestSkillsNotNil(t *testing.T) {
 		t.Errorf("Expected name 'test', got %q", skill.Name)
 	}
 }
+
+func TestAvailableSkills_As(t *testing.T) {
+	t.Parallel()
+
+	available := AvailableSkills{Skills: []Skill{
+		{Name: "pdf", Description: "Read | write\nPDFs", Location: "/skills/pdf/SKILL.md"},
+	}}
+
+	tests := []struct {
+		format Format
+		want   string
+	}{
+		{
+			format: FormatMarkdown,
+			want: "| Name | Description | Location |\n| --- | --- | --- |\n" +
+				"| pdf | Read \\| write PDFs | /skills/pdf/SKILL.md |",
+		},
+		{
+			format: FormatJSON,
+			want: "[\n  {\n    \"name\": \"pdf\",\n    \"description\": \"Read | write\\nPDFs\",\n" +
+				"    \"location\": \"/skills/pdf/SKILL.md\"\n  }\n]",
+		},
+		{
+			format: "",
+			want: "<available_skills>\n  <skill>\n    <name>pdf</name>\n" +
+				"    <description>Read | write&#xA;PDFs</description>\n" +
+				"    <location>/skills/pdf/SKILL.md</location>\n  </skill>\n</available_skills>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			t.Parallel()
+
+			got, err := available.As(tt.format)
+			if err != nil {
+				t.Fatalf("As(%q) error: %v", tt.format, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("As(%q) mismatch\nGot:\n%s\n\nWant:\n%s", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAvailableSkills_AsJSONEmpty(t *testing.T) {
+	t.Parallel()
+
+	got, err := AvailableSkills{}.AsJSON()
+	if err != nil {
+		t.Fatalf("AsJSON() error: %v", err)
+	}
+
+	if got != "[]" {
+		t.Errorf("AsJSON() = %q, want []", got)
+	}
+}
+
+func TestAvailableSkills_AsTemplate(t *testing.T) {
+	t.Parallel()
+
+	available := AvailableSkills{Skills: []Skill{
+		{Name: "pdf", Description: "PDFs", Location: "/a"},
+		{Name: "xlsx", Description: "Spreadsheets", Location: "/b"},
+	}}
+
+	got, err := available.AsTemplate("{{range .Skills}}- {{.Name}}: {{.Description}}\n{{end}}")
+	if err != nil {
+		t.Fatalf("AsTemplate() error: %v", err)
+	}
+
+	if want := "- pdf: PDFs\n- xlsx: Spreadsheets\n"; got != want {
+		t.Errorf("AsTemplate() = %q, want %q", got, want)
+	}
+
+	if _, err := available.AsTemplate("{{.Missing"); err == nil {
+		t.Error("AsTemplate() with an invalid template: expected error")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"xml", "markdown", "json"} {
+		if f, err := ParseFormat(s); err != nil || string(f) != s {
+			t.Errorf("ParseFormat(%q) = %q, %v", s, f, err)
+		}
+	}
+
+	if _, err := ParseFormat("yaml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("ParseFormat(yaml) error = %v, want ErrUnknownFormat", err)
+	}
+}
@@ -0,0 +1,339 @@
+package codingcontext
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	yaml "github.com/goccy/go-yaml"
+)
+
+// SourcePrefix is the prefix of the names of source executables found by FindSources.
+const SourcePrefix = "coding-context-source-"
+
+// SourceConfigFile is the path of a source config file, relative to the working
+// directory (repo-level) or the home directory (user-level).
+const SourceConfigFile = ".agents/sources.yaml"
+
+var (
+	// ErrInvalidSourceConfig is returned when a source config file is invalid.
+	ErrInvalidSourceConfig = errors.New("invalid source config")
+	// ErrInvalidSourceResponse is returned when a source writes an invalid response.
+	ErrInvalidSourceResponse = errors.New("invalid source response")
+
+	sourceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// Source is an external program that provides rules and skills, e.g. from a database or
+// a ticket system. It is run once per run, once the task is found, with a SourceRequest
+// as JSON on stdin, and writes a SourceResponse as JSON to stdout. Its rules and skills
+// are then selected like rule and skill files.
+type Source struct {
+	// Name identifies the source in logs and names its rules' files.
+	Name string `yaml:"name"`
+	// Command is the executable, looked up on PATH when it has no path separator.
+	Command string `yaml:"command"`
+	// Args are passed to Command.
+	Args []string `yaml:"args"`
+}
+
+// SourceRequest describes the run a source provides rules and skills for.
+type SourceRequest struct {
+	TaskName  string              `json:"task_name"`
+	Namespace string              `json:"namespace,omitempty"`
+	Agent     string              `json:"agent,omitempty"`
+	Selectors map[string][]string `json:"selectors"`
+	Params    map[string][]string `json:"params"`
+}
+
+// SourceResponse holds the rules and skills provided by a source.
+type SourceResponse struct {
+	Rules  []SourceRule  `json:"rules"`
+	Skills []SourceSkill `json:"skills"`
+}
+
+// SourceRule is a rule provided by a source, with the frontmatter and content of a rule file.
+type SourceRule struct {
+	Name        string         `json:"name"`
+	FrontMatter map[string]any `json:"frontmatter,omitempty"`
+	Content     string         `json:"content"`
+}
+
+// SourceSkill is a skill provided by a source, with the frontmatter and content of a SKILL.md file.
+type SourceSkill struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	FrontMatter map[string]any `json:"frontmatter,omitempty"`
+	Content     string         `json:"content"`
+}
+
+// sourceConfigFile is the format of a source config file.
+type sourceConfigFile struct {
+	Sources []Source `yaml:"sources"`
+}
+
+// FindSources returns a source for each executable named coding-context-source-<name> in
+// the directories of pathList (e.g. $PATH). Like a PATH lookup, the first one of a name wins.
+func FindSources(pathList string) []Source {
+	var sources []Source
+
+	seen := make(map[string]bool)
+
+	for _, dir := range filepath.SplitList(pathList) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), SourcePrefix)
+			if !ok || name == "" || seen[name] || !sourceNamePattern.MatchString(name) {
+				continue
+			}
+
+			info, err := entry.Info()
+			if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+				continue
+			}
+
+			seen[name] = true
+
+			sources = append(sources, Source{Name: name, Command: filepath.Join(dir, entry.Name())})
+		}
+	}
+
+	return sources
+}
+
+// LoadSourceConfig returns the sources declared in the source config file at path. Relative
+// commands with a path separator are relative to the directory holding the .agents directory.
+// A missing file is not an error.
+func LoadSourceConfig(path string) ([]Source, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read source config %s: %w", path, err)
+	}
+
+	var file sourceConfigFile
+
+	if err := yaml.UnmarshalWithOptions(data, &file, yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidSourceConfig, path, err)
+	}
+
+	root := filepath.Dir(filepath.Dir(path))
+
+	for i, source := range file.Sources {
+		if !sourceNamePattern.MatchString(source.Name) {
+			return nil, fmt.Errorf("%w: %s: source name %q must be letters, digits, dots, hyphens, and underscores",
+				ErrInvalidSourceConfig, path, source.Name)
+		}
+
+		if source.Command == "" {
+			return nil, fmt.Errorf("%w: %s: source %s requires a command", ErrInvalidSourceConfig, path, source.Name)
+		}
+
+		if strings.ContainsRune(source.Command, filepath.Separator) && !filepath.IsAbs(source.Command) {
+			file.Sources[i].Command = filepath.Join(root, source.Command)
+		}
+	}
+
+	return file.Sources, nil
+}
+
+// runSources runs each source and adds a search path holding the rules and skills they
+// provide, so that they are selected like rule and skill files. The search path's directory
+// is removed by Close.
+func (cc *Context) runSources(ctx context.Context) error {
+	if len(cc.sources) == 0 || !cc.doBootstrap {
+		return nil
+	}
+
+	if cc.lintMode {
+		cc.logger.Info("Lint mode: skipping rule sources")
+
+		return nil
+	}
+
+	request, err := json.Marshal(cc.sourceRequest())
+	if err != nil {
+		return fmt.Errorf("failed to encode source request: %w", err)
+	}
+
+	dir, err := cc.shared.newSourceDir()
+	if err != nil {
+		return err
+	}
+
+	cc.runSourceDir = dir
+	providedBy := make(map[string]string)
+
+	for _, source := range cc.sources {
+		response, err := cc.runSource(ctx, source, request)
+		if err != nil {
+			return err
+		}
+
+		if err := writeSourceFiles(dir, source.Name, response, providedBy); err != nil {
+			return fmt.Errorf("source %s: %w", source.Name, err)
+		}
+
+		cc.logger.Info("Ran source", "name", source.Name, "rules", len(response.Rules), "skills", len(response.Skills))
+	}
+
+	cc.downloadedPaths = append(cc.downloadedPaths, SearchPath{Path: dir})
+
+	return nil
+}
+
+// sourceRequest describes the run, with the selectors of the task and its commands.
+func (cc *Context) sourceRequest() SourceRequest {
+	request := SourceRequest{
+		TaskName:  cc.task.FrontMatter.Name,
+		Namespace: cc.namespace,
+		Selectors: make(map[string][]string, len(cc.includes)),
+		Params:    make(map[string][]string, len(cc.params)),
+	}
+
+	if cc.agent.IsSet() {
+		request.Agent = cc.agent.String()
+	}
+
+	for key, values := range cc.includes {
+		request.Selectors[key] = slices.Sorted(maps.Keys(values))
+	}
+
+	maps.Copy(request.Params, cc.params)
+
+	return request
+}
+
+// runSource runs a source with request on stdin and decodes its response.
+func (cc *Context) runSource(ctx context.Context, source Source, request []byte) (SourceResponse, error) {
+	cc.logger.Info("Running source", "name", source.Name, "command", source.Command)
+
+	var stdout bytes.Buffer
+
+	// #nosec G204 -- sources are executables the user installed or configured
+	cmd := exec.CommandContext(ctx, source.Command, source.Args...)
+	cmd.Stdin = bytes.NewReader(request)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cc.cmdRunner(cmd); err != nil {
+		return SourceResponse{}, fmt.Errorf("source %s failed: %w", source.Name, err)
+	}
+
+	var response SourceResponse
+
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return SourceResponse{}, fmt.Errorf("%w: source %s: %w", ErrInvalidSourceResponse, source.Name, err)
+	}
+
+	return response, nil
+}
+
+// writeSourceFiles writes the rules of a source to dir/.agents/rules/<source>/<rule>.md
+// and its skills to dir/.agents/skills/<skill>/SKILL.md. providedBy maps each rule and skill
+// already written to the source that provided it.
+func writeSourceFiles(dir, sourceName string, response SourceResponse, providedBy map[string]string) error {
+	for _, rule := range response.Rules {
+		if !sourceNamePattern.MatchString(rule.Name) {
+			return fmt.Errorf("%w: rule name %q must be letters, digits, dots, hyphens, and underscores",
+				ErrInvalidSourceResponse, rule.Name)
+		}
+
+		if err := provide(providedBy, "rule "+rule.Name, sourceName); err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, ".agents", "rules", sourceName, rule.Name+".md")
+		if err := writeSourceFile(path, rule.FrontMatter, rule.Content); err != nil {
+			return err
+		}
+	}
+
+	for _, skill := range response.Skills {
+		if !skillNamePattern.MatchString(skill.Name) {
+			return fmt.Errorf("%w: skill name %q must be lowercase letters, digits, and hyphens",
+				ErrInvalidSourceResponse, skill.Name)
+		}
+
+		if err := provide(providedBy, "skill "+skill.Name, sourceName); err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, ".agents", "skills", skill.Name, "SKILL.md")
+
+		frontMatter := maps.Clone(skill.FrontMatter)
+		if frontMatter == nil {
+			frontMatter = make(map[string]any)
+		}
+
+		frontMatter["name"] = skill.Name
+		frontMatter["description"] = skill.Description
+
+		if err := writeSourceFile(path, frontMatter, skill.Content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// provide records that sourceName provides item, a rule or skill, and returns an error if a
+// source already provided it.
+func provide(providedBy map[string]string, item, sourceName string) error {
+	other, ok := providedBy[item]
+	if !ok {
+		providedBy[item] = sourceName
+
+		return nil
+	}
+
+	if other == sourceName {
+		return fmt.Errorf("%w: %s is provided more than once", ErrInvalidSourceResponse, item)
+	}
+
+	return fmt.Errorf("%w: %s is provided by sources %s and %s", ErrInvalidSourceResponse, item, other, sourceName)
+}
+
+// writeSourceFile writes a markdown file with frontMatter and content.
+func writeSourceFile(path string, frontMatter map[string]any, content string) error {
+	var b bytes.Buffer
+
+	if len(frontMatter) > 0 {
+		data, err := yaml.Marshal(frontMatter)
+		if err != nil {
+			return fmt.Errorf("failed to encode frontmatter of %s: %w", path, err)
+		}
+
+		b.WriteString("---\n")
+		b.Write(data)
+		b.WriteString("---\n")
+	}
+
+	b.WriteString(content)
+
+	const dirMode, fileMode = 0o750, 0o600
+
+	if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, b.Bytes(), fileMode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,348 @@
+package codingcontext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
+)
+
+const sourceResponseJSON = `{
+  "rules": [
+    {"name": "team", "frontmatter": {"team": "platform"}, "content": "# Team Rule from a Source\n"},
+    {"name": "other", "frontmatter": {"team": "other"}, "content": "# Other Rule from a Source\n"}
+  ],
+  "skills": [
+    {"name": "ticket-skill", "description": "A skill from a source.", "content": "# Ticket Skill\n"}
+  ]
+}`
+
+// sourceScript returns a source script that saves its request next to itself, in
+// <script>.json, and writes response to stdout.
+func sourceScript(response string) string {
+	return "#!/bin/sh\ncat > \"$0.json\"\ncat <<'EOF'\n" + response + "\nEOF\n"
+}
+
+// createSource writes an executable source script to dir and returns its path.
+func createSource(t *testing.T, dir, name, script string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, SourcePrefix+name)
+
+	// #nosec G306 -- sources are executed directly; require 0755
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to create source: %v", err)
+	}
+
+	return path
+}
+
+func TestWithSources_Run(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "fix-bug", "selectors:\n  team: platform", "Fix the bug.")
+
+	command := createSource(t, t.TempDir(), "tickets", sourceScript(sourceResponseJSON))
+	source := Source{Name: "tickets", Command: command}
+
+	cc := New(
+		WithSearchPaths(dir),
+		WithParams(taskparser.Params{"issue": {"42"}}),
+		WithSources(source),
+	)
+	defer cc.Close()
+
+	result, err := cc.Run(context.Background(), "fix-bug")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if !strings.Contains(result.Prompt, "# Team Rule from a Source") {
+		t.Errorf("expected the matching rule from the source, got:\n%s", result.Prompt)
+	}
+
+	if strings.Contains(result.Prompt, "# Other Rule from a Source") {
+		t.Errorf("expected the other team's rule to be left out, got:\n%s", result.Prompt)
+	}
+
+	if len(result.Skills.Skills) != 1 || result.Skills.Skills[0].Description != "A skill from a source." {
+		t.Errorf("expected the skill from the source, got %+v", result.Skills.Skills)
+	}
+
+	data, err := os.ReadFile(source.Command + ".json")
+	if err != nil {
+		t.Fatalf("failed to read request: %v", err)
+	}
+
+	var request SourceRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatalf("failed to decode request %s: %v", data, err)
+	}
+
+	if request.TaskName != "fix-bug" || !slices.Equal(request.Params["issue"], []string{"42"}) ||
+		!slices.Equal(request.Selectors["team"], []string{"platform"}) {
+		t.Errorf("unexpected request: %+v", request)
+	}
+}
+
+func TestWithSources_Close(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	createTask(t, dir, "fix-bug", "", "Fix the bug.")
+
+	command := createSource(t, t.TempDir(), "tickets", sourceScript(sourceResponseJSON))
+	source := Source{Name: "tickets", Command: command}
+	cc := New(WithSearchPaths(dir), WithSources(source))
+
+	result, err := cc.Run(context.Background(), "fix-bug")
+	if err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	location := result.Skills.Skills[0].Location
+	if _, err := os.Stat(location); err != nil {
+		t.Fatalf("expected the source's skill at %s: %v", location, err)
+	}
+
+	if err := cc.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	if _, err := os.Stat(location); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected Close to remove %s, got %v", location, err)
+	}
+}
+
+func TestWithSources_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		script   string
+		wantErr  error
+		wantText string
+	}{
+		{
+			name:    "invalid json",
+			script:  sourceScript("not json"),
+			wantErr: ErrInvalidSourceResponse,
+		},
+		{
+			name:    "invalid rule name",
+			script:  sourceScript(`{"rules": [{"name": "../escape", "content": "x"}]}`),
+			wantErr: ErrInvalidSourceResponse,
+		},
+		{
+			name: "duplicate skill",
+			script: sourceScript(
+				`{"skills": [{"name": "dup", "description": "a"}, {"name": "dup", "description": "b"}]}`),
+			wantErr: ErrInvalidSourceResponse,
+		},
+		{
+			name:     "duplicate rule",
+			script:   sourceScript(`{"rules": [{"name": "dup", "content": "a"}, {"name": "dup", "content": "b"}]}`),
+			wantErr:  ErrInvalidSourceResponse,
+			wantText: "rule dup is provided more than once",
+		},
+		{
+			name:     "failing source",
+			script:   "#!/bin/sh\nexit 1\n",
+			wantText: "source tickets failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "fix-bug", "", "Fix the bug.")
+
+			source := Source{Name: "tickets", Command: createSource(t, t.TempDir(), "tickets", tt.script)}
+			cc := New(WithSearchPaths(dir), WithSources(source))
+			defer cc.Close()
+
+			_, err := cc.Run(context.Background(), "fix-bug")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+
+			if tt.wantText != "" && !strings.Contains(err.Error(), tt.wantText) {
+				t.Errorf("expected error containing %q, got %v", tt.wantText, err)
+			}
+		})
+	}
+}
+
+func TestFindSources(t *testing.T) {
+	t.Parallel()
+
+	first, second := t.TempDir(), t.TempDir()
+	createSource(t, first, "tickets", "{}")
+	createSource(t, second, "tickets", "{}")
+	createSource(t, second, "wiki", "{}")
+
+	// Not executable.
+	if err := os.WriteFile(filepath.Join(second, SourcePrefix+"notes"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := FindSources(strings.Join([]string{first, filepath.Join(first, "missing"), second},
+		string(filepath.ListSeparator)))
+
+	want := []Source{
+		{Name: "tickets", Command: filepath.Join(first, SourcePrefix+"tickets")},
+		{Name: "wiki", Command: filepath.Join(second, SourcePrefix+"wiki")},
+	}
+
+	if len(sources) != len(want) {
+		t.Fatalf("FindSources() = %+v, want %+v", sources, want)
+	}
+
+	for i := range want {
+		if sources[i].Name != want[i].Name || sources[i].Command != want[i].Command {
+			t.Errorf("FindSources()[%d] = %+v, want %+v", i, sources[i], want[i])
+		}
+	}
+}
+
+func TestLoadSourceConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  string
+		want    []Source
+		wantErr bool
+	}{
+		{
+			name:   "missing file",
+			config: "",
+		},
+		{
+			name: "commands",
+			config: "sources:\n  - name: tickets\n    command: ./tools/tickets\n    args: [--open]\n" +
+				"  - name: wiki\n    command: wiki\n",
+			want: []Source{
+				{Name: "tickets", Command: filepath.Join("tools", "tickets"), Args: []string{"--open"}},
+				{Name: "wiki", Command: "wiki"},
+			},
+		},
+		{
+			name:    "missing command",
+			config:  "sources:\n  - name: tickets\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid name",
+			config:  "sources:\n  - name: ../tickets\n    command: tickets\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			config:  "sources:\n  - name: tickets\n    cmd: tickets\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, SourceConfigFile)
+
+			if tt.config != "" {
+				if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			sources, err := LoadSourceConfig(path)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSourceConfig) {
+					t.Errorf("expected ErrInvalidSourceConfig, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("LoadSourceConfig() error: %v", err)
+			}
+
+			if len(sources) != len(tt.want) {
+				t.Fatalf("LoadSourceConfig() = %+v, want %+v", sources, tt.want)
+			}
+
+			for i, want := range tt.want {
+				if strings.ContainsRune(want.Command, filepath.Separator) {
+					want.Command = filepath.Join(dir, want.Command)
+				}
+
+				got := sources[i]
+				if got.Name != want.Name || got.Command != want.Command || !slices.Equal(got.Args, want.Args) {
+					t.Errorf("LoadSourceConfig()[%d] = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWithSources_DuplicateAcrossSources(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		response string
+		wantText string
+	}{
+		{
+			name:     "rule",
+			response: `{"rules": [{"name": "dup", "content": "x"}]}`,
+			wantText: "rule dup is provided by sources tickets and wiki",
+		},
+		{
+			name:     "skill",
+			response: `{"skills": [{"name": "dup", "description": "x"}]}`,
+			wantText: "skill dup is provided by sources tickets and wiki",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			createTask(t, dir, "fix-bug", "", "Fix the bug.")
+
+			bin := t.TempDir()
+			tickets := Source{Name: "tickets", Command: createSource(t, bin, "tickets", sourceScript(tt.response))}
+			wiki := Source{Name: "wiki", Command: createSource(t, bin, "wiki", sourceScript(tt.response))}
+
+			cc := New(WithSearchPaths(dir), WithSources(tickets, wiki))
+			defer cc.Close()
+
+			_, err := cc.Run(context.Background(), "fix-bug")
+			if !errors.Is(err, ErrInvalidSourceResponse) || !strings.Contains(err.Error(), tt.wantText) {
+				t.Errorf("expected error containing %q, got %v", tt.wantText, err)
+			}
+		})
+	}
+}
@@ -2,9 +2,13 @@ package taskparser
 
 import (
 	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -17,6 +21,9 @@ type ExpandOptions struct {
 	SkipCommands bool
 	// PathRefs, if non-nil, is appended with each successfully resolved @path reference.
 	PathRefs *[]string
+	// FS, if non-nil, is the file system @path references are read from, such as the
+	// embed.FS of the file being expanded. Paths are then relative to the root of FS.
+	FS fs.FS
 }
 
 // Expand performs all types of expansion on the content in a single pass:
@@ -65,7 +72,7 @@ func (p Params) ExpandWith(content string, opts ExpandOptions) (string, error) {
 		}
 
 		if runes[i] == '@' && (i == 0 || isWhitespaceRune(runes[i-1])) {
-			if pathContent, newI, ok := tryExpandPath(runes, i, opts.PathRefs); ok {
+			if pathContent, newI, ok := tryExpandPath(runes, i, opts.FS, opts.PathRefs); ok {
 				result.Write(pathContent)
 
 				i = newI
@@ -81,6 +88,38 @@ func (p Params) ExpandWith(content string, opts ExpandOptions) (string, error) {
 	return result.String(), nil
 }
 
+// ParamRefs returns the names of the parameters referenced as ${param} in content, in the
+// order they are first referenced, each once. Like Expand, it does not look inside !`cmd`.
+func ParamRefs(content string) []string {
+	var names []string
+
+	runes := []rune(content)
+
+	for i := 0; i < len(runes); {
+		if _, end, ok := trySkipCommand(runes, i); ok {
+			i = end
+
+			continue
+		}
+
+		_, end, ok := tryExpandParam(runes, i, nil)
+		if !ok {
+			i++
+
+			continue
+		}
+
+		name := string(runes[i+2 : end-1])
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+
+		i = end
+	}
+
+	return names
+}
+
 // tryExpandParam attempts ${param} expansion at position i.
 // Returns the expanded value, the new index past the expansion, and true if matched.
 func tryExpandParam(runes []rune, i int, p Params) (string, int, bool) {
@@ -135,7 +174,7 @@ func tryExpandCommand(runes []rune, i int) ([]byte, int, bool) {
 // tryExpandPathAt attempts to expand @path at the given index.
 // Returns the content to write (either file content or original @path), the new index,
 // and true if expansion was attempted.
-func tryExpandPathAt(runes []rune, i int) ([]byte, int, bool) {
+func tryExpandPathAt(runes []rune, i int, fsys fs.FS) ([]byte, int, bool) {
 	pathStart := i + 1
 	pathEnd := pathStart
 
@@ -160,9 +199,7 @@ pathScan:
 		return []byte(string(runes[i:pathEnd])), pathEnd, true
 	}
 
-	cleanPath := filepath.Clean(path)
-
-	fileContent, err := os.ReadFile(cleanPath)
+	fileContent, _, err := readExpandPath(fsys, path)
 	if err != nil {
 		return []byte(string(runes[i:pathEnd])), pathEnd, true
 	}
@@ -192,12 +229,12 @@ func trySkipCommand(runes []rune, i int) ([]byte, int, bool) {
 
 // tryExpandPath expands @path at position i, optionally tracking resolved paths.
 // If pathRefs is non-nil, the successfully resolved path is appended to it.
-func tryExpandPath(runes []rune, i int, pathRefs *[]string) ([]byte, int, bool) {
+func tryExpandPath(runes []rune, i int, fsys fs.FS, pathRefs *[]string) ([]byte, int, bool) {
 	if pathRefs == nil {
-		return tryExpandPathAt(runes, i)
+		return tryExpandPathAt(runes, i, fsys)
 	}
 
-	fileContent, newI, ok, resolved := tryExpandPathAtTracked(runes, i)
+	fileContent, newI, ok, resolved := tryExpandPathAtTracked(runes, i, fsys)
 	if ok && resolved != "" {
 		*pathRefs = append(*pathRefs, resolved)
 	}
@@ -208,7 +245,7 @@ func tryExpandPath(runes []rune, i int, pathRefs *[]string) ([]byte, int, bool)
 // tryExpandPathAtTracked is identical to tryExpandPathAt but also returns the resolved
 // cleanPath so callers can record it as a loaded file. resolvedPath is empty if the
 // file could not be read (the original @path text is returned as content in that case).
-func tryExpandPathAtTracked(runes []rune, i int) ([]byte, int, bool, string) {
+func tryExpandPathAtTracked(runes []rune, i int, fsys fs.FS) ([]byte, int, bool, string) {
 	pathStart := i + 1
 	pathEnd := pathStart
 
@@ -233,9 +270,7 @@ pathScan:
 		return []byte(string(runes[i:pathEnd])), pathEnd, true, ""
 	}
 
-	cleanPath := filepath.Clean(path)
-
-	fileContent, err := os.ReadFile(cleanPath)
+	fileContent, cleanPath, err := readExpandPath(fsys, path)
 	if err != nil {
 		return []byte(string(runes[i:pathEnd])), pathEnd, true, ""
 	}
@@ -243,6 +278,30 @@ pathScan:
 	return fileContent, pathEnd, true, cleanPath
 }
 
+// readExpandPath reads the file referenced by @ref, from fsys when it is non-nil, and
+// returns its content and cleaned path.
+func readExpandPath(fsys fs.FS, ref string) ([]byte, string, error) {
+	if fsys != nil {
+		name := path.Clean(filepath.ToSlash(ref))
+
+		content, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+
+		return content, name, nil
+	}
+
+	cleanPath := filepath.Clean(ref)
+
+	content, err := os.ReadFile(cleanPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", cleanPath, err)
+	}
+
+	return content, cleanPath, nil
+}
+
 // isWhitespaceRune checks if a rune is whitespace (space, tab, newline, carriage return).
 func isWhitespaceRune(r rune) bool {
 	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
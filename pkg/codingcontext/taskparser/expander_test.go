@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/kitproj/coding-context-cli/pkg/codingcontext/taskparser"
 	"github.com/stretchr/testify/require"
@@ -527,6 +528,42 @@ func TestExpandWithPathRefs(t *testing.T) {
 	})
 }
 
+func TestExpandWithFS(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"docs/guide.md": {Data: []byte("guide content")},
+	}
+
+	t.Run("path read from FS", func(t *testing.T) {
+		t.Parallel()
+
+		var pathRefs []string
+
+		opts := taskparser.ExpandOptions{FS: fsys, PathRefs: &pathRefs}
+		result, err := (taskparser.Params{}).ExpandWith("See @docs/guide.md", opts)
+		require.NoError(t, err)
+		require.Equal(t, "See guide content", result)
+		require.Equal(t, []string{"docs/guide.md"}, pathRefs)
+	})
+
+	t.Run("missing path left as-is", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := (taskparser.Params{}).ExpandWith("See @docs/missing.md", taskparser.ExpandOptions{FS: fsys})
+		require.NoError(t, err)
+		require.Equal(t, "See @docs/missing.md", result)
+	})
+
+	t.Run("path outside FS left as-is", func(t *testing.T) {
+		t.Parallel()
+
+		result, err := (taskparser.Params{}).ExpandWith("See @../guide.md", taskparser.ExpandOptions{FS: fsys})
+		require.NoError(t, err)
+		require.Equal(t, "See @../guide.md", result)
+	})
+}
+
 func TestExpandSecurityNoReExpansion(t *testing.T) {
 	t.Parallel()
 
@@ -580,3 +617,42 @@ func TestExpandSecurityNoReExpansion(t *testing.T) {
 		})
 	}
 }
+
+func TestParamRefs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "no parameters",
+			content: "Fix the bug.",
+			want:    nil,
+		},
+		{
+			name:    "parameters in order of first reference",
+			content: "Deploy ${service} to ${env}. Check ${service} in ${env}.",
+			want:    []string{"service", "env"},
+		},
+		{
+			name:    "unterminated and empty references are ignored",
+			content: "Cost: $5, ${} and ${unterminated",
+			want:    nil,
+		},
+		{
+			name:    "references in commands are not expanded",
+			content: "!`echo ${shell}` @file.txt ${issue_key}",
+			want:    []string{"issue_key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tt.want, taskparser.ParamRefs(tt.content))
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kitproj/coding-context-cli/pkg/codingcontext"
+)
+
+// Markers around the generated rules in an agent's rules file. Content outside the markers
+// is the user's, and is kept when the rules are written again.
+const (
+	managedSectionBegin = codingcontext.ManagedSectionBegin
+	managedSectionEnd   = codingcontext.ManagedSectionEnd
+)
+
+var (
+	errRulesOutOfDate  = errors.New("rules file is out of date")
+	errManagedSections = errors.New("expected at most one managed section")
+)
+
+// mergeManagedSection returns existing with its managed section replaced by rules. If existing
+// has no managed section, the section is appended to it, or, for a new file, written after
+// header. The rest of existing is never changed. A file with more than one begin or end marker,
+// or with only one of them, is an error, since it is not clear which section is managed.
+func mergeManagedSection(existing string, rules []string, header string) (string, error) {
+	section := managedSectionBegin + "\n" + strings.Join(rules, "\n\n") + "\n" + managedSectionEnd + "\n"
+
+	if existing == "" {
+		return header + section, nil
+	}
+
+	begins := strings.Count(existing, managedSectionBegin)
+	ends := strings.Count(existing, managedSectionEnd)
+
+	if begins == 0 && ends == 0 {
+		if !strings.HasSuffix(existing, "\n") {
+			existing += "\n"
+		}
+
+		return existing + "\n" + section, nil
+	}
+
+	begin := strings.Index(existing, managedSectionBegin)
+	end := strings.Index(existing, managedSectionEnd)
+
+	if begins != 1 || ends != 1 || end < begin {
+		return "", fmt.Errorf("%w: found %d %q and %d %q markers",
+			errManagedSections, begins, managedSectionBegin, ends, managedSectionEnd)
+	}
+
+	after := strings.TrimPrefix(existing[end+len(managedSectionEnd):], "\n")
+
+	return existing[:begin] + section + after, nil
+}
+
+// updateRulesFile replaces the managed section of rulesFile with rules, reporting whether the
+// file changed. In check mode nothing is written, and a stale file is errRulesOutOfDate.
+// With backup, the previous file is kept as rulesFile.bak.
+func updateRulesFile(rulesFile string, rules []string, header string, check, backup bool) (bool, error) {
+	// #nosec G304 -- rulesFile is validated to be within its root by rulesFilePath
+	existing, err := os.ReadFile(rulesFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("failed to read rules file %s: %w", rulesFile, err)
+	}
+
+	updated, err := mergeManagedSection(string(existing), rules, header)
+	if err != nil {
+		return false, fmt.Errorf("failed to update rules file %s: %w", rulesFile, err)
+	}
+
+	if updated == string(existing) {
+		return false, nil
+	}
+
+	if check {
+		return true, fmt.Errorf("%w: %s", errRulesOutOfDate, rulesFile)
+	}
+
+	const fileMode = 0o600
+
+	if backup && existing != nil {
+		// #nosec G703 -- rulesFile is validated to be within its root by rulesFilePath
+		if err := os.WriteFile(rulesFile+".bak", existing, fileMode); err != nil {
+			return false, fmt.Errorf("failed to back up rules file %s: %w", rulesFile, err)
+		}
+	}
+
+	// #nosec G703 -- rulesFile is validated to be within its root by rulesFilePath
+	if err := os.WriteFile(rulesFile, []byte(updated), fileMode); err != nil {
+		return false, fmt.Errorf("failed to write rules to %s: %w", rulesFile, err)
+	}
+
+	return true, nil
+}
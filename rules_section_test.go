@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMergeManagedSection(t *testing.T) {
+	t.Parallel()
+
+	const header = "---\nalwaysApply: true\n---\n\n"
+
+	section := func(rules string) string {
+		return managedSectionBegin + "\n" + rules + "\n" + managedSectionEnd + "\n"
+	}
+
+	tests := []struct {
+		name     string
+		existing string
+		rules    []string
+		header   string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:  "new file",
+			rules: []string{"# A", "# B"},
+			want:  section("# A\n\n# B"),
+		},
+		{
+			name:   "new file with header",
+			rules:  []string{"# A"},
+			header: header,
+			want:   header + section("# A"),
+		},
+		{
+			name:     "managed section is replaced",
+			existing: "# Notes\n\n" + section("# Old") + "\n# More notes\n",
+			rules:    []string{"# New"},
+			want:     "# Notes\n\n" + section("# New") + "\n# More notes\n",
+		},
+		{
+			name:     "section is appended to a file without markers",
+			existing: "# A\n\n# Notes\n",
+			rules:    []string{"# A", "# B"},
+			want:     "# A\n\n# Notes\n\n" + section("# A\n\n# B"),
+		},
+		{
+			name:     "file without markers or a trailing newline is kept",
+			existing: header + "# Notes",
+			rules:    []string{"# A"},
+			header:   header,
+			want:     header + "# Notes\n\n" + section("# A"),
+		},
+		{
+			name:     "two managed sections",
+			existing: section("# A") + "# Notes\n" + section("# B"),
+			rules:    []string{"# A"},
+			wantErr:  true,
+		},
+		{
+			name:     "nested managed sections",
+			existing: managedSectionBegin + "\n" + section("# A") + managedSectionEnd + "\n",
+			rules:    []string{"# A"},
+			wantErr:  true,
+		},
+		{
+			name:     "begin marker without end marker",
+			existing: "# Notes\n" + managedSectionBegin + "\n# A\n",
+			rules:    []string{"# A"},
+			wantErr:  true,
+		},
+		{
+			name:     "end marker before begin marker",
+			existing: managedSectionEnd + "\n# A\n" + managedSectionBegin + "\n",
+			rules:    []string{"# A"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := mergeManagedSection(tt.existing, tt.rules, tt.header)
+			if tt.wantErr {
+				if !errors.Is(err, errManagedSections) {
+					t.Errorf("mergeManagedSection() error = %v, want %v", err, errManagedSections)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("mergeManagedSection() error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("mergeManagedSection() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// watchInterval is how often --watch checks the watched paths for changes.
+const watchInterval = 500 * time.Millisecond
+
+// fileStamp identifies a version of a file, like the entries of the library's file cache.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+	mode    fs.FileMode
+}
+
+// watch calls generate, and then again each time the files under paths change, until ctx
+// is done. Changes are debounced: generate is called once the files have stopped changing
+// for a whole interval, so that saving several files at once generates once. An error from
+// generate is logged, and the files are watched for the change that fixes it.
+func watch(
+	ctx context.Context, paths []string, interval time.Duration, generate func() error, logger *slog.Logger,
+) error {
+	// A failed run prints the usage for mistakes on the command line, but while watching,
+	// failures come from the watched files.
+	flag.Usage = func() {}
+
+	logger.Info("Watching for changes", "paths", len(paths))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := generate(); err != nil {
+			logger.Error("Failed to generate context", "error", err)
+		}
+
+		// Snapshot after generating, so that the rules files it writes are not changes.
+		last := snapshotFiles(paths)
+		changed := false
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+
+			current := snapshotFiles(paths)
+			if !maps.Equal(current, last) {
+				last, changed = current, true
+
+				continue
+			}
+
+			if changed {
+				break
+			}
+		}
+
+		logger.Info("Files changed, generating context again")
+	}
+}
+
+// snapshotFiles returns the stamp of every file and directory under paths. Paths that do
+// not exist are left out, so that creating them is a change.
+func snapshotFiles(paths []string) map[string]fileStamp {
+	files := make(map[string]fileStamp)
+
+	for _, root := range paths {
+		// Unreadable entries are left out; a file that cannot be read cannot be included either.
+		_ = filepath.WalkDir(root, func(path string, _ fs.DirEntry, err error) error {
+			if err != nil {
+				return nil //nolint:nilerr // keep walking the rest of the paths
+			}
+
+			if info, err := os.Stat(path); err == nil {
+				files[path] = fileStamp{size: info.Size(), modTime: info.ModTime(), mode: info.Mode()}
+			}
+
+			return nil
+		})
+	}
+
+	return files
+}
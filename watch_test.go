@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rulesDir := filepath.Join(dir, ".agents", "rules")
+	missingDir := filepath.Join(dir, ".agents", "tasks")
+
+	if err := os.MkdirAll(rulesDir, 0o750); err != nil {
+		t.Fatalf("failed to create rules dir: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	generated := make(chan struct{})
+	done := make(chan error)
+
+	go func() {
+		done <- watch(ctx, []string{rulesDir, missingDir}, 10*time.Millisecond, func() error {
+			generated <- struct{}{}
+
+			return nil
+		}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}()
+
+	waitGenerated := func(reason string) {
+		t.Helper()
+
+		select {
+		case <-generated:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("context was not generated %s", reason)
+		}
+	}
+
+	waitGenerated("on start")
+
+	if err := os.WriteFile(filepath.Join(rulesDir, "style.md"), []byte("# Style"), 0o600); err != nil {
+		t.Fatalf("failed to write rule: %v", err)
+	}
+
+	waitGenerated("after a rule was created")
+
+	// Creating a watched path that did not exist is a change.
+	if err := os.MkdirAll(missingDir, 0o750); err != nil {
+		t.Fatalf("failed to create tasks dir: %v", err)
+	}
+
+	waitGenerated("after the tasks dir was created")
+
+	select {
+	case <-generated:
+		t.Fatal("context was generated again without a change")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("watch() error: %v", err)
+	}
+}